@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// abortMoveLimit is how many half-moves may be played before a game can
+// no longer be aborted.
+const abortMoveLimit = 2
+
+// abortGame aborts a game with no result while fewer than two half-moves
+// have been played. A player without an opponent aborts straight away;
+// otherwise the first request has to be confirmed by the opponent sending
+// abort too.
+func abortGame(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	switch {
+	case player == nil:
+		sendJSON(ws, map[string]string{"error": "only players can abort"})
+		return
+	case game.Game.Outcome() != chess.NoOutcome:
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	case len(game.Game.Moves()) >= abortMoveLimit:
+		sendJSON(ws, map[string]string{"error": "cannot abort after two moves, use resign or draw"})
+		return
+	case game.AbortRequested && game.AbortRequester == player.Color:
+		sendJSON(ws, map[string]string{"error": "an abort request is already pending"})
+		return
+	}
+
+	other := opponent(game, player.Color)
+	if other != nil && game.PracticeMode == "" && !game.AbortRequested {
+		game.AbortRequested = true
+		game.AbortRequester = player.Color
+		var timer *time.Timer
+		timer = time.AfterFunc(config.AbortTimeout, func() {
+			expireAbortRequest(gameID, game, &timer)
+		})
+		game.abortTimer = timer
+		sendToPlayer(other, map[string]string{"status": "abortRequest", "from": colorName(player.Color)})
+		logger.Info("Abort requested", slog.String("gameID", gameID), slog.String("playerColor", colorName(player.Color)))
+		return
+	}
+
+	for _, conn := range removeAbortedGame(gameID, game) {
+		sendJSON(conn, map[string]string{"status": "aborted", "gameID": gameID})
+		if !inOtherGames(conn, gameID) {
+			closeConnection(conn, CloseGameEnded, "game aborted")
+		}
+	}
+	logger.Info("Game aborted", slog.String("gameID", gameID))
+}
+
+// removeAbortedGame deletes game and stops its timers, returning everyone
+// who should hear about it. The caller must hold gamesMutex.
+func removeAbortedGame(gameID string, game *Game) []*websocket.Conn {
+	game.Lock()
+	defer game.Unlock()
+
+	deleteGame(gameID, game)
+	if game.abortTimer != nil {
+		game.abortTimer.Stop()
+	}
+	if game.Clock != nil {
+		game.Clock.stop()
+	}
+	var conns []*websocket.Conn
+	for _, player := range game.Players {
+		if player.ForfeitTimer != nil {
+			player.ForfeitTimer.Stop()
+		}
+		if player.Conn != nil {
+			conns = append(conns, player.Conn)
+		}
+	}
+	conns = append(conns, game.Coaches...)
+	conns = append(conns, game.Spectators...)
+	game.StateSeq++
+	publishEvent(game, map[string]interface{}{"status": "aborted", "gameID": gameID, "seq": game.StateSeq})
+	return conns
+}
+
+// expireAbortRequest withdraws an abort request the opponent has not
+// answered within config.AbortTimeout. timer is only read under gamesMutex, which
+// abortGame held while setting it.
+func expireAbortRequest(gameID string, game *Game, timer **time.Timer) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	if games[gameID] != game || game.abortTimer != *timer {
+		return
+	}
+	game.AbortRequested = false
+	game.AbortRequester = chess.NoColor
+	game.abortTimer = nil
+	for _, player := range game.Players {
+		sendToPlayer(player, map[string]string{"status": "abortExpired", "gameID": gameID})
+	}
+	logger.Info("Abort request expired", slog.String("gameID", gameID))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAbort(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4")
+
+	send(t, white, map[string]string{"action": "abort", "gameID": gameID})
+	if request := readUntil(t, black, hasStatus("abortRequest")); request["from"] != "white" {
+		t.Errorf("abort request from %v, want white", request["from"])
+	}
+	send(t, black, map[string]string{"action": "abort", "gameID": gameID})
+	for _, ws := range []*websocket.Conn{white, black} {
+		readUntil(t, ws, hasStatus("aborted"))
+	}
+
+	gamesMutex.RLock()
+	_, exists := games[gameID]
+	gamesMutex.RUnlock()
+	if exists {
+		t.Error("the aborted game is still held")
+	}
+}
+
+func TestAbortKeepsPlayersInOtherGames(t *testing.T) {
+	useConfig(t, func(c *Config) { c.MaxGamesPerPlayer = 2 })
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	send(t, white, map[string]string{"action": "create"})
+	other := readUntil(t, white, hasStatus("created"))["gameID"].(string)
+
+	send(t, white, map[string]string{"action": "abort", "gameID": gameID})
+	readUntil(t, black, hasStatus("abortRequest"))
+	send(t, black, map[string]string{"action": "abort", "gameID": gameID})
+	readUntil(t, white, hasStatus("aborted"))
+	if code := readCloseCode(t, black); code != CloseGameEnded {
+		t.Errorf("black closed with %d, want %d", code, CloseGameEnded)
+	}
+
+	// White is still waiting for an opponent in the other game.
+	send(t, white, map[string]string{"action": "syncState", "gameID": other})
+	if msg := readUntil(t, white, hasError); msg["error"] != "game has not started" {
+		t.Errorf("syncing the other game: %v", msg["error"])
+	}
+}
+
+func TestAbortAfterTwoMoves(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5")
+
+	send(t, white, map[string]string{"action": "abort", "gameID": gameID})
+	if msg := readUntil(t, white, hasError); msg["error"] != "cannot abort after two moves, use resign or draw" {
+		t.Errorf("aborting after two moves: %v", msg["error"])
+	}
+}
+
+func TestAbortRequestExpires(t *testing.T) {
+	useConfig(t, func(c *Config) { c.AbortTimeout = 50 * time.Millisecond })
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	send(t, white, map[string]string{"action": "abort", "gameID": gameID})
+	readUntil(t, black, hasStatus("abortRequest"))
+	for _, ws := range []*websocket.Conn{white, black} {
+		readUntil(t, ws, hasStatus("abortExpired"))
+	}
+
+	// Once expired, black's abort is a new request rather than agreement.
+	send(t, black, map[string]string{"action": "abort", "gameID": gameID})
+	if request := readUntil(t, white, hasStatus("abortRequest")); request["from"] != "black" {
+		t.Errorf("abort request from %v, want black", request["from"])
+	}
+	send(t, white, map[string]string{"action": "syncState", "gameID": gameID})
+	if state := readUntil(t, white, isState); state["status"] != "ongoing" {
+		t.Errorf("status after the request expired = %v, want ongoing", state["status"])
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// activityRingSize is how many of a player's latest games their activity
+// heatmap is drawn from.
+const activityRingSize = 500
+
+// activityRing holds when a player's last activityRingSize games ended,
+// overwriting the oldest once full.
+type activityRing struct {
+	ended [activityRingSize]time.Time
+	next  int
+	count int
+}
+
+func (r *activityRing) add(t time.Time) {
+	r.ended[r.next] = t
+	r.next = (r.next + 1) % activityRingSize
+	r.count = min(r.count+1, activityRingSize)
+}
+
+// heatmap counts the games in r by day of the week, Sunday first, and
+// hour of the day in UTC, scaled so that the busiest hour is 1.
+func (r *activityRing) heatmap() [7][24]float64 {
+	var counts [7][24]int
+	busiest := 0
+	for _, t := range r.ended[:r.count] {
+		t = t.UTC()
+		counts[t.Weekday()][t.Hour()]++
+		busiest = max(busiest, counts[t.Weekday()][t.Hour()])
+	}
+	var heatmap [7][24]float64
+	for day := range counts {
+		for hour, n := range counts[day] {
+			if n > 0 {
+				heatmap[day][hour] = math.Round(100*float64(n)/float64(busiest)) / 100
+			}
+		}
+	}
+	return heatmap
+}
+
+// recordActivity adds archived to the activity of each of its players
+// with an account. The caller must hold the archive's mutex.
+func recordActivity(archived *archivedGame) {
+	for _, player := range archived.Players {
+		if player.Subject == "" {
+			continue
+		}
+		ring := archive.activity[player.Subject]
+		if ring == nil {
+			ring = &activityRing{}
+			archive.activity[player.Subject] = ring
+		}
+		ring.add(archived.EndedAt)
+	}
+}
+
+// rebuildActivity refills every player's activity from the archive, in
+// the order the games ended. The caller must hold the archive's mutex.
+func rebuildActivity() {
+	ended := make([]*archivedGame, 0, len(archive.games))
+	for _, archived := range archive.games {
+		ended = append(ended, archived)
+	}
+	sort.Slice(ended, func(i, j int) bool { return ended[i].EndedAt.Before(ended[j].EndedAt) })
+	clear(archive.activity)
+	for _, archived := range ended {
+		recordActivity(archived)
+	}
+}
+
+// activityHeatmap returns the heatmap of playerID's games, all zeroes if
+// they have finished none.
+func activityHeatmap(playerID string) [7][24]float64 {
+	archive.Lock()
+	defer archive.Unlock()
+	loadArchive()
+	if ring := archive.activity[playerID]; ring != nil {
+		return ring.heatmap()
+	}
+	return [7][24]float64{}
+}
+
+// getActivityHeatmap sends ws the heatmap of playerID, or of ws's own
+// account if no playerID is given.
+func getActivityHeatmap(ws *websocket.Conn, playerID string) {
+	if playerID == "" {
+		identitiesMutex.Lock()
+		playerID = identities[ws].Subject
+		identitiesMutex.Unlock()
+	}
+	if playerID == "" {
+		sendJSON(ws, map[string]string{"error": "playerID required"})
+		return
+	}
+	sendJSON(ws, map[string]interface{}{"playerID": playerID, "heatmap": activityHeatmap(playerID)})
+	logger.Debug("Activity heatmap sent", slog.String("playerID", playerID))
+}
+
+// handleActivityHeatmap serves GET /player/{id}/heatmap, where id is the
+// subject of the player's token.
+func handleActivityHeatmap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	playerID := r.PathValue("id")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"playerID": playerID,
+		"heatmap":  activityHeatmap(playerID),
+	}); err != nil {
+		logger.Error("Error sending activity heatmap", slog.Any("error", err))
+	}
+}
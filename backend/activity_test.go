@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestActivityRing(t *testing.T) {
+	var ring activityRing
+	// Sunday 5 January 2025, 10:00 UTC, then one game every week.
+	sunday := time.Date(2025, 1, 5, 10, 0, 0, 0, time.UTC)
+	ring.add(sunday.Add(3 * time.Hour))
+	for i := 0; i < activityRingSize; i++ {
+		ring.add(sunday.AddDate(0, 0, 7*i))
+	}
+	heatmap := ring.heatmap()
+	if heatmap[0][10] != 1 || heatmap[0][13] != 0 {
+		t.Errorf("got %v at 10:00 and %v at 13:00 on Sundays, want 1 and the overwritten game gone", heatmap[0][10], heatmap[0][13])
+	}
+	ring.add(sunday.AddDate(0, 0, 1).Add(4 * time.Hour))
+	if got := ring.heatmap()[1][14]; got != 0 {
+		t.Errorf("one Monday game against 499 on Sundays scores %v, want 0 after rounding", got)
+	}
+}
+
+func TestActivityHeatmap(t *testing.T) {
+	useConfig(t, func(c *Config) { c.JWTSecret = []byte("test secret") })
+	srv := newTestServer(t)
+	store := newMemoryStore()
+	useArchive(t, store)
+
+	finished := func(at time.Time, subjects ...string) *Game {
+		g := chess.NewGame()
+		g.Resign(chess.White)
+		game := &Game{Game: g, StartingFEN: chess.StartingPosition().String(), EndedAt: at}
+		for _, subject := range subjects {
+			game.Players = append(game.Players, &Player{Subject: subject})
+		}
+		return game
+	}
+	// A Tuesday game archived before the server started, then two on
+	// Wednesdays since.
+	tuesday := time.Date(2025, 1, 7, 18, 30, 0, 0, time.UTC)
+	data, err := encodeGame(finished(tuesday, "alice", "bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.ArchiveGame("stored", data)
+	archiveGame("live1", finished(tuesday.AddDate(0, 0, 1), "alice", "bob"))
+	archiveGame("live2", finished(tuesday.AddDate(0, 0, 8), "alice"))
+
+	resp, err := http.Get(srv.URL + "/player/alice/heatmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		PlayerID string      `json:"playerID"`
+		Heatmap  [][]float64 `json:"heatmap"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.PlayerID != "alice" || len(got.Heatmap) != 7 || len(got.Heatmap[2]) != 24 || got.Heatmap[2][18] != 0.5 || got.Heatmap[3][18] != 1 {
+		t.Errorf("alice's heatmap: %+v", got)
+	}
+
+	bob := dialAuthenticated(t, srv, "bob")
+	send(t, bob, map[string]string{"action": "get_activity_heatmap"})
+	msg := readUntil(t, bob, func(msg map[string]interface{}) bool { return msg["heatmap"] != nil })
+	heatmap := msg["heatmap"].([]interface{})
+	if msg["playerID"] != "bob" || heatmap[2].([]interface{})[18] != 1.0 || heatmap[3].([]interface{})[18] != 1.0 {
+		t.Errorf("bob's heatmap: %v", msg)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// isAdmin reports whether token matches the configured admin token.
+// Admin actions are disabled entirely when no token is configured.
+func isAdmin(token string) bool {
+	adminToken := config.AdminToken
+	if adminToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+func setGameResult(ws *websocket.Conn, msg map[string]string) {
+	gameID := msg["gameID"]
+	if !isAdmin(msg["adminToken"]) {
+		sendJSON(ws, map[string]string{"error": "unauthorized"})
+		logger.Warn("Unauthorized adjudication attempt", slog.String("gameID", gameID))
+		return
+	}
+
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to adjudicate non-existent game", slog.String("gameID", gameID))
+		return
+	}
+
+	if game.Game.Outcome() != chess.NoOutcome {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	}
+
+	// notnil/chess has no way to set an arbitrary outcome, so a decisive
+	// result is recorded as a resignation by the losing side.
+	switch chess.Outcome(msg["outcome"]) {
+	case chess.WhiteWon:
+		game.Game.Resign(chess.Black)
+	case chess.BlackWon:
+		game.Game.Resign(chess.White)
+	case chess.Draw:
+		if err := game.Game.Draw(chess.DrawOffer); err != nil {
+			gamesMutex.Unlock()
+			sendJSON(ws, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "invalid outcome"})
+		return
+	}
+	game.Adjudicated = true
+	game.AdjudicationReason = msg["reason"]
+	clearTakebackRequest(game)
+	gamesMutex.Unlock()
+
+	// Anyone holding the admin token can adjudicate, so the log records
+	// what the server can vouch for: where the request came from and the
+	// subject of the connection's token, if it has one. adminID is only
+	// what the client says it is, and is labelled as such.
+	attrs := []interface{}{slog.String("gameID", gameID), slog.String("outcome", msg["outcome"]),
+		slog.String("reason", msg["reason"]), slog.String("remoteAddr", ws.RemoteAddr().String())}
+	identitiesMutex.Lock()
+	claims, authenticated := identities[ws]
+	identitiesMutex.Unlock()
+	if authenticated {
+		attrs = append(attrs, slog.String("adminSubject", claims.Subject))
+	}
+	if id := msg["adminID"]; id != "" {
+		attrs = append(attrs, slog.String("claimedAdminID", id))
+	}
+	logger.Info("Game adjudicated", attrs...)
+
+	broadcastGameState(gameID)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// logBuffer collects log output written from the server's goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestAdjudicationLog checks that the log of an adjudication keeps the
+// client's own adminID apart from what the server saw.
+func TestAdjudicationLog(t *testing.T) {
+	useConfig(t, func(c *Config) { c.AdminToken = "admin secret" })
+	var logs logBuffer
+	saved := logger
+	logger = slog.New(slog.NewTextHandler(&logs, nil))
+	t.Cleanup(func() { logger = saved })
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+
+	admin := dial(t, srv)
+	send(t, admin, map[string]string{"action": "set_game_result", "gameID": gameID, "adminToken": "admin secret", "adminID": "alice", "outcome": "1-0", "reason": "test"})
+	readUntil(t, white, func(msg map[string]interface{}) bool { return isState(msg) && msg["status"] != "ongoing" })
+
+	var line string
+	for _, l := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(l, `msg="Game adjudicated"`) {
+			line = l
+		}
+	}
+	if !strings.Contains(line, "remoteAddr=127.0.0.1:") || !strings.Contains(line, "claimedAdminID=alice") || strings.Contains(line, " admin=") {
+		t.Errorf("adjudication logged as %q", line)
+	}
+}
+
+// TestAdjudicationBroadcast checks what players and spectators are told
+// of an adjudicated result.
+func TestAdjudicationBroadcast(t *testing.T) {
+	useConfig(t, func(c *Config) {
+		c.AdminToken = "admin secret"
+		c.MaxConnectionsPerIP = 20
+	})
+	srv := newTestServer(t)
+	for _, c := range []struct{ outcome, winner string }{
+		{"1-0", "white"},
+		{"0-1", "black"},
+		{"1/2-1/2", ""},
+	} {
+		white, black, gameID := startTestGame(t, srv)
+		spectator := dial(t, srv)
+		send(t, spectator, map[string]string{"action": "join", "gameID": gameID})
+		readUntil(t, spectator, hasStatus("spectating"))
+
+		admin := dial(t, srv)
+		send(t, admin, map[string]string{"action": "set_game_result", "gameID": gameID, "adminToken": "admin secret", "outcome": c.outcome, "reason": "fair play"})
+		for _, ws := range []*websocket.Conn{white, black, spectator} {
+			// game_over may overtake the final state.
+			var state, over map[string]interface{}
+			for state == nil || over == nil {
+				msg := readUntil(t, ws, func(msg map[string]interface{}) bool {
+					return msg["type"] == "game_over" || isState(msg) && msg["status"] != "ongoing"
+				})
+				if msg["type"] == "game_over" {
+					over = msg
+				} else {
+					state = msg
+				}
+			}
+			if state["status"] != "adjudicated" || state["adjudicated"] != true || state["outcome"] != c.outcome || state["reason"] != "fair play" || !hasWinner(state, c.winner) {
+				t.Errorf("%s: got state %v", c.outcome, state)
+			}
+			if over["status"] != "adjudicated" || over["adjudicated"] != true || !hasWinner(over, c.winner) {
+				t.Errorf("%s: got game_over %v", c.outcome, over)
+			}
+		}
+	}
+}
+
+// hasWinner reports whether msg names winner, or no winner when it is "".
+func hasWinner(msg map[string]interface{}, winner string) bool {
+	if winner == "" {
+		_, ok := msg["winner"]
+		return !ok
+	}
+	return msg["winner"] == winner
+}
@@ -0,0 +1,94 @@
+// Package analysis holds the board primitives shared by the position
+// analyses in the server and by the plans package: piece values, attacked
+// squares and pawn structure.
+package analysis
+
+import "github.com/notnil/chess"
+
+// The steps of the knight and king and the lines along which the sliding
+// pieces move, as file and rank offsets.
+var (
+	KnightOffsets    = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	KingOffsets      = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	RookDirections   = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	BishopDirections = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+// OffsetSquare returns the square df files and dr ranks away from sq.
+func OffsetSquare(sq chess.Square, df, dr int) (chess.Square, bool) {
+	f, r := int(sq.File())+df, int(sq.Rank())+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return chess.NoSquare, false
+	}
+	return chess.NewSquare(chess.File(f), chess.Rank(r)), true
+}
+
+// AttackedSquares returns the squares attacked by piece standing on sq,
+// regardless of whose turn it is or whether the piece is pinned. Sliding
+// attacks stop at, and include, the first occupied square.
+func AttackedSquares(board *chess.Board, sq chess.Square, piece chess.Piece) []chess.Square {
+	var squares []chess.Square
+	step := func(offsets [][2]int) {
+		for _, o := range offsets {
+			if target, ok := OffsetSquare(sq, o[0], o[1]); ok {
+				squares = append(squares, target)
+			}
+		}
+	}
+	slide := func(directions [][2]int) {
+		for _, d := range directions {
+			for target, ok := OffsetSquare(sq, d[0], d[1]); ok; target, ok = OffsetSquare(target, d[0], d[1]) {
+				squares = append(squares, target)
+				if board.Piece(target) != chess.NoPiece {
+					break
+				}
+			}
+		}
+	}
+
+	switch piece.Type() {
+	case chess.Pawn:
+		dr := 1
+		if piece.Color() == chess.Black {
+			dr = -1
+		}
+		step([][2]int{{-1, dr}, {1, dr}})
+	case chess.Knight:
+		step(KnightOffsets)
+	case chess.King:
+		step(KingOffsets)
+	case chess.Bishop:
+		slide(BishopDirections)
+	case chess.Rook:
+		slide(RookDirections)
+	case chess.Queen:
+		slide(RookDirections)
+		slide(BishopDirections)
+	}
+	return squares
+}
+
+// AttackCounts returns, for every square, how many pieces of the given color
+// attack it.
+func AttackCounts(board *chess.Board, color chess.Color) [64]int {
+	var counts [64]int
+	for sq, piece := range board.SquareMap() {
+		if piece.Color() != color {
+			continue
+		}
+		for _, target := range AttackedSquares(board, sq, piece) {
+			counts[target]++
+		}
+	}
+	return counts
+}
+
+// KingSquare returns the square of the king of the given color.
+func KingSquare(board *chess.Board, color chess.Color) chess.Square {
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() == chess.King && piece.Color() == color {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
@@ -0,0 +1,30 @@
+package analysis
+
+import "github.com/notnil/chess"
+
+// PieceValues holds the centipawn value of each piece type. Kings are
+// never exchanged and so carry no material value.
+var PieceValues = map[chess.PieceType]int{
+	chess.Pawn:   100,
+	chess.Knight: 320,
+	chess.Bishop: 330,
+	chess.Rook:   500,
+	chess.Queen:  900,
+}
+
+// EndgameMaterial is the most non-pawn material, in centipawns and for
+// both sides together, at which a position counts as an endgame.
+const EndgameMaterial = 2600
+
+// KingValue stands in for the king when comparing the worth of pieces, for
+// example when deciding whether a fork or pin wins material.
+const KingValue = 10000
+
+// PieceValue returns the value of a piece of type t, counting the king as
+// KingValue.
+func PieceValue(t chess.PieceType) int {
+	if t == chess.King {
+		return KingValue
+	}
+	return PieceValues[t]
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import "github.com/notnil/chess"
+
+// PawnSquares returns the squares occupied by pawns of the given color.
+func PawnSquares(board *chess.Board, color chess.Color) []chess.Square {
+	var squares []chess.Square
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() == chess.Pawn && piece.Color() == color {
+			squares = append(squares, sq)
+		}
+	}
+	return squares
+}
+
+// PawnFiles counts the pawns of the given color on each file.
+func PawnFiles(board *chess.Board, color chess.Color) [8]int {
+	var files [8]int
+	for _, sq := range PawnSquares(board, color) {
+		files[sq.File()]++
+	}
+	return files
+}
+
+// RelativeRank returns the rank of sq counted from color's side of the
+// board, so 0 is that side's back rank.
+func RelativeRank(sq chess.Square, color chess.Color) int {
+	if color == chess.White {
+		return int(sq.Rank())
+	}
+	return 7 - int(sq.Rank())
+}
+
+// IsPassedPawn reports whether no enemy pawn stands in front of the pawn on
+// sq on its own or an adjacent file.
+func IsPassedPawn(board *chess.Board, sq chess.Square, color chess.Color) bool {
+	for _, enemy := range PawnSquares(board, color.Other()) {
+		df := int(enemy.File()) - int(sq.File())
+		if df >= -1 && df <= 1 && RelativeRank(enemy, color) > RelativeRank(sq, color) {
+			return false
+		}
+	}
+	return true
+}
+
+// PawnAttacks reports whether a pawn of the given color attacks sq.
+func PawnAttacks(board *chess.Board, sq chess.Square, color chess.Color) bool {
+	dr := -1
+	if color == chess.Black {
+		dr = 1
+	}
+	r := int(sq.Rank()) + dr
+	if r < 0 || r > 7 {
+		return false
+	}
+	for _, df := range []int{-1, 1} {
+		f := int(sq.File()) + df
+		if f < 0 || f > 7 {
+			continue
+		}
+		p := board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r)))
+		if p.Type() == chess.Pawn && p.Color() == color {
+			return true
+		}
+	}
+	return false
+}
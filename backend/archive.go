@@ -0,0 +1,176 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// archivedGame is what the archive keeps in memory of a finished game.
+// Positions holds the positionKey of each position reached, once each.
+type archivedGame struct {
+	ID          string
+	EndedAt     time.Time
+	Outcome     chess.Outcome
+	Positions   []string
+	Players     []archivedPlayer
+	StartingFEN string
+	Moves       []*chess.Move
+	TimeControl string
+	Variant     string
+	Ranked      bool
+}
+
+// archivedPlayer is a player of an archivedGame. Subject is empty for
+// guests, and the ratings are only set for ranked games.
+type archivedPlayer struct {
+	Subject      string
+	Name         string
+	Color        chess.Color
+	RatingBefore int
+	RatingAfter  int
+}
+
+// archive holds every finished game. Games are written to store as they
+// end; those archived before the server started are read back from it the
+// first time the archive is searched. positions maps each positionKey to
+// the IDs of the games it arose in, and is built on that first search too.
+// activity holds when each account's latest games ended. All are guarded
+// by the archive's mutex.
+var archive = struct {
+	sync.Mutex
+	store     Store
+	loaded    bool
+	games     map[string]*archivedGame
+	positions map[string][]string
+	activity  map[string]*activityRing
+}{store: newMemoryStore(), games: make(map[string]*archivedGame), activity: make(map[string]*activityRing)}
+
+// openArchive archives finished games to store from now on.
+func openArchive(store Store) {
+	archive.Lock()
+	archive.store = store
+	archive.loaded = false
+	archive.positions = nil
+	archive.Unlock()
+}
+
+// positionKey is pos's FEN without the move clocks, so the same position
+// matches however it was reached.
+func positionKey(pos *chess.Position) string {
+	fields := strings.Fields(pos.String())
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+	return strings.Join(fields, " ")
+}
+
+// summarizeGame makes the archive's entry for a finished game. The caller
+// must hold game's lock.
+func summarizeGame(gameID string, game *Game) *archivedGame {
+	archived := &archivedGame{
+		ID:          gameID,
+		EndedAt:     game.EndedAt,
+		Outcome:     game.Game.Outcome(),
+		StartingFEN: game.StartingFEN,
+		Moves:       game.Game.Moves(),
+		TimeControl: game.TimeControl,
+		Variant:     game.Variant,
+		Ranked:      game.Ranked,
+	}
+	seen := make(map[string]bool)
+	for _, pos := range game.Game.Positions() {
+		if key := positionKey(pos); !seen[key] {
+			seen[key] = true
+			archived.Positions = append(archived.Positions, key)
+		}
+	}
+	for _, player := range game.Players {
+		archivedPlayer := archivedPlayer{Subject: player.Subject, Name: player.Name, Color: player.Color}
+		if game.Ranked && player.RatingBefore != 0 {
+			archivedPlayer.RatingBefore, archivedPlayer.RatingAfter = player.RatingBefore, player.Rating
+		}
+		archived.Players = append(archived.Players, archivedPlayer)
+	}
+	return archived
+}
+
+// addArchivedGame adds archived to the archive, its players' activity
+// and, once it is built, the position index. The caller must hold the
+// archive's mutex.
+func addArchivedGame(archived *archivedGame) {
+	if _, exists := archive.games[archived.ID]; exists {
+		return
+	}
+	archive.games[archived.ID] = archived
+	recordActivity(archived)
+	if archive.positions != nil {
+		for _, key := range archived.Positions {
+			archive.positions[key] = append(archive.positions[key], archived.ID)
+		}
+	}
+}
+
+// archiveGame adds a game that has just ended to the archive and returns
+// it encoded for saveArchivedGame, or nil if it could not be encoded. The
+// caller must hold game's lock.
+func archiveGame(gameID string, game *Game) []byte {
+	data, err := encodeGame(game)
+	if err != nil {
+		logger.Error("Error encoding finished game", slog.String("gameID", gameID), slog.Any("error", err))
+		return nil
+	}
+	archived := summarizeGame(gameID, game)
+	archive.Lock()
+	addArchivedGame(archived)
+	archive.Unlock()
+	return data
+}
+
+// saveArchivedGame writes a game encoded by archiveGame to the archive's
+// store.
+func saveArchivedGame(gameID string, data []byte) {
+	archive.Lock()
+	store := archive.store
+	archive.Unlock()
+	if err := store.ArchiveGame(gameID, data); err != nil {
+		logger.Error("Error archiving game", slog.String("gameID", gameID), slog.Any("error", err))
+	}
+}
+
+// loadArchive reads the games archived before the server started on the
+// first call, and builds the position index. The older games are added
+// to the players' activity in the order they ended, before those since.
+// The caller must hold the archive's mutex.
+func loadArchive() {
+	if !archive.loaded {
+		archive.loaded = true
+		stored, err := archive.store.LoadArchive()
+		if err != nil {
+			logger.Error("Error loading archived games", slog.Any("error", err))
+		}
+		for id, data := range stored {
+			if _, exists := archive.games[id]; exists {
+				continue
+			}
+			game, err := decodeGame(data)
+			if err != nil {
+				logger.Error("Error decoding archived game", slog.String("gameID", id), slog.Any("error", err))
+				continue
+			}
+			archive.games[id] = summarizeGame(id, game)
+		}
+		rebuildActivity()
+	}
+	if archive.positions == nil {
+		archive.positions = make(map[string][]string)
+		for id, archived := range archive.games {
+			for _, key := range archived.Positions {
+				archive.positions[key] = append(archive.positions[key], id)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/segmentio/ksuid"
+)
+
+// tokenLifetime is how long tokens issued by POST /auth/token stay valid.
+// A token only needs to be valid when a connection opens or takes a new
+// seat; see allowSeat.
+const tokenLifetime = 15 * time.Minute
+
+// Claims are the JWT claims the server issues and reads: the player's
+// stable ID in sub and their display name.
+type Claims struct {
+	Name string `json:"name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+var (
+	identities      = make(map[*websocket.Conn]Claims)
+	identitiesMutex sync.Mutex
+)
+
+// errUnsupportedHeader refuses token headers the server does not
+// understand.
+var errUnsupportedHeader = errors.New("unsupported token header")
+
+func signToken(claims Claims, secret []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// parseToken checks an HS256 token's header, signature and expiry and
+// returns its claims. Headers naming critical extensions are refused, as
+// none are understood. Segments are decoded strictly, so a signature has
+// one spelling.
+func parseToken(token string, secret []byte, now time.Time) (Claims, error) {
+	var claims Claims
+	if len(secret) == 0 {
+		return claims, errors.New("authentication is not configured")
+	}
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		typ, hasTyp := t.Header["typ"]
+		if hasTyp && typ != "JWT" || t.Header["crit"] != nil {
+			return nil, errUnsupportedHeader
+		}
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithStrictDecoding(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	switch {
+	case err == nil:
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return claims, errors.New("malformed token")
+	case parsed == nil || parsed.Method == nil || parsed.Method.Alg() != "HS256":
+		return claims, errors.New("unsupported signing algorithm")
+	case errors.Is(err, errUnsupportedHeader):
+		return claims, errUnsupportedHeader
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return claims, errors.New("invalid token signature")
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return claims, errors.New("token has no expiry")
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return claims, errors.New("token expired")
+	default:
+		return claims, errors.New("invalid token")
+	}
+	if claims.Subject == "" {
+		return claims, errors.New("token has no subject")
+	}
+	if err := validateName(claims.Name); err != nil {
+		return claims, err
+	}
+	return claims, nil
+}
+
+// authenticate reads an optional "Authorization: Bearer" header. Requests
+// without one are guests; an invalid token is an error.
+func authenticate(r *http.Request) (Claims, bool, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return Claims{}, false, nil
+	}
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		return Claims{}, false, errors.New("malformed authorization header")
+	}
+	claims, err := parseToken(token, config.JWTSecret, time.Now())
+	if err != nil {
+		return Claims{}, false, err
+	}
+	return claims, true, nil
+}
+
+func rememberIdentity(ws *websocket.Conn, claims Claims) {
+	identitiesMutex.Lock()
+	identities[ws] = claims
+	identitiesMutex.Unlock()
+}
+
+func forgetIdentity(ws *websocket.Conn) {
+	identitiesMutex.Lock()
+	delete(identities, ws)
+	delete(guests, ws)
+	identitiesMutex.Unlock()
+}
+
+func isAuthenticated(ws *websocket.Conn) bool {
+	identitiesMutex.Lock()
+	_, ok := identities[ws]
+	identitiesMutex.Unlock()
+	return ok
+}
+
+// identify returns a fresh player ID for a seat taken by ws, along with
+// the subject of its token and its display name. Authenticated players
+// default to the name in their token; guests have no subject.
+func identify(ws *websocket.Conn, name string) (playerID, subject, shownName string) {
+	playerID = ksuid.New().String()
+	identitiesMutex.Lock()
+	claims, ok := identities[ws]
+	identitiesMutex.Unlock()
+	if !ok {
+		return playerID, "", displayName(name, ksuid.New().String())
+	}
+	if name == "" {
+		name = claims.Name
+	}
+	return playerID, claims.Subject, displayName(name, claims.Subject)
+}
+
+// allowGameCreation answers ws with an error if authentication is required
+// and it is not authenticated, or if its token has expired.
+func allowGameCreation(ws *websocket.Conn) bool {
+	if config.RequireAuth && !isAuthenticated(ws) {
+		sendJSON(ws, map[string]string{"error": "authentication required"})
+		return false
+	}
+	return allowSeat(ws)
+}
+
+// allowSeat answers ws with an error if the token it connected with has
+// expired since. Expiry is only checked when a connection opens or takes a
+// new seat, so that it never cuts a game short. A connection in no game is
+// also closed, for its client to connect again with a new token.
+func allowSeat(ws *websocket.Conn) bool {
+	identitiesMutex.Lock()
+	claims, ok := identities[ws]
+	identitiesMutex.Unlock()
+	if !ok || time.Now().Before(claims.ExpiresAt.Time) {
+		return true
+	}
+	sendJSON(ws, map[string]string{"error": "token expired"})
+	gamesMutex.RLock()
+	idle := !inOtherGames(ws, "")
+	gamesMutex.RUnlock()
+	if idle {
+		closeConnection(ws, CloseAuthExpired, "token expired")
+	}
+	return false
+}
+
+// handleIssueToken serves POST /auth/token. Clients holding the shared
+// secret get a short-lived token for the given player ID, or
+// for a new one.
+func handleIssueToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	var req struct {
+		Secret   string `json:"secret"`
+		PlayerID string `json:"playerID"`
+		Name     string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	shared := config.AuthSharedSecret
+	if shared == "" || len(config.JWTSecret) == 0 {
+		http.Error(w, "authentication is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Secret), []byte(shared)) != 1 {
+		http.Error(w, "invalid secret", http.StatusUnauthorized)
+		return
+	}
+	if err := validateName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.PlayerID == "" {
+		req.PlayerID = ksuid.New().String()
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Name: req.Name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   req.PlayerID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenLifetime)),
+		},
+	}
+	token, err := signToken(claims, config.JWTSecret)
+	if err != nil {
+		http.Error(w, "could not issue token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"token":     token,
+		"playerID":  claims.Subject,
+		"expiresAt": claims.ExpiresAt.UTC().Format(time.RFC3339),
+	}); err != nil {
+		logger.Error("Error sending token", slog.Any("error", err))
+	}
+}
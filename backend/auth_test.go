@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectNeedsSeatSecretNotSubject(t *testing.T) {
+	secret := []byte("test secret")
+	useConfig(t, func(c *Config) { c.JWTSecret = secret })
+	srv := newTestServer(t)
+
+	token, err := signToken(testClaims("alice", "Alice", time.Now().Add(time.Minute)), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := dialWith(t, srv, http.Header{"Authorization": {"Bearer " + token}})
+	send(t, alice, map[string]string{"action": "create"})
+	created := readUntil(t, alice, hasStatus("created"))
+	gameID, seatID := created["gameID"].(string), created["playerID"].(string)
+	if seatID == "alice" {
+		t.Fatalf("seat ID is the token subject")
+	}
+
+	bob := dial(t, srv)
+	send(t, bob, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, bob, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{alice, bob} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	readUntil(t, bob, isState)
+	alice.Close()
+
+	attacker := dial(t, srv)
+	send(t, attacker, map[string]string{"action": "reconnect", "gameID": gameID, "playerID": "alice"})
+	if reply := readUntil(t, attacker, hasError); reply["error"] != "player not found" {
+		t.Fatalf("reconnecting with the subject: got %q, want player not found", reply["error"])
+	}
+
+	back := dial(t, srv)
+	send(t, back, map[string]string{"action": "reconnect", "gameID": gameID, "playerID": seatID})
+	readUntil(t, back, hasStatus("reconnected"))
+}
+
+func TestParseToken(t *testing.T) {
+	secret := []byte("test secret")
+	now := time.Now()
+	sign := func(claims Claims, key []byte) string {
+		token, err := signToken(claims, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := testClaims("alice", "Alice", now.Add(time.Minute))
+
+	claims, err := parseToken(sign(valid, secret), secret, now)
+	if err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	if claims.Subject != valid.Subject || claims.Name != valid.Name || !claims.ExpiresAt.Equal(valid.ExpiresAt.Time) {
+		t.Errorf("claims = %+v, want %+v", claims, valid)
+	}
+
+	for _, c := range []struct {
+		name, token string
+		secret      []byte
+		want        string
+	}{
+		{"expired", sign(testClaims("alice", "", now.Add(-time.Second)), secret), secret, "token expired"},
+		{"no expiry", sign(Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}}, secret), secret, "token has no expiry"},
+		{"no subject", sign(Claims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: valid.ExpiresAt}}, secret), secret, "token has no subject"},
+		{"wrong secret", sign(valid, []byte("other secret")), secret, "invalid token signature"},
+		{"not a token", "abc", secret, "malformed token"},
+		{"unsigned", "eyJhbGciOiJub25lIn0." + strings.Split(sign(valid, secret), ".")[1] + ".", secret, "unsupported signing algorithm"},
+		{"no secret", sign(valid, secret), nil, "authentication is not configured"},
+	} {
+		if _, err := parseToken(c.token, c.secret, now); err == nil || err.Error() != c.want {
+			t.Errorf("%s: error = %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	secret := []byte("test secret")
+	useConfig(t, func(c *Config) {
+		c.JWTSecret = secret
+		c.RequireAuth = true
+	})
+	srv := newTestServer(t)
+
+	alice := dialAuthenticated(t, srv, "alice")
+	send(t, alice, map[string]string{"action": "create"})
+	readUntil(t, alice, hasStatus("created"))
+
+	expired, err := signToken(testClaims("alice", "", time.Now().Add(-time.Minute)), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	ws, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + expired}})
+	if err == nil {
+		ws.Close()
+		t.Fatal("dialing with an expired token succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("dialing with an expired token: %v, want 401", err)
+	}
+
+	guest := dial(t, srv)
+	send(t, guest, map[string]string{"action": "create"})
+	if reply := readUntil(t, guest, hasError); reply["error"] != "authentication required" {
+		t.Errorf("creating a game as a guest: %v", reply["error"])
+	}
+}
+
+func TestIssueToken(t *testing.T) {
+	secret := []byte("test secret")
+	useConfig(t, func(c *Config) {
+		c.JWTSecret = secret
+		c.AuthSharedSecret = "shared"
+	})
+	srv := newTestServer(t)
+
+	post := func(body map[string]string) *http.Response {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		resp, err := http.Post(srv.URL+"/auth/token", "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := post(map[string]string{"secret": "wrong"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong shared secret: status %d, want 401", resp.StatusCode)
+	}
+
+	resp := post(map[string]string{"secret": "shared", "playerID": "alice", "name": "Alice"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	var issued map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := parseToken(issued["token"], secret, time.Now())
+	if err != nil {
+		t.Fatalf("issued token: %v", err)
+	}
+	if claims.Subject != "alice" || claims.Name != "Alice" || issued["playerID"] != "alice" {
+		t.Errorf("issued %v with claims %+v", issued, claims)
+	}
+}
+
+// signRaw signs a token made of the given header and payload JSON, which
+// need not be anything signToken would make.
+func signRaw(header, payload string, key []byte) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseTokenRejectsForgeries(t *testing.T) {
+	secret := []byte("test secret")
+	now := time.Now()
+	exp := strconv.FormatInt(now.Add(time.Minute).Unix(), 10)
+	claims := `{"sub":"alice","exp":` + exp + `}`
+	good := signRaw(`{"alg":"HS256","typ":"JWT"}`, claims, secret)
+	if _, err := parseToken(good, secret, now); err != nil {
+		t.Fatalf("hand-made token: %v", err)
+	}
+	parts := strings.Split(good, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory","exp":` + exp + `}`))
+	// The signature is 32 bytes, so its last character carries two unused
+	// bits. Setting one decodes to the same bytes unless decoding is
+	// strict.
+	sig := parts[2]
+	last := strings.IndexByte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_", sig[len(sig)-1])
+	loose := sig[:len(sig)-1] + string("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"[last|1])
+
+	for _, c := range []struct {
+		name, token, want string
+	}{
+		// Header checks.
+		{"alg none", signRaw(`{"alg":"none"}`, claims, secret), "unsupported signing algorithm"},
+		{"alg none unsigned", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".", "unsupported signing algorithm"},
+		{"alg HS512", signRaw(`{"alg":"HS512"}`, claims, secret), "unsupported signing algorithm"},
+		{"alg in lower case", signRaw(`{"alg":"hs256"}`, claims, secret), "unsupported signing algorithm"},
+		{"alg RS256", signRaw(`{"alg":"RS256"}`, claims, secret), "unsupported signing algorithm"},
+		{"no alg", signRaw(`{"typ":"JWT"}`, claims, secret), "unsupported signing algorithm"},
+		{"alg not a string", signRaw(`{"alg":1}`, claims, secret), "unsupported signing algorithm"},
+		{"typ not JWT", signRaw(`{"alg":"HS256","typ":"JWE"}`, claims, secret), "unsupported token header"},
+		{"critical extension", signRaw(`{"alg":"HS256","crit":["b64"],"b64":false}`, claims, secret), "unsupported token header"},
+		{"header not an object", signRaw(`["HS256"]`, claims, secret), "malformed token"},
+		{"header not JSON", signRaw(`alg=HS256`, claims, secret), "malformed token"},
+
+		// Tampered signatures.
+		{"payload swapped", parts[0] + "." + forged + "." + parts[2], "invalid token signature"},
+		{"header swapped", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." + parts[1] + "." + parts[2], "invalid token signature"},
+		// Forty characters are thirty whole bytes, with no unused bits.
+		{"signature cut short", parts[0] + "." + parts[1] + "." + sig[:40], "invalid token signature"},
+		{"signature extended", parts[0] + "." + parts[1] + "." + sig + "AAAA", "invalid token signature"},
+		{"signature empty", parts[0] + "." + parts[1] + ".", "invalid token signature"},
+		{"signature bit flipped", parts[0] + "." + parts[1] + "." + string(sig[0]^1) + sig[1:], "invalid token signature"},
+		{"signature unused bits set", parts[0] + "." + parts[1] + "." + loose, "malformed token"},
+		{"signature padded", good + "=", "malformed token"},
+		{"signature in standard base64", parts[0] + "." + parts[1] + "." + strings.NewReplacer("-", "+", "_", "/").Replace(sig) + "+/", "malformed token"},
+
+		// Malformed segments.
+		{"empty", "", "malformed token"},
+		{"two segments", parts[0] + "." + parts[1], "malformed token"},
+		{"four segments", good + ".", "malformed token"},
+		{"only dots", "..", "malformed token"},
+		{"header not base64", "!!." + parts[1] + "." + parts[2], "malformed token"},
+		{"payload not base64", parts[0] + ".!!." + parts[2], "malformed token"},
+		{"payload not JSON", signRaw(`{"alg":"HS256"}`, `sub=alice`, secret), "malformed token"},
+		{"payload not an object", signRaw(`{"alg":"HS256"}`, `["alice"]`, secret), "malformed token"},
+		{"exp not a number", signRaw(`{"alg":"HS256"}`, `{"sub":"alice","exp":"tomorrow"}`, secret), "malformed token"},
+	} {
+		if _, err := parseToken(c.token, secret, now); err == nil || err.Error() != c.want {
+			t.Errorf("%s: error = %v, want %q", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type BatteryReport struct {
+	Type    string   `json:"type"`
+	Squares []string `json:"squares"`
+	Target  string   `json:"target"`
+	Power   string   `json:"power"`
+
+	strength int
+}
+
+// batteryPower names what makes a major piece battery dangerous, with a
+// strength used to rank batteries against each other.
+func batteryPower(board *chess.Board, b Battery, color chess.Color) (string, int) {
+	if b.Front.Rank() == b.Back.Rank() && analysis.RelativeRank(b.Front, color) == 6 {
+		return "doubled_on_7th", 5
+	}
+	if target := board.Piece(b.Target); target != chess.NoPiece && target.Color() != color {
+		return "attacks_" + pieceNames[target.Type()], 4
+	}
+	if b.Front.File() == b.Back.File() {
+		switch {
+		case analysis.PawnFiles(board, color)[b.Front.File()] == 0 && analysis.PawnFiles(board, color.Other())[b.Front.File()] == 0:
+			return "open_file", 3
+		case analysis.PawnFiles(board, color)[b.Front.File()] == 0:
+			return "half_open_file", 2
+		}
+	}
+	return "aligned", 1
+}
+
+// detectBatteries lists the rook-rook and queen-rook batteries of the side
+// to move, leaving out those that only point at their own pieces.
+func detectBatteries(pos *chess.Position) []BatteryReport {
+	board := pos.Board()
+	color := pos.Turn()
+	reports := []BatteryReport{}
+	for _, b := range findBatteries(board, color) {
+		if target := board.Piece(b.Target); target != chess.NoPiece && target.Color() == color {
+			continue
+		}
+		front, back := board.Piece(b.Front).Type(), board.Piece(b.Back).Type()
+		var kind string
+		switch {
+		case front == chess.Rook && back == chess.Rook:
+			kind = "rook_rook"
+		case front == chess.Queen && back == chess.Rook, front == chess.Rook && back == chess.Queen:
+			kind = "queen_rook"
+		default:
+			continue
+		}
+		power, strength := batteryPower(board, b, color)
+		reports = append(reports, BatteryReport{
+			Type:     kind,
+			Squares:  []string{b.Front.String(), b.Back.String()},
+			Target:   b.Target.String(),
+			Power:    power,
+			strength: strength,
+		})
+	}
+	return reports
+}
+
+// strongestBattery returns the battery with the most dangerous power.
+func strongestBattery(batteries []BatteryReport) (BatteryReport, bool) {
+	if len(batteries) == 0 {
+		return BatteryReport{}, false
+	}
+	best := batteries[0]
+	for _, b := range batteries[1:] {
+		if b.strength > best.strength {
+			best = b
+		}
+	}
+	return best, true
+}
+
+func getBatteryAnalysis(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, map[string]interface{}{"batteries": detectBatteries(pos)})
+	logger.Debug("Battery analysis sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type OppositeBishops struct {
+	OppositeColoredBishops bool    `json:"oppositeColoredBishops"`
+	DrawinessScore         float64 `json:"drawinessScore"`
+	MaterialAdvantage      string  `json:"materialAdvantage,omitempty"`
+	Recommendation         string  `json:"recommendation,omitempty"`
+}
+
+// loneBishop returns the square of color's only bishop.
+func loneBishop(board *chess.Board, color chess.Color) (chess.Square, bool) {
+	bishop, count := chess.NoSquare, 0
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() == chess.Bishop && piece.Color() == color {
+			bishop = sq
+			count++
+		}
+	}
+	return bishop, count == 1
+}
+
+func pawnCount(n int) string {
+	if n == 1 {
+		return "1 pawn"
+	}
+	return fmt.Sprintf("%d pawns", n)
+}
+
+// evaluateOppositeBishops scores how drawish an opposite-coloured bishop
+// ending is, from 0 (decisive) to 1 (dead draw). A pure bishop ending
+// with one extra pawn scores 0.78; other pieces and extra pawns give the
+// stronger side more winning chances.
+func evaluateOppositeBishops(pos *chess.Position) OppositeBishops {
+	board := pos.Board()
+	whiteBishop, ok := loneBishop(board, chess.White)
+	if !ok {
+		return OppositeBishops{}
+	}
+	blackBishop, ok := loneBishop(board, chess.Black)
+	if !ok || isDarkSquare(whiteBishop) == isDarkSquare(blackBishop) {
+		return OppositeBishops{}
+	}
+
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	pure := white.Queen+white.Rooks+white.Knights+black.Queen+black.Rooks+black.Knights == 0
+	diff := white.Pawns - black.Pawns
+	ahead := chess.White
+	if diff < 0 {
+		ahead, diff = chess.Black, -diff
+	}
+
+	drawiness := 0.9 - 0.12*float64(diff)
+	if !pure {
+		drawiness = 0.6 - 0.1*float64(diff)
+	}
+	drawiness = math.Max(0.05, math.Round(drawiness*100)/100)
+	result := OppositeBishops{OppositeColoredBishops: true, DrawinessScore: drawiness}
+
+	if diff == 0 {
+		result.MaterialAdvantage = "even"
+		result.Recommendation = "With level pawns the opposite-colored bishops make a draw very likely; blockade enemy pawns on your bishop's color"
+		return result
+	}
+	result.MaterialAdvantage = "+" + pawnCount(diff)
+	if ahead == chess.Black {
+		result.MaterialAdvantage = "-" + pawnCount(diff)
+	}
+
+	bishop := whiteBishop
+	if ahead == chess.Black {
+		bishop = blackBishop
+	}
+	// The most advanced passed pawn whose promotion square the bishop
+	// covers is the one worth pushing.
+	passer := chess.NoSquare
+	for _, sq := range analysis.PawnSquares(board, ahead) {
+		promotion := chess.NewSquare(sq.File(), promotionRank(ahead))
+		if !analysis.IsPassedPawn(board, sq, ahead) || isDarkSquare(promotion) != isDarkSquare(bishop) {
+			continue
+		}
+		if passer == chess.NoSquare || analysis.RelativeRank(sq, ahead) > analysis.RelativeRank(passer, ahead) {
+			passer = sq
+		}
+	}
+	advice := "Focus on creating a passed pawn on the side where your bishop controls the promotion square"
+	if passer != chess.NoSquare {
+		advice = fmt.Sprintf("Push the passed pawn on %s; your bishop controls its promotion square %s", passer, chess.NewSquare(passer.File(), promotionRank(ahead)))
+	}
+	extra := "pawn"
+	if diff > 1 {
+		extra = pawnCount(diff)
+	}
+	if drawiness >= 0.5 {
+		result.Recommendation = fmt.Sprintf("Despite the extra %s, the opposite-colored bishops make this likely a draw. %s", extra, advice)
+	} else {
+		result.Recommendation = fmt.Sprintf("The extra %s should tell despite the opposite-colored bishops. %s", extra, advice)
+	}
+	return result
+}
+
+func getOppositeColoredBishops(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, evaluateOppositeBishops(pos))
+	logger.Debug("Opposite-colored bishop evaluation sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// breakthroughDepth is how many of the attacker's moves the breakthrough
+// search looks ahead.
+const breakthroughDepth = 3
+
+type Breakthrough struct {
+	BreakthroughAvailable bool     `json:"breakthroughAvailable"`
+	Sequence              []string `json:"sequence"`
+	ResultingPassedPawn   string   `json:"resultingPassedPawn,omitempty"`
+}
+
+// unstoppablePasser returns a passed pawn of color that the enemy king, with
+// the move, cannot catch by the rule of the square.
+func unstoppablePasser(pos *chess.Position, color chess.Color) (chess.Square, bool) {
+	board := pos.Board()
+	enemyKing := analysis.KingSquare(board, color.Other())
+	for _, sq := range analysis.PawnSquares(board, color) {
+		if !analysis.IsPassedPawn(board, sq, color) {
+			continue
+		}
+		steps := 7 - analysis.RelativeRank(sq, color)
+		if analysis.RelativeRank(sq, color) == 1 {
+			steps--
+		}
+		promotion := chess.NewSquare(sq.File(), chess.Rank8)
+		if color == chess.Black {
+			promotion = chess.NewSquare(sq.File(), chess.Rank1)
+		}
+		if enemyKing == chess.NoSquare || squareDistance(enemyKing, promotion) > steps {
+			return sq, true
+		}
+	}
+	return chess.NoSquare, false
+}
+
+// searchBreakthrough looks for a pawn-only plan for attacker that produces an
+// unstoppable passed pawn against every defence within depth moves. It
+// returns the line in SAN, preferring captures for the defence shown, and the
+// square of the resulting passer.
+func searchBreakthrough(pos *chess.Position, attacker chess.Color, depth int) ([]string, chess.Square) {
+	board := pos.Board()
+	for _, m := range pos.ValidMoves() {
+		if board.Piece(m.S1()).Type() != chess.Pawn {
+			continue
+		}
+		san := chess.AlgebraicNotation{}.Encode(pos, m)
+		next := pos.Update(m)
+		if m.Promo() != chess.NoPieceType {
+			return []string{san}, m.S2()
+		}
+		if sq, ok := unstoppablePasser(next, attacker); ok {
+			return []string{san}, sq
+		}
+		if depth == 1 || next.Status() != chess.NoMethod {
+			continue
+		}
+
+		var line []string
+		passer := chess.NoSquare
+		refuted := false
+		lineIsCapture := false
+		for _, reply := range next.ValidMoves() {
+			rest, sq := searchBreakthrough(next.Update(reply), attacker, depth-1)
+			if rest == nil {
+				refuted = true
+				break
+			}
+			isCapture := reply.HasTag(chess.Capture)
+			if line == nil || (isCapture && !lineIsCapture) {
+				line = append([]string{san, chess.AlgebraicNotation{}.Encode(next, reply)}, rest...)
+				passer = sq
+				lineIsCapture = isCapture
+			}
+		}
+		if !refuted && line != nil {
+			return line, passer
+		}
+	}
+	return nil, chess.NoSquare
+}
+
+// detectBreakthrough reports whether the side to move in a pawn endgame can
+// force a passed pawn through by sacrificing pawns.
+func detectBreakthrough(pos *chess.Position) Breakthrough {
+	result := Breakthrough{Sequence: []string{}}
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	if nonPawnMaterial(white)+nonPawnMaterial(black) != 0 {
+		return result
+	}
+	attacker := pos.Turn()
+	if _, ok := unstoppablePasser(pos, attacker); ok {
+		return result
+	}
+	line, passer := searchBreakthrough(pos, attacker, breakthroughDepth)
+	if line == nil {
+		return result
+	}
+	result.BreakthroughAvailable = true
+	result.Sequence = line
+	result.ResultingPassedPawn = passer.String()
+	return result
+}
+
+func getPawnBreakthrough(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, detectBreakthrough(pos))
+	logger.Debug("Pawn breakthrough analysis sent", slog.String("gameID", gameID))
+}
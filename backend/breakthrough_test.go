@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// classicBreakthrough has three pawns against three with the defending
+// king too far away: 1.b6! breaks through whichever pawn takes.
+const classicBreakthrough = "7k/ppp5/8/PPP5/8/8/8/7K w - - 0 1"
+
+func TestClassicBreakthrough(t *testing.T) {
+	pos := gameFromFEN(t, classicBreakthrough).Position()
+	got := detectBreakthrough(pos)
+	want := Breakthrough{
+		BreakthroughAvailable: true,
+		Sequence:              []string{"b6", "axb6", "c6", "bxa5", "cxb7"},
+		ResultingPassedPawn:   "b7",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("detectBreakthrough = %+v, want %+v", got, want)
+	}
+
+	game := gameFromFEN(t, classicBreakthrough)
+	for _, move := range got.Sequence {
+		if err := game.MoveStr(move); err != nil {
+			t.Fatalf("sequence move %s: %v", move, err)
+		}
+	}
+	if piece := game.Position().Board().Piece(chess.B7); piece != chess.WhitePawn {
+		t.Errorf("b7 holds %v after the sequence, want a white pawn", piece)
+	}
+}
+
+// TestClassicBreakthroughAgainstEveryDefence plays 1.b6 and checks that
+// white still breaks through after each of black's replies.
+func TestClassicBreakthroughAgainstEveryDefence(t *testing.T) {
+	pos := gameFromFEN(t, classicBreakthrough).Position()
+	var b6 *chess.Move
+	for _, m := range pos.ValidMoves() {
+		if m.S1() == chess.B5 && m.S2() == chess.B6 {
+			b6 = m
+		}
+	}
+	afterB6 := pos.Update(b6)
+	for _, reply := range afterB6.ValidMoves() {
+		next := afterB6.Update(reply)
+		if _, ok := unstoppablePasser(next, chess.White); ok {
+			continue
+		}
+		if line, _ := searchBreakthrough(next, chess.White, breakthroughDepth-1); line == nil {
+			t.Errorf("no breakthrough after 1.b6 %s", chess.AlgebraicNotation{}.Encode(afterB6, reply))
+		}
+	}
+}
+
+func TestBreakthroughForBlack(t *testing.T) {
+	got := detectBreakthrough(gameFromFEN(t, "7K/8/8/8/ppp5/8/PPP5/7k b - - 0 1").Position())
+	if !got.BreakthroughAvailable || got.Sequence[0] != "b3" {
+		t.Errorf("detectBreakthrough = %+v, want one starting b3", got)
+	}
+}
+
+func TestNoBreakthrough(t *testing.T) {
+	tests := map[string]string{
+		"defending king in time":   "1k6/ppp5/8/PPP5/8/8/8/7K w - - 0 1",
+		"pieces on the board":      "7k/ppp5/8/PPP5/8/8/8/6QK w - - 0 1",
+		"passer already runs":      "7k/8/8/P7/8/8/8/7K w - - 0 1",
+		"blocked pawns only":       "7k/8/p7/P7/8/8/8/7K w - - 0 1",
+		"side to move has no pawn": "7k/ppp5/8/8/8/8/8/7K w - - 0 1",
+	}
+	for name, fen := range tests {
+		if got := detectBreakthrough(gameFromFEN(t, fen).Position()); got.BreakthroughAvailable || len(got.Sequence) != 0 {
+			t.Errorf("%s: detectBreakthrough = %+v, want none", name, got)
+		}
+	}
+}
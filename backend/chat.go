@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	chatMaxLength   = 300
+	chatHistorySize = 50
+	chatRateLimit   = 5
+	chatRateWindow  = 10 * time.Second
+)
+
+type ChatMessage struct {
+	From    string    `json:"from"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// sanitizeChat strips control characters and surrounding whitespace.
+func sanitizeChat(message string) string {
+	message = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, message)
+	return strings.TrimSpace(message)
+}
+
+// allowChat records a message sent at now in sent, reporting false instead
+// if that would exceed chatRateLimit messages within chatRateWindow.
+func allowChat(sent []time.Time, now time.Time) ([]time.Time, bool) {
+	recent := sent[:0]
+	for _, t := range sent {
+		if now.Sub(t) < chatRateWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= chatRateLimit {
+		return recent, false
+	}
+	return append(recent, now), true
+}
+
+func sendChat(ws *websocket.Conn, gameID, text string) {
+	text = sanitizeChat(text)
+	if text == "" {
+		sendJSON(ws, map[string]string{"error": "empty message"})
+		return
+	}
+	if utf8.RuneCountInString(text) > chatMaxLength {
+		sendJSON(ws, map[string]string{"error": "message too long"})
+		return
+	}
+
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	game.Lock()
+	defer game.Unlock()
+
+	now := time.Now()
+	from := ""
+	allowed := false
+	if player := getPlayer(ws, game); player != nil {
+		from = colorName(player.Color)
+		player.ChatTimes, allowed = allowChat(player.ChatTimes, now)
+	} else if isSpectator(ws, game) {
+		from = "spectator"
+		if game.SpectatorChatTimes == nil {
+			game.SpectatorChatTimes = make(map[*websocket.Conn][]time.Time)
+		}
+		game.SpectatorChatTimes[ws], allowed = allowChat(game.SpectatorChatTimes[ws], now)
+	} else {
+		sendJSON(ws, map[string]string{"error": "not in this game"})
+		return
+	}
+	if !allowed {
+		sendJSON(ws, map[string]string{"error": "too many chat messages"})
+		return
+	}
+
+	game.ChatHistory = append(game.ChatHistory, ChatMessage{From: from, Message: text, SentAt: now})
+	if len(game.ChatHistory) > chatHistorySize {
+		game.ChatHistory = game.ChatHistory[len(game.ChatHistory)-chatHistorySize:]
+	}
+	chat := map[string]string{"type": "chat", "from": from, "message": text}
+	for _, player := range game.Players {
+		sendToPlayer(player, chat)
+	}
+	for _, spectator := range game.Spectators {
+		sendJSON(spectator, chat)
+	}
+	logger.Info("Chat message", slog.String("gameID", gameID), slog.String("from", from))
+}
+
+// getChatHistory sends the game's recent chat to one of its players,
+// coaches or spectators.
+func getChatHistory(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	game.Lock()
+	defer game.Unlock()
+
+	if getPlayer(ws, game) == nil && !isGameCoach(ws, game) && !isSpectator(ws, game) {
+		sendJSON(ws, map[string]string{"error": "not in this game"})
+		return
+	}
+	messages := append([]ChatMessage{}, game.ChatHistory...)
+	sendJSON(ws, map[string]interface{}{"type": "chat_history", "messages": messages})
+}
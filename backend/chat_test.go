@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestChatHistoryIsForTheGameOnly(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+
+	send(t, white, map[string]string{"action": "chat", "gameID": gameID, "message": "good luck"})
+	readUntil(t, white, func(msg map[string]interface{}) bool { return msg["type"] == "chat" })
+
+	outsider := dial(t, srv)
+	send(t, outsider, map[string]string{"action": "getChatHistory", "gameID": gameID})
+	if msg := readUntil(t, outsider, func(msg map[string]interface{}) bool {
+		return hasError(msg) || msg["type"] == "chat_history"
+	}); msg["error"] != "not in this game" {
+		t.Fatalf("outsider got %v", msg)
+	}
+
+	spectator := dial(t, srv)
+	send(t, spectator, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, spectator, hasStatus("spectating"))
+	send(t, spectator, map[string]string{"action": "getChatHistory", "gameID": gameID})
+	history := readUntil(t, spectator, func(msg map[string]interface{}) bool {
+		if hasError(msg) {
+			t.Fatalf("spectator got %v", msg["error"])
+		}
+		return msg["type"] == "chat_history"
+	})
+	messages, _ := history["messages"].([]interface{})
+	if len(messages) != 1 || messages[0].(map[string]interface{})["message"] != "good luck" {
+		t.Errorf("spectator got history %v", history["messages"])
+	}
+}
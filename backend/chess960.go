@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// chess960BackRank shuffles white's back rank under the Chess960 rules:
+// the bishops on opposite-coloured squares and the king between the rooks.
+func chess960BackRank() [8]byte {
+	var rank [8]byte
+	// a1 is a dark square, so even files are dark on the first rank.
+	rank[2*rand.Intn(4)] = 'B'
+	rank[2*rand.Intn(4)+1] = 'B'
+
+	empty := func() []int {
+		var files []int
+		for f, piece := range rank {
+			if piece == 0 {
+				files = append(files, f)
+			}
+		}
+		return files
+	}
+	for _, piece := range []byte{'Q', 'N', 'N'} {
+		files := empty()
+		rank[files[rand.Intn(len(files))]] = piece
+	}
+	// The three squares left take rook, king and rook in that order.
+	for i, f := range empty() {
+		rank[f] = "RKR"[i]
+	}
+	return rank
+}
+
+// chess960FEN returns the starting FEN for back rank. notnil/chess only
+// castles from the standard king and rook squares, so castling rights are
+// only given when the shuffle lands on the standard arrangement.
+func chess960FEN(rank [8]byte) string {
+	white := string(rank[:])
+	castling := "-"
+	if white == "RNBQKBNR" {
+		castling = "KQkq"
+	}
+	return strings.ToLower(white) + "/pppppppp/8/8/8/8/PPPPPPPP/" + white + " w " + castling + " - 0 1"
+}
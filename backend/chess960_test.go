@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960BackRank(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		rank := chess960BackRank()
+		files := map[byte][]int{}
+		for f, piece := range rank {
+			files[piece] = append(files[piece], f)
+		}
+		bishops, rooks, king := files['B'], files['R'], files['K']
+		switch {
+		case len(bishops) != 2 || len(rooks) != 2 || len(king) != 1 || len(files['Q']) != 1 || len(files['N']) != 2:
+			t.Fatalf("back rank %s does not have the standard pieces", rank[:])
+		case bishops[0]%2 == bishops[1]%2:
+			t.Fatalf("back rank %s has both bishops on one colour", rank[:])
+		case king[0] < rooks[0] || king[0] > rooks[1]:
+			t.Fatalf("back rank %s does not have the king between the rooks", rank[:])
+		}
+
+		fen := chess960FEN(rank)
+		pos := gameFromFEN(t, fen).Position()
+		if got := strings.Fields(pos.String())[0]; got != strings.Fields(fen)[0] {
+			t.Fatalf("position %s from FEN %s", got, fen)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// GameClock tracks both players' remaining time. All fields are guarded
+// by the owning Game's mutex.
+type GameClock struct {
+	// Remaining is indexed by clockIndex; the side to move is charged
+	// for the time since TurnStarted on top of this.
+	Remaining   [2]time.Duration
+	TurnStarted time.Time
+
+	// ClockMode is "increment", "delay" (Bronstein: time used is given
+	// back up to Increment), "simple" (no time added) or
+	// "correspondence" (Increment afresh for every move).
+	ClockMode string
+	Increment time.Duration
+
+	// History holds Remaining as it was before each move, so takebacks
+	// can restore it.
+	History [][2]time.Duration
+
+	// flagTimer fires when the side to move runs out of time. It is nil
+	// while the clock is stopped.
+	flagTimer *time.Timer
+}
+
+// parseTimeControl reads a time control such as "5+3": five minutes per
+// side plus a three second increment per move. "5+3d" gives a three second
+// Bronstein delay instead and a bare "5" adds no time at all.
+func parseTimeControl(s string) (*GameClock, error) {
+	base, increment, found := strings.Cut(s, "+")
+	minutes, err := strconv.ParseFloat(base, 64)
+	if err != nil || minutes <= 0 {
+		return nil, errors.New("invalid time control")
+	}
+	clock := &GameClock{ClockMode: "simple"}
+	if found {
+		clock.ClockMode = "increment"
+		if strings.HasSuffix(increment, "d") {
+			clock.ClockMode = "delay"
+			increment = strings.TrimSuffix(increment, "d")
+		}
+		seconds, err := strconv.Atoi(increment)
+		if err != nil || seconds < 0 {
+			return nil, errors.New("invalid time control")
+		}
+		clock.Increment = time.Duration(seconds) * time.Second
+	}
+	initial := time.Duration(minutes * float64(time.Minute))
+	clock.Remaining = [2]time.Duration{initial, initial}
+	return clock, nil
+}
+
+func clockIndex(color chess.Color) int {
+	if color == chess.Black {
+		return 1
+	}
+	return 0
+}
+
+// remaining is color's time left at now, with turn being the side to move.
+func (c *GameClock) remaining(color, turn chess.Color, now time.Time) time.Duration {
+	left := c.Remaining[clockIndex(color)]
+	if c.flagTimer != nil && color == turn {
+		left -= now.Sub(c.TurnStarted)
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// switchTurn charges mover for their move, adds any increment or delay and
+// starts the opponent's clock.
+func (c *GameClock) switchTurn(mover chess.Color, now time.Time) {
+	used := now.Sub(c.TurnStarted)
+	c.History = append(c.History, c.Remaining)
+	c.Remaining[clockIndex(mover)] -= used
+	switch c.ClockMode {
+	case "increment":
+		c.Remaining[clockIndex(mover)] += c.Increment
+	case "delay":
+		c.Remaining[clockIndex(mover)] += min(used, c.Increment)
+	case "correspondence":
+		c.Remaining[clockIndex(mover)] = c.Increment
+	}
+	c.TurnStarted = now
+}
+
+// running reports whether the side to move's time is being charged.
+func (c *GameClock) running() bool {
+	return c.flagTimer != nil
+}
+
+func (c *GameClock) stop() {
+	if c.flagTimer != nil {
+		c.flagTimer.Stop()
+		c.flagTimer = nil
+	}
+}
+
+// stopAt stops the clock at now, charging turn for its move so far, for a
+// game that has ended.
+func (c *GameClock) stopAt(turn chess.Color, now time.Time) {
+	if c.running() {
+		c.Remaining[clockIndex(turn)] = c.remaining(turn, turn, now)
+		c.stop()
+	}
+}
+
+// startClock starts timing the first move once both players are seated
+// and, in timed games, starts white's clock. Callers hold gamesMutex.
+func startClock(gameID string, game *Game) {
+	game.Lock()
+	defer game.Unlock()
+	game.MoveStartedAt = time.Now()
+	if game.StartedAt.IsZero() {
+		game.StartedAt = game.MoveStartedAt
+	}
+	if game.Clock == nil || game.Clock.running() {
+		return
+	}
+	game.Clock.TurnStarted = time.Now()
+	armFlag(gameID, game)
+}
+
+// armFlag replaces the clock's flag timer with one that fires when the
+// side to move's time runs out, for a clock just started or whose turn or
+// remaining time just changed. Callers hold game's lock.
+func armFlag(gameID string, game *Game) {
+	game.Clock.stop()
+	turn := game.Game.Position().Turn()
+	left := game.Clock.Remaining[clockIndex(turn)] - time.Since(game.Clock.TurnStarted)
+	var timer *time.Timer
+	timer = time.AfterFunc(max(left, 0), func() {
+		checkFlag(gameID, game, &timer)
+	})
+	game.Clock.flagTimer = timer
+}
+
+// checkFlag ends the game on time if the side to move has none left, for
+// a flag timer that fired. A timer replaced since it was armed does
+// nothing, as does one for a game that is over.
+func checkFlag(gameID string, game *Game, timer **time.Timer) {
+	gamesMutex.RLock()
+	if games[gameID] != game {
+		gamesMutex.RUnlock()
+		return
+	}
+	game.Lock()
+	if game.Clock.flagTimer != *timer {
+		game.Unlock()
+		gamesMutex.RUnlock()
+		return
+	}
+	if game.Game.Outcome() != chess.NoOutcome {
+		game.Clock.stop()
+		game.Unlock()
+		gamesMutex.RUnlock()
+		return
+	}
+	turn := game.Game.Position().Turn()
+	flagged := game.Clock.remaining(turn, turn, time.Now()) <= 0
+	if !flagged {
+		armFlag(gameID, game)
+	}
+	game.Unlock()
+	gamesMutex.RUnlock()
+
+	if flagged {
+		forfeitOnTime(gameID, turn)
+	}
+}
+
+// cannotMate reports whether color has no way left to checkmate however
+// its opponent plays: it has a bare king, a lone knight or bishop against
+// a bare king, or both sides have nothing but their kings and bishops
+// that all stand on squares of one colour.
+func cannotMate(board *chess.Board, color chess.Color) bool {
+	own, theirs := 0, 0
+	lonePieceMinor, bishopsOnly := false, true
+	bishopsOn := map[bool]bool{}
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() == chess.King {
+			continue
+		}
+		if piece.Color() == color {
+			own++
+			lonePieceMinor = piece.Type() == chess.Knight || piece.Type() == chess.Bishop
+		} else {
+			theirs++
+		}
+		if piece.Type() == chess.Bishop {
+			bishopsOn[isDarkSquare(sq)] = true
+		} else {
+			bishopsOnly = false
+		}
+	}
+	switch {
+	case own == 0:
+		return true
+	case own == 1 && lonePieceMinor && theirs == 0:
+		return true
+	}
+	return bishopsOnly && len(bishopsOn) == 1
+}
+
+// forfeitOnTime ends the game against color for running out of time, or
+// drawn if the opponent could never have mated.
+func forfeitOnTime(gameID string, color chess.Color) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		return
+	}
+	game.Lock()
+	forfeited := game.Game.Outcome() == chess.NoOutcome
+	if forfeited {
+		if cannotMate(game.Game.Position().Board(), color.Other()) {
+			game.Game.Draw(chess.DrawOffer)
+		} else {
+			game.Game.Resign(color)
+		}
+		game.TimedOut = true
+		game.Clock.Remaining[clockIndex(color)] = 0
+		clearTakebackRequest(game)
+	}
+	game.Clock.stop()
+	game.Unlock()
+	gamesMutex.Unlock()
+
+	if forfeited {
+		logger.Info("Player ran out of time", slog.String("gameID", gameID), slog.String("playerColor", colorName(color)))
+		broadcastGameState(gameID)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		in        string
+		mode      string
+		initial   time.Duration
+		increment time.Duration
+	}{
+		{"5", "simple", 5 * time.Minute, 0},
+		{"5+3", "increment", 5 * time.Minute, 3 * time.Second},
+		{"5+3d", "delay", 5 * time.Minute, 3 * time.Second},
+		{"0.5+0", "increment", 30 * time.Second, 0},
+		{"15+10d", "delay", 15 * time.Minute, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		clock, err := parseTimeControl(tt.in)
+		if err != nil {
+			t.Errorf("parseTimeControl(%q): %v", tt.in, err)
+			continue
+		}
+		if clock.ClockMode != tt.mode || clock.Increment != tt.increment {
+			t.Errorf("parseTimeControl(%q) = %s %v, want %s %v", tt.in, clock.ClockMode, clock.Increment, tt.mode, tt.increment)
+		}
+		if clock.Remaining != [2]time.Duration{tt.initial, tt.initial} {
+			t.Errorf("parseTimeControl(%q) starts with %v, want %v each", tt.in, clock.Remaining, tt.initial)
+		}
+	}
+}
+
+func TestParseTimeControlInvalid(t *testing.T) {
+	for _, in := range []string{"", "0", "-5", "five", "5+", "5+x", "5+-3", "5+3x", "5+3dd"} {
+		if _, err := parseTimeControl(in); err == nil {
+			t.Errorf("parseTimeControl(%q) succeeded", in)
+		}
+	}
+}
+
+func TestSwitchTurn(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name string
+		tc   string
+		used time.Duration
+		want time.Duration
+	}{
+		{"simple", "5", 10 * time.Second, 5*time.Minute - 10*time.Second},
+		{"increment", "5+3", 10 * time.Second, 5*time.Minute - 7*time.Second},
+		{"increment exceeds time used", "5+3", time.Second, 5*time.Minute + 2*time.Second},
+		{"delay gives back the delay", "5+3d", 10 * time.Second, 5*time.Minute - 7*time.Second},
+		{"delay gives back only the time used", "5+3d", time.Second, 5 * time.Minute},
+		{"delay exactly used", "5+3d", 3 * time.Second, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, err := parseTimeControl(tt.tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock.TurnStarted = start
+			clock.switchTurn(chess.White, start.Add(tt.used))
+
+			if got := clock.Remaining[clockIndex(chess.White)]; got != tt.want {
+				t.Errorf("white has %v, want %v", got, tt.want)
+			}
+			if got := clock.Remaining[clockIndex(chess.Black)]; got != 5*time.Minute {
+				t.Errorf("black has %v, want 5m0s", got)
+			}
+			if !clock.TurnStarted.Equal(start.Add(tt.used)) {
+				t.Errorf("black's turn started at %v, want %v", clock.TurnStarted, start.Add(tt.used))
+			}
+			if len(clock.History) != 1 || clock.History[0] != [2]time.Duration{5 * time.Minute, 5 * time.Minute} {
+				t.Errorf("history = %v, want the clocks before the move", clock.History)
+			}
+		})
+	}
+}
+
+func TestDelayDoesNotAccumulate(t *testing.T) {
+	clock, err := parseTimeControl("1+5d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	clock.TurnStarted = now
+	for i := 0; i < 10; i++ {
+		now = now.Add(2 * time.Second)
+		clock.switchTurn(chess.White, now)
+		now = now.Add(2 * time.Second)
+		clock.switchTurn(chess.Black, now)
+	}
+	if clock.Remaining != [2]time.Duration{time.Minute, time.Minute} {
+		t.Errorf("after quick moves the clocks read %v, want a minute each", clock.Remaining)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	now := time.Now()
+	clock := &GameClock{Remaining: [2]time.Duration{time.Minute, 2 * time.Minute}, TurnStarted: now}
+
+	if got := clock.remaining(chess.White, chess.White, now.Add(10*time.Second)); got != time.Minute {
+		t.Errorf("stopped clock: white has %v, want 1m0s", got)
+	}
+
+	clock.flagTimer = time.NewTimer(time.Hour)
+	defer clock.stop()
+	if got := clock.remaining(chess.White, chess.White, now.Add(10*time.Second)); got != 50*time.Second {
+		t.Errorf("running clock: white has %v, want 50s", got)
+	}
+	if got := clock.remaining(chess.Black, chess.White, now.Add(10*time.Second)); got != 2*time.Minute {
+		t.Errorf("running clock: black has %v while waiting, want 2m0s", got)
+	}
+	if got := clock.remaining(chess.White, chess.White, now.Add(2*time.Minute)); got != 0 {
+		t.Errorf("flagged: white has %v, want 0", got)
+	}
+}
+
+func TestFlagFall(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create", "timeControl": "0.005+0"})
+	gameID := readUntil(t, creator, hasStatus("created"))["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	send(t, creator, map[string]string{"action": "ready", "gameID": gameID})
+	send(t, joiner, map[string]string{"action": "ready", "gameID": gameID})
+
+	state := readUntil(t, creator, hasStatus("timeout"))
+	if state["winner"] != "black" || state["whiteTime"] != float64(0) {
+		t.Errorf("white flagged: got winner %v with %v ms left", state["winner"], state["whiteTime"])
+	}
+}
+
+// TestFlagFallAfterMove checks that a move rearms the flag timer for the
+// side now to move.
+func TestFlagFallAfterMove(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create", "timeControl": "0.01+0"})
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID := created["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	send(t, creator, map[string]string{"action": "ready", "gameID": gameID})
+	send(t, joiner, map[string]string{"action": "ready", "gameID": gameID})
+	white := creator
+	if created["color"] != "w" {
+		white = joiner
+	}
+	readUntil(t, white, isState)
+	send(t, white, map[string]string{"action": "move", "gameID": gameID, "move": "e4"})
+
+	state := readUntil(t, white, hasStatus("timeout"))
+	if state["winner"] != "white" || state["blackTime"] != float64(0) || state["moveCount"] != float64(1) {
+		t.Errorf("black flagged after e4: got winner %v with %v ms left after %v moves", state["winner"], state["blackTime"], state["moveCount"])
+	}
+}
+
+// TestFlagFallAgainstBareKing checks that running out of time is a draw
+// when the opponent has only a king left to mate with.
+func TestFlagFallAgainstBareKing(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create", "timeControl": "0.005+0", "fen": "8/8/8/4k3/8/8/8/3QK3 w - - 0 1"})
+	gameID := readUntil(t, creator, hasStatus("created"))["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	send(t, creator, map[string]string{"action": "ready", "gameID": gameID})
+	send(t, joiner, map[string]string{"action": "ready", "gameID": gameID})
+
+	state := readUntil(t, creator, hasStatus("timeout"))
+	if _, won := state["winner"]; won || state["method"] != "insufficientMaterial" {
+		t.Errorf("white flagged against a bare king: got winner %v by %v", state["winner"], state["method"])
+	}
+}
+
+// TestCannotMate asks whether the opponent of the side to move, which has
+// just flagged, could still mate.
+func TestCannotMate(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		{"8/8/8/4k3/8/8/8/3QK3 w - - 0 1", true},
+		{"8/8/8/4k3/8/8/8/3QK3 b - - 0 1", false},
+		{"8/8/8/3bk3/8/8/8/4KB2 w - - 0 1", true},
+		{"8/8/8/4kb2/8/8/8/2B1K3 w - - 0 1", false},
+		{"8/8/8/3bk3/8/8/8/3NKB2 w - - 0 1", false},
+		{"8/8/4p3/4k3/8/8/8/2B1K3 b - - 0 1", false},
+		{"8/8/8/3nk3/8/8/8/4K3 w - - 0 1", true},
+		{"8/8/8/3nk3/8/8/8/4KP2 w - - 0 1", false},
+		{"8/8/8/2nnk3/8/8/8/4K3 w - - 0 1", false},
+	}
+	for _, tt := range tests {
+		pos := gameFromFEN(t, tt.fen).Position()
+		if got := cannotMate(pos.Board(), pos.Turn().Other()); got != tt.want {
+			t.Errorf("cannotMate(%s) for %s = %v, want %v", tt.fen, pos.Turn().Other(), got, tt.want)
+		}
+	}
+}
+
+func TestClockHistory(t *testing.T) {
+	clock, err := parseTimeControl("5+2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	clock.TurnStarted = now
+	for i, used := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second} {
+		now = now.Add(used)
+		clock.switchTurn([]chess.Color{chess.White, chess.Black}[i%2], now)
+	}
+
+	want := []ClockSnapshot{
+		{MoveNumber: 1, WhiteRemainingMs: 292000, BlackRemainingMs: 300000},
+		{MoveNumber: 2, WhiteRemainingMs: 292000, BlackRemainingMs: 282000},
+		{MoveNumber: 3, WhiteRemainingMs: 264000, BlackRemainingMs: 282000},
+	}
+	got := clockHistory(clock)
+	if len(got) != len(want) {
+		t.Fatalf("clockHistory = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("snapshot %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGetClockHistory(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create", "timeControl": "5+3"})
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID := created["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		readUntil(t, ws, isState)
+	}
+	white, black := creator, joiner
+	if created["color"] != "w" {
+		white, black = joiner, creator
+	}
+	play(t, white, black, gameID, "e4", "e5")
+
+	send(t, white, map[string]string{"action": "get_clock_history", "gameID": gameID})
+	if msg := readUntil(t, white, hasError); msg["error"] != "game still in progress" {
+		t.Errorf("clock history during the game: %v", msg["error"])
+	}
+
+	send(t, black, map[string]string{"action": "resign", "gameID": gameID})
+	readUntil(t, white, hasStatus("resigned"))
+	send(t, white, map[string]string{"action": "get_clock_history", "gameID": gameID})
+	msg := readUntil(t, white, func(msg map[string]interface{}) bool { return hasError(msg) || msg["clockHistory"] != nil })
+	history, _ := msg["clockHistory"].([]interface{})
+	if len(history) != 2 {
+		t.Fatalf("clock history = %v, want two moves", msg)
+	}
+	for i, entry := range history {
+		snapshot := entry.(map[string]interface{})
+		white, black := snapshot["whiteRemainingMs"].(float64), snapshot["blackRemainingMs"].(float64)
+		// The moves are instant, so each side has gained most of an increment.
+		if snapshot["moveNumber"] != float64(i+1) || white < 300000 || white > 303000 || black < 300000 || black > 303000 {
+			t.Errorf("snapshot %d = %v", i, snapshot)
+		}
+	}
+}
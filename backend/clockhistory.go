@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// ClockSnapshot is both clocks as they stood after a move. MoveNumber
+// counts half-moves from 1.
+type ClockSnapshot struct {
+	MoveNumber       int   `json:"moveNumber"`
+	WhiteRemainingMs int64 `json:"whiteRemainingMs"`
+	BlackRemainingMs int64 `json:"blackRemainingMs"`
+}
+
+// clockHistory returns the clocks after each move. clock.History holds
+// them as they were before each move, so the reading after a move is the
+// one before the next, or the current one after the last move.
+func clockHistory(clock *GameClock) []ClockSnapshot {
+	snapshots := []ClockSnapshot{}
+	for i := range clock.History {
+		after := clock.Remaining
+		if i+1 < len(clock.History) {
+			after = clock.History[i+1]
+		}
+		snapshots = append(snapshots, ClockSnapshot{
+			MoveNumber:       i + 1,
+			WhiteRemainingMs: after[clockIndex(chess.White)].Milliseconds(),
+			BlackRemainingMs: after[clockIndex(chess.Black)].Milliseconds(),
+		})
+	}
+	return snapshots
+}
+
+func getClockHistory(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	game.Lock()
+	var errMsg string
+	var history []ClockSnapshot
+	switch {
+	case game.Clock == nil:
+		errMsg = "game has no clock"
+	case game.Game.Outcome() == chess.NoOutcome:
+		errMsg = "game still in progress"
+	default:
+		history = clockHistory(game.Clock)
+	}
+	game.Unlock()
+	gamesMutex.Unlock()
+
+	if errMsg != "" {
+		sendJSON(ws, map[string]string{"error": errMsg})
+		return
+	}
+	sendJSON(ws, map[string]interface{}{"gameID": gameID, "clockHistory": history})
+	logger.Debug("Clock history sent", slog.String("gameID", gameID), slog.Int("moves", len(history)))
+}
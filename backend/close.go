@@ -0,0 +1,21 @@
+package main
+
+import "github.com/gorilla/websocket"
+
+// Application-level close codes sent to clients before the server closes a
+// connection, so they can tell the user why. They are listed in the README.
+const (
+	CloseGameEnded         = 4000
+	CloseOpponentAbandoned = 4001
+	CloseServerShutdown    = 4002
+	CloseAuthExpired       = 4004
+	CloseRateLimited       = 4005
+)
+
+// closeConnection has ws's writer send a close frame with the given code
+// and reason after anything already queued, then close ws.
+func closeConnection(ws *websocket.Conn, code int, reason string) {
+	if !enqueue(ws, outgoing{closeCode: code, closeReason: reason}) {
+		ws.Close()
+	}
+}
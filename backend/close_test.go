@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// readCloseCode reads from ws until the server closes it and returns the
+// close code it sent.
+func readCloseCode(t testing.TB, ws *websocket.Conn) int {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, _, err := ws.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("reading until closed: %v", err)
+		}
+		return closeErr.Code
+	}
+}
+
+func TestCloseOnAbandonment(t *testing.T) {
+	useConfig(t, func(c *Config) { c.ReconnectGrace = 50 * time.Millisecond })
+	srv := newTestServer(t)
+	white, black, _ := startTestGame(t, srv)
+
+	black.Close()
+	if state := readUntil(t, white, hasStatus("abandoned")); state["winner"] != nil && state["winner"] != "white" {
+		t.Errorf("winner = %v, want white", state["winner"])
+	}
+	if code := readCloseCode(t, white); code != CloseOpponentAbandoned {
+		t.Errorf("close code = %d, want %d", code, CloseOpponentAbandoned)
+	}
+}
+
+func TestAbandonmentKeepsConnectionInOtherGames(t *testing.T) {
+	useConfig(t, func(c *Config) { c.ReconnectGrace = 50 * time.Millisecond })
+	srv := newTestServer(t)
+	white, black, _ := startTestGame(t, srv)
+	send(t, white, map[string]string{"action": "create"})
+	readUntil(t, white, hasStatus("created"))
+
+	black.Close()
+	readUntil(t, white, hasStatus("abandoned"))
+	send(t, white, map[string]string{"action": "create"})
+	readUntil(t, white, hasStatus("created"))
+}
+
+// TestCloseOnTokenExpiry lets the token of two connections expire. The one
+// playing a game keeps its connection and is only refused a new seat; the
+// idle one is closed when it asks for one.
+func TestCloseOnTokenExpiry(t *testing.T) {
+	useConfig(t, func(c *Config) { c.JWTSecret = []byte("test secret") })
+	srv := newTestServer(t)
+	// Token times are in whole seconds, so this expires one to two
+	// seconds from now.
+	expires := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	token, err := signToken(testClaims("alice", "", expires), config.JWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}}
+	playing, idle := dialWith(t, srv, header), dialWith(t, srv, header)
+	send(t, playing, map[string]string{"action": "create"})
+	gameID := readUntil(t, playing, hasStatus("created"))["gameID"].(string)
+	opponent := dial(t, srv)
+	send(t, opponent, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, opponent, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{playing, opponent} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	readUntil(t, playing, isState)
+
+	time.Sleep(time.Until(expires) + 100*time.Millisecond)
+	for _, ws := range []*websocket.Conn{playing, idle} {
+		send(t, ws, map[string]string{"action": "create"})
+		if reply := readUntil(t, ws, hasError); reply["error"] != "token expired" {
+			t.Errorf("creating a game after the token expired: %v", reply)
+		}
+	}
+	if code := readCloseCode(t, idle); code != CloseAuthExpired {
+		t.Errorf("idle connection's close code = %d, want %d", code, CloseAuthExpired)
+	}
+
+	send(t, playing, map[string]string{"action": "getLegalMoves", "gameID": gameID})
+	if reply := readUntil(t, playing, func(msg map[string]interface{}) bool { return hasError(msg) || msg["legalMoves"] != nil }); reply["legalMoves"] == nil {
+		t.Errorf("player's connection after the token expired: %v", reply)
+	}
+}
+
+func TestCloseOnRateLimit(t *testing.T) {
+	useConfig(t, func(c *Config) { c.ConnectionRateLimit = 1 })
+	srv := newTestServer(t)
+	ws := dial(t, srv)
+	for i := 0; i < rateLimitStrikes+1; i++ {
+		send(t, ws, map[string]string{"action": "listGames"})
+	}
+	if code := readCloseCode(t, ws); code != CloseRateLimited {
+		t.Errorf("close code = %d, want %d", code, CloseRateLimited)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// isCoach reports whether token grants coaching rights, either through the
+// coach token or the admin token.
+func isCoach(token string) bool {
+	if isAdmin(token) {
+		return true
+	}
+	coachToken := config.CoachToken
+	if coachToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(coachToken)) == 1
+}
+
+func isGameCoach(ws *websocket.Conn, game *Game) bool {
+	for _, coach := range game.Coaches {
+		if coach == ws {
+			return true
+		}
+	}
+	return false
+}
+
+func coachObserve(ws *websocket.Conn, msg map[string]string) {
+	gameID := msg["gameID"]
+	if !isCoach(msg["coachToken"]) {
+		sendJSON(ws, map[string]string{"error": "unauthorized"})
+		logger.Warn("Unauthorized coach attempt", slog.String("gameID", gameID))
+		return
+	}
+
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to coach non-existent game", slog.String("gameID", gameID))
+		return
+	}
+	if !isGameCoach(ws, game) {
+		game.Coaches = append(game.Coaches, ws)
+		indexGame(ws, gameID)
+	}
+	gamesMutex.Unlock()
+
+	sendJSON(ws, map[string]string{"status": "observing", "gameID": gameID})
+	logger.Info("Coach attached", slog.String("gameID", gameID))
+
+	broadcastGameState(gameID)
+}
+
+// coachComment relays a comment from one coach to every coach of the game.
+// Players and spectators never see it.
+func coachComment(ws *websocket.Conn, gameID, text string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists || !isGameCoach(ws, game) {
+		sendJSON(ws, map[string]string{"error": "not coaching this game"})
+		return
+	}
+	for _, coach := range game.Coaches {
+		sendJSON(coach, map[string]string{"type": "coach_comment", "text": text})
+	}
+}
+
+// coachWhisper sends a message from a coach to the player of one color only.
+func coachWhisper(ws *websocket.Conn, gameID, targetColor, message string) {
+	color, ok := parseColorName(targetColor)
+	if !ok {
+		sendJSON(ws, map[string]string{"error": "invalid target color"})
+		return
+	}
+
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists || !isGameCoach(ws, game) {
+		sendJSON(ws, map[string]string{"error": "not coaching this game"})
+		return
+	}
+	for _, player := range game.Players {
+		if player.Color == color {
+			sendToPlayer(player, map[string]string{"type": "coach_whisper", "message": message})
+			logger.Info("Coach whispered", slog.String("gameID", gameID), slog.String("playerColor", colorName(color)))
+			return
+		}
+	}
+	sendJSON(ws, map[string]string{"error": "no player with that color"})
+}
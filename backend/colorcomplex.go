@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type ColorWeakness struct {
+	WeakComplex string   `json:"weakComplex"`
+	WeakSquares []string `json:"weakSquares"`
+	Reason      string   `json:"reason,omitempty"`
+}
+
+func isDarkSquare(sq chess.Square) bool {
+	return (int(sq.File())+int(sq.Rank()))%2 == 0
+}
+
+func complexName(dark bool) string {
+	if dark {
+		return "dark"
+	}
+	return "light"
+}
+
+// evaluateColorWeakness finds the colour complex around color's king that its
+// pawns cannot guard and its bishops no longer cover.
+func evaluateColorWeakness(board *chess.Board, color chess.Color) ColorWeakness {
+	hasBishop := map[bool]bool{}
+	enemyBishop := map[bool]bool{}
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() != chess.Bishop {
+			continue
+		}
+		if piece.Color() == color {
+			hasBishop[isDarkSquare(sq)] = true
+		} else {
+			enemyBishop[isDarkSquare(sq)] = true
+		}
+	}
+
+	// Holes are squares in front of the king that no friendly pawn guards
+	// or occupies. The back rank is skipped since pawns never guard it.
+	king := analysis.KingSquare(board, color)
+	holes := map[bool][]string{}
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if king == chess.NoSquare || squareDistance(sq, king) > 2 || analysis.RelativeRank(sq, color) == 0 {
+			continue
+		}
+		if p := board.Piece(sq); p.Type() == chess.Pawn && p.Color() == color {
+			continue
+		}
+		if !analysis.PawnAttacks(board, sq, color) {
+			holes[isDarkSquare(sq)] = append(holes[isDarkSquare(sq)], sq.String())
+		}
+	}
+
+	weakness := ColorWeakness{WeakComplex: "none", WeakSquares: []string{}}
+	for _, dark := range []bool{true, false} {
+		if hasBishop[dark] || len(holes[dark]) == 0 {
+			continue
+		}
+		if len(weakness.WeakSquares) >= len(holes[dark]) {
+			continue
+		}
+		weakness.WeakComplex = complexName(dark)
+		weakness.WeakSquares = holes[dark]
+		weakness.Reason = fmt.Sprintf("%s's %s-squared bishop is gone", color.Name(), complexName(dark))
+		if enemyBishop[dark] {
+			weakness.Reason += " while the opponent still has one"
+		}
+	}
+	return weakness
+}
+
+func getColorWeakness(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	board := pos.Board()
+	sendJSON(ws, map[string]ColorWeakness{
+		"white": evaluateColorWeakness(board, chess.White),
+		"black": evaluateColorWeakness(board, chess.Black),
+	})
+	logger.Debug("Colour complex weakness sent", slog.String("gameID", gameID))
+}
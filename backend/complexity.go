@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+const (
+	// complexityMoveLimit is how many half-moves into a game complexity
+	// is reported in broadcasts.
+	complexityMoveLimit = 80
+	// criticalComplexityJump is the change in complexity between two
+	// broadcasts that marks a critical moment.
+	criticalComplexityJump = 20
+)
+
+type ComplexityScore struct {
+	Score             int `json:"complexity"`
+	LegalMoves        int `json:"legalMoves"`
+	Captures          int `json:"captures"`
+	PiecesUnderAttack int `json:"piecesUnderAttack"`
+	Tension           int `json:"tension"`
+}
+
+// scaled maps n onto 0..weight, saturating at limit.
+func scaled(n, limit, weight int) int {
+	if n > limit {
+		n = limit
+	}
+	return n * weight / limit
+}
+
+// scorePositionComplexity rates from 0 to 100 how hard it is to choose a move,
+// combining the number of legal moves and captures with how many pieces are
+// attacked and how many of those could be exchanged.
+func scorePositionComplexity(pos *chess.Position) ComplexityScore {
+	var score ComplexityScore
+	moves := pos.ValidMoves()
+	score.LegalMoves = len(moves)
+	for _, m := range moves {
+		if m.HasTag(chess.Capture) {
+			score.Captures++
+		}
+	}
+
+	board := pos.Board()
+	attacks := map[chess.Color][64]int{
+		chess.White: analysis.AttackCounts(board, chess.White),
+		chess.Black: analysis.AttackCounts(board, chess.Black),
+	}
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() == chess.King || attacks[piece.Color().Other()][sq] == 0 {
+			continue
+		}
+		score.PiecesUnderAttack++
+		if attacks[piece.Color()][sq] > 0 {
+			score.Tension++
+		}
+	}
+
+	score.Score = scaled(score.LegalMoves, 60, 30) +
+		scaled(score.Captures, 10, 25) +
+		scaled(score.PiecesUnderAttack, 12, 25) +
+		scaled(score.Tension, 8, 20)
+	return score
+}
+
+func getPositionComplexity(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, scorePositionComplexity(pos))
+	logger.Debug("Position complexity sent", slog.String("gameID", gameID))
+}
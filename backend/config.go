@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every setting the server reads from the environment.
+type Config struct {
+	// Port is where the server listens: PORT, or 8080 (443 with TLS).
+	Port string
+
+	// LogLevel and LogFormat ("text" or "json") set up logger.
+	LogLevel  slog.Level
+	LogFormat string
+
+	// StoreBackend is "memory" or "redis", which connects to RedisAddr.
+	StoreBackend string
+	RedisAddr    string
+
+	// SnapshotPath is where games are saved on shutdown.
+	SnapshotPath string
+
+	// TLSCertFile and TLSKeyFile turn on HTTPS together, with plain HTTP
+	// on HTTPRedirectPort redirecting to it. ACMEDomain turns it on
+	// instead with certificates from Let's Encrypt, kept in ACMECacheDir.
+	TLSCertFile      string
+	TLSKeyFile       string
+	ACMEDomain       string
+	ACMECacheDir     string
+	HTTPRedirectPort string
+
+	// AllowedOrigins restricts which browser origins may open a
+	// WebSocket. Empty allows all.
+	AllowedOrigins []string
+
+	// MoveRateLimit caps the move attempts per second of the player to
+	// move in each game, and ConnectionRateLimit messages per second on
+	// each connection.
+	MoveRateLimit       int
+	ConnectionRateLimit int
+
+	// MaxConnectionsPerIP caps the open WebSockets from one address,
+	// except for TrustedIPs. TrustForwardedFor takes the address from
+	// the last X-Forwarded-For entry, for servers behind a proxy that
+	// appends it.
+	MaxConnectionsPerIP int
+	TrustedIPs          []string
+	TrustForwardedFor   bool
+
+	// MaxGamesPerPlayer caps the unfinished games one connection may
+	// play at once, from 1 to 5.
+	MaxGamesPerPlayer int
+
+	// ReconnectGrace is how long a disconnected player's seat is held,
+	// and TakebackTimeout and AbortTimeout how long takeback and abort
+	// requests wait for an answer.
+	ReconnectGrace  time.Duration
+	TakebackTimeout time.Duration
+	AbortTimeout    time.Duration
+
+	// CorrespondenceMoveTime is the time each player has per move once a
+	// live game is converted to correspondence, set in whole days.
+	CorrespondenceMoveTime time.Duration
+
+	// ReplayBufferSize is how many of the latest messages to each seat
+	// are kept to replay to its player on reconnecting.
+	ReplayBufferSize int
+
+	// ReadyTimeout is how long joined players have to both say they are
+	// ready before the second seat is reopened.
+	ReadyTimeout time.Duration
+
+	// TargetLatency is the one-way latency delayed games normalise to.
+	TargetLatency time.Duration
+
+	// SpectatorLimit caps how many spectators may watch one game.
+	SpectatorLimit int
+
+	// GameEndWebhookURL is posted every finished game, if set.
+	GameEndWebhookURL string
+
+	// AdminToken and CoachToken grant admin and coaching actions, which
+	// are disabled while they are empty.
+	AdminToken string
+	CoachToken string
+
+	// JWTSecret signs player tokens, issued to clients that know
+	// AuthSharedSecret. RequireAuth restricts game creation to
+	// authenticated players.
+	JWTSecret        []byte
+	AuthSharedSecret string
+	RequireAuth      bool
+
+	// GuestTTL is how long a guest's ID reclaims their seats, and how
+	// long after creation games with a guest in them are deleted.
+	GuestTTL time.Duration
+
+	// ShareBaseURL is the public origin of shared position links, and
+	// FrontendURL where they redirect browsers.
+	ShareBaseURL string
+	FrontendURL  string
+}
+
+// config is the server's configuration. It holds the defaults until main
+// loads it from the environment.
+var config = defaultConfig()
+
+func defaultConfig() *Config {
+	return &Config{
+		Port:                   "8080",
+		LogLevel:               slog.LevelInfo,
+		LogFormat:              "text",
+		StoreBackend:           "memory",
+		RedisAddr:              "localhost:6379",
+		SnapshotPath:           "games.json",
+		ACMECacheDir:           "acme-cache",
+		HTTPRedirectPort:       "80",
+		MoveRateLimit:          10,
+		ConnectionRateLimit:    5,
+		MaxConnectionsPerIP:    10,
+		MaxGamesPerPlayer:      1,
+		ReconnectGrace:         60 * time.Second,
+		TakebackTimeout:        30 * time.Second,
+		AbortTimeout:           30 * time.Second,
+		GuestTTL:               24 * time.Hour,
+		ReplayBufferSize:       50,
+		CorrespondenceMoveTime: 3 * 24 * time.Hour,
+		ReadyTimeout:           60 * time.Second,
+		TargetLatency:          100 * time.Millisecond,
+		SpectatorLimit:         50,
+		ShareBaseURL:           "http://localhost:8080",
+		FrontendURL:            "http://localhost:5173",
+	}
+}
+
+// LoadConfig reads the configuration from the environment over the
+// defaults. Every invalid setting is reported, not just the first.
+func LoadConfig() (*Config, error) {
+	cfg := defaultConfig()
+	var errs []error
+	invalid := func(name, value, reason string) {
+		errs = append(errs, fmt.Errorf("%s=%q: %s", name, value, reason))
+	}
+	str := func(name string, dst *string) {
+		if value := os.Getenv(name); value != "" {
+			*dst = value
+		}
+	}
+	integer := func(name string, dst *int, least int) {
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			invalid(name, value, "not a whole number")
+		} else if n < least {
+			invalid(name, value, fmt.Sprintf("must be at least %d", least))
+		} else {
+			*dst = n
+		}
+	}
+	duration := func(name string, dst *time.Duration, unit time.Duration, least int) {
+		n := int(*dst / unit)
+		integer(name, &n, least)
+		*dst = time.Duration(n) * unit
+	}
+	boolean := func(name string, dst *bool) {
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			invalid(name, value, "must be true or false")
+			return
+		}
+		*dst = b
+	}
+	port := func(name string, dst *string) {
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+			invalid(name, value, "not a port number")
+			return
+		}
+		*dst = value
+	}
+	absoluteURL := func(name string, dst *string) {
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid(name, value, "not an http or https URL")
+			return
+		}
+		*dst = strings.TrimSuffix(value, "/")
+	}
+
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := cfg.LogLevel.UnmarshalText([]byte(value)); err != nil {
+			invalid("LOG_LEVEL", value, "must be debug, info, warn or error")
+		}
+	}
+	str("LOG_FORMAT", &cfg.LogFormat)
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		invalid("LOG_FORMAT", cfg.LogFormat, "must be text or json")
+	}
+
+	str("STORE_BACKEND", &cfg.StoreBackend)
+	if cfg.StoreBackend != "memory" && cfg.StoreBackend != "redis" {
+		invalid("STORE_BACKEND", cfg.StoreBackend, "must be memory or redis")
+	}
+	str("REDIS_ADDR", &cfg.RedisAddr)
+	str("SNAPSHOT_PATH", &cfg.SnapshotPath)
+
+	str("TLS_CERT_FILE", &cfg.TLSCertFile)
+	str("TLS_KEY_FILE", &cfg.TLSKeyFile)
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	str("ACME_DOMAIN", &cfg.ACMEDomain)
+	str("ACME_CACHE_DIR", &cfg.ACMECacheDir)
+	if cfg.ACMEDomain != "" && cfg.TLSCertFile != "" {
+		errs = append(errs, errors.New("ACME_DOMAIN cannot be set with TLS_CERT_FILE and TLS_KEY_FILE"))
+	}
+	if cfg.useTLS() {
+		cfg.Port = "443"
+	}
+	port("PORT", &cfg.Port)
+	port("HTTP_REDIRECT_PORT", &cfg.HTTPRedirectPort)
+	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
+		for _, origin := range strings.Split(origins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+			}
+		}
+	}
+
+	integer("MOVE_RATE_LIMIT", &cfg.MoveRateLimit, 1)
+	integer("CONNECTION_RATE_LIMIT", &cfg.ConnectionRateLimit, 1)
+	integer("MAX_CONNECTIONS_PER_IP", &cfg.MaxConnectionsPerIP, 1)
+	if ips := os.Getenv("TRUSTED_IPS"); ips != "" {
+		for _, ip := range strings.Split(ips, ",") {
+			parsed := net.ParseIP(strings.TrimSpace(ip))
+			if parsed == nil {
+				invalid("TRUSTED_IPS", ip, "not an IP address")
+				continue
+			}
+			cfg.TrustedIPs = append(cfg.TrustedIPs, parsed.String())
+		}
+	}
+	boolean("TRUST_FORWARDED_FOR", &cfg.TrustForwardedFor)
+	integer("MAX_GAMES_PER_PLAYER", &cfg.MaxGamesPerPlayer, 1)
+	if cfg.MaxGamesPerPlayer > 5 {
+		invalid("MAX_GAMES_PER_PLAYER", strconv.Itoa(cfg.MaxGamesPerPlayer), "must be at most 5")
+	}
+	duration("RECONNECT_GRACE_SECONDS", &cfg.ReconnectGrace, time.Second, 1)
+	duration("TAKEBACK_TIMEOUT_SECONDS", &cfg.TakebackTimeout, time.Second, 1)
+	duration("ABORT_TIMEOUT_SECONDS", &cfg.AbortTimeout, time.Second, 1)
+	integer("REPLAY_BUFFER_SIZE", &cfg.ReplayBufferSize, 0)
+	duration("CORRESPONDENCE_DAYS_PER_MOVE", &cfg.CorrespondenceMoveTime, 24*time.Hour, 1)
+	duration("READY_TIMEOUT_SECONDS", &cfg.ReadyTimeout, time.Second, 1)
+	duration("TARGET_LATENCY_MS", &cfg.TargetLatency, time.Millisecond, 0)
+	integer("SPECTATOR_LIMIT", &cfg.SpectatorLimit, 0)
+
+	absoluteURL("GAME_END_WEBHOOK_URL", &cfg.GameEndWebhookURL)
+	str("ADMIN_TOKEN", &cfg.AdminToken)
+	str("COACH_TOKEN", &cfg.CoachToken)
+
+	cfg.JWTSecret = []byte(os.Getenv("JWT_SECRET"))
+	str("AUTH_SHARED_SECRET", &cfg.AuthSharedSecret)
+	boolean("REQUIRE_AUTH", &cfg.RequireAuth)
+	duration("GUEST_TTL_HOURS", &cfg.GuestTTL, time.Hour, 1)
+	if cfg.RequireAuth && len(cfg.JWTSecret) == 0 {
+		errs = append(errs, errors.New("REQUIRE_AUTH needs JWT_SECRET to be set"))
+	}
+
+	absoluteURL("SHARE_BASE_URL", &cfg.ShareBaseURL)
+	absoluteURL("FRONTEND_URL", &cfg.FrontendURL)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// useTLS reports whether the server should serve HTTPS.
+func (c *Config) useTLS() bool {
+	return c.ACMEDomain != "" || (c.TLSCertFile != "" && c.TLSKeyFile != "")
+}
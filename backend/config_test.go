@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	for name, value := range map[string]string{
+		"PORT":                     "9000",
+		"LOG_LEVEL":                "debug",
+		"LOG_FORMAT":               "json",
+		"STORE_BACKEND":            "redis",
+		"REDIS_ADDR":               "redis:6379",
+		"ALLOWED_ORIGINS":          "https://a.example, https://b.example",
+		"MOVE_RATE_LIMIT":          "3",
+		"TRUSTED_IPS":              "10.0.0.1",
+		"MAX_GAMES_PER_PLAYER":     "4",
+		"TAKEBACK_TIMEOUT_SECONDS": "45",
+		"TARGET_LATENCY_MS":        "0",
+		"GAME_END_WEBHOOK_URL":     "https://hooks.example/chess/",
+		"JWT_SECRET":               "secret",
+		"REQUIRE_AUTH":             "true",
+	} {
+		t.Setenv(name, value)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"Port", cfg.Port, "9000"},
+		{"LogLevel", cfg.LogLevel, slog.LevelDebug},
+		{"LogFormat", cfg.LogFormat, "json"},
+		{"StoreBackend", cfg.StoreBackend, "redis"},
+		{"RedisAddr", cfg.RedisAddr, "redis:6379"},
+		{"AllowedOrigins", cfg.AllowedOrigins, []string{"https://a.example", "https://b.example"}},
+		{"MoveRateLimit", cfg.MoveRateLimit, 3},
+		{"TrustedIPs", cfg.TrustedIPs, []string{"10.0.0.1"}},
+		{"MaxGamesPerPlayer", cfg.MaxGamesPerPlayer, 4},
+		{"TakebackTimeout", cfg.TakebackTimeout, 45 * time.Second},
+		{"TargetLatency", cfg.TargetLatency, time.Duration(0)},
+		{"GameEndWebhookURL", cfg.GameEndWebhookURL, "https://hooks.example/chess"},
+		{"JWTSecret", string(cfg.JWTSecret), "secret"},
+		{"RequireAuth", cfg.RequireAuth, true},
+		{"ReadyTimeout", cfg.ReadyTimeout, defaultConfig().ReadyTimeout},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidValues(t *testing.T) {
+	for _, c := range []struct{ name, value string }{
+		{"PORT", "70000"},
+		{"LOG_LEVEL", "loud"},
+		{"STORE_BACKEND", "disk"},
+		{"MOVE_RATE_LIMIT", "0"},
+		{"MAX_GAMES_PER_PLAYER", "6"},
+		{"TAKEBACK_TIMEOUT_SECONDS", "-5"},
+		{"READY_TIMEOUT_SECONDS", "soon"},
+		{"TRUSTED_IPS", "not-an-ip"},
+		{"REQUIRE_AUTH", "maybe"},
+		{"GAME_END_WEBHOOK_URL", "ftp://hooks.example"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(c.name, c.value)
+			cfg, err := LoadConfig()
+			if err == nil {
+				t.Fatalf("LoadConfig with %s=%q returned %+v", c.name, c.value, cfg)
+			}
+			if !strings.Contains(err.Error(), c.name) {
+				t.Errorf("error %q does not name %s", err, c.name)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsEveryError(t *testing.T) {
+	t.Setenv("PORT", "http")
+	t.Setenv("SPECTATOR_LIMIT", "-1")
+	t.Setenv("REQUIRE_AUTH", "true")
+	_, err := LoadConfig()
+	if err == nil {
+		t.Fatal("LoadConfig returned no error")
+	}
+	for _, want := range []string{"PORT", "SPECTATOR_LIMIT", "REQUIRE_AUTH needs JWT_SECRET"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// previewMove validates moveStr without playing it and shows the resulting
+// position to the moving player only. The caller must hold gamesMutex.
+func previewMove(ws *websocket.Conn, game *Game, player *Player, moveStr string) {
+	pos := game.Game.Position()
+	move, err := chess.AlgebraicNotation{}.Decode(pos, moveStr)
+	if err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+	player.PendingMove = moveStr
+
+	var legalMoves []string
+	for _, m := range pos.ValidMoves() {
+		legalMoves = append(legalMoves, chess.AlgebraicNotation{}.Encode(pos, m))
+	}
+	sendJSON(ws, map[string]interface{}{
+		"type":       "move_preview",
+		"move":       moveStr,
+		"fen_after":  pos.Update(move).String(),
+		"legalMoves": legalMoves,
+	})
+}
+
+func confirmMove(ws *websocket.Conn, gameID, moveStr string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	if player == nil || player.PendingMove == "" || player.PendingMove != moveStr {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "no matching move to confirm"})
+		return
+	}
+	player.PendingMove = ""
+	gamesMutex.Unlock()
+
+	makeMove(ws, gameID, moveStr, true)
+}
+
+func cancelMove(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	if player := getPlayer(ws, game); player != nil {
+		player.PendingMove = ""
+	}
+	sendJSON(ws, map[string]string{"type": "move_cancelled"})
+	logger.Info("Pending move cancelled", slog.String("gameID", gameID))
+}
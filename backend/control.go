@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// controlInterval is the number of full moves between board control
+// snapshots.
+const controlInterval = 5
+
+// boardControl counts the squares each side attacks more often than the
+// other side does.
+func boardControl(board *chess.Board) (white, black int) {
+	whiteAttacks := analysis.AttackCounts(board, chess.White)
+	blackAttacks := analysis.AttackCounts(board, chess.Black)
+	for sq := 0; sq < 64; sq++ {
+		if whiteAttacks[sq] > blackAttacks[sq] {
+			white++
+		} else if blackAttacks[sq] > whiteAttacks[sq] {
+			black++
+		}
+	}
+	return white, black
+}
+
+// spaceScores counts, for each side, the squares in the opponent's half of
+// the board that it attacks more often than the opponent does.
+func spaceScores(board *chess.Board) (white, black int) {
+	whiteAttacks := analysis.AttackCounts(board, chess.White)
+	blackAttacks := analysis.AttackCounts(board, chess.Black)
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if sq.Rank() >= chess.Rank5 && whiteAttacks[sq] > blackAttacks[sq] {
+			white++
+		}
+		if sq.Rank() <= chess.Rank4 && blackAttacks[sq] > whiteAttacks[sq] {
+			black++
+		}
+	}
+	return white, black
+}
+
+func getSpaceAdvantage(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	white, black := spaceScores(pos.Board())
+	side := "equal"
+	if white > black {
+		side = "white"
+	} else if black > white {
+		side = "black"
+	}
+	sendJSON(ws, map[string]interface{}{
+		"whiteSpaceScore": white,
+		"blackSpaceScore": black,
+		"advantageSide":   side,
+	})
+	logger.Debug("Space advantage sent", slog.String("gameID", gameID))
+}
+
+func getBoardControlOverTime(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to query non-existent game", slog.String("gameID", gameID))
+		return
+	}
+	positions := game.Game.Positions()
+	gamesMutex.Unlock()
+
+	moves := []int{}
+	whiteControl := []int{}
+	blackControl := []int{}
+	for move := controlInterval; 2*move < len(positions); move += controlInterval {
+		white, black := boardControl(positions[2*move].Board())
+		moves = append(moves, move)
+		whiteControl = append(whiteControl, white)
+		blackControl = append(blackControl, black)
+	}
+
+	sendJSON(ws, map[string]interface{}{
+		"moves":        moves,
+		"whiteControl": whiteControl,
+		"blackControl": blackControl,
+	})
+	logger.Debug("Board control history sent", slog.String("gameID", gameID))
+}
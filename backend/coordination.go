@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type Coordination struct {
+	Score            int    `json:"score"`
+	BestCoordination string `json:"bestCoordination,omitempty"`
+	WorstPiece       string `json:"worstPiece,omitempty"`
+}
+
+// Battery is two sliding pieces lined up on the same line, Back supporting
+// Front, pointing at Target.
+type Battery struct {
+	Front  chess.Square
+	Back   chess.Square
+	Target chess.Square
+}
+
+// slidesAlong reports whether a piece of type t moves along direction d.
+func slidesAlong(t chess.PieceType, d [2]int) bool {
+	diagonal := d[0] != 0 && d[1] != 0
+	switch t {
+	case chess.Queen:
+		return true
+	case chess.Bishop:
+		return diagonal
+	case chess.Rook:
+		return !diagonal
+	}
+	return false
+}
+
+// findBatteries returns every battery of color's sliding pieces. Target is
+// the first enemy piece beyond the front piece, or the last square of the
+// line when nothing stands there.
+func findBatteries(board *chess.Board, color chess.Color) []Battery {
+	var batteries []Battery
+	directions := append(append([][2]int{}, analysis.RookDirections...), analysis.BishopDirections...)
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		back := board.Piece(sq)
+		if back.Color() != color {
+			continue
+		}
+		for _, d := range directions {
+			if !slidesAlong(back.Type(), d) {
+				continue
+			}
+			front := chess.NoSquare
+			target := chess.NoSquare
+			for next, ok := analysis.OffsetSquare(sq, d[0], d[1]); ok; next, ok = analysis.OffsetSquare(next, d[0], d[1]) {
+				p := board.Piece(next)
+				if front == chess.NoSquare {
+					if p == chess.NoPiece {
+						continue
+					}
+					if p.Color() != color || !slidesAlong(p.Type(), d) {
+						break
+					}
+					front = next
+					continue
+				}
+				target = next
+				if p != chess.NoPiece {
+					break
+				}
+			}
+			if front != chess.NoSquare && target != chess.NoSquare {
+				batteries = append(batteries, Battery{Front: front, Back: sq, Target: target})
+			}
+		}
+	}
+	return batteries
+}
+
+func pieceLabel(board *chess.Board, sq chess.Square) string {
+	p := board.Piece(sq)
+	if p.Type() == chess.Pawn {
+		return sq.String()
+	}
+	return strings.ToUpper(p.Type().String()) + sq.String()
+}
+
+// evaluateCoordination scores from 0 to 100 how well color's pieces work
+// together: defended pieces, connected rooks, pawn-supported knights and
+// batteries all count.
+func evaluateCoordination(board *chess.Board, color chess.Color) Coordination {
+	var coordination Coordination
+	own := analysis.AttackCounts(board, color)
+
+	pieces, defended, supportedKnights := 0, 0, 0
+	var rooks []chess.Square
+	worst, worstMobility := chess.NoSquare, 100
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		piece := board.Piece(sq)
+		if piece.Color() != color || piece.Type() == chess.King || piece.Type() == chess.Pawn {
+			continue
+		}
+		pieces++
+		if own[sq] > 0 {
+			defended++
+		}
+		if piece.Type() == chess.Knight && analysis.PawnAttacks(board, sq, color) {
+			supportedKnights++
+		}
+		if piece.Type() == chess.Rook {
+			rooks = append(rooks, sq)
+		}
+		if mobility := len(analysis.AttackedSquares(board, sq, piece)); mobility < worstMobility {
+			worst, worstMobility = sq, mobility
+		}
+	}
+	if pieces == 0 {
+		return coordination
+	}
+
+	score := 40 * defended / pieces
+	if len(rooks) == 2 && rooksConnected(board, rooks[0], rooks[1]) {
+		score += 15
+	}
+	if supportedKnights > 2 {
+		supportedKnights = 2
+	}
+	score += 10 * supportedKnights
+
+	batteries := findBatteries(board, color)
+	if len(batteries) > 0 {
+		score += 15
+		b := batteries[0]
+		for _, candidate := range batteries {
+			if board.Piece(candidate.Target) != chess.NoPiece {
+				b = candidate
+				break
+			}
+		}
+		coordination.BestCoordination = fmt.Sprintf("%s and %s battery targeting %s",
+			pieceLabel(board, b.Front), pieceLabel(board, b.Back), b.Target)
+	}
+	if len(batteries) > 1 {
+		score += 10
+	}
+	if score > 100 {
+		score = 100
+	}
+	coordination.Score = score
+	if worstMobility <= 2 {
+		coordination.WorstPiece = pieceLabel(board, worst) + " is passive"
+	}
+	return coordination
+}
+
+func getPieceCoordination(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	board := pos.Board()
+	sendJSON(ws, map[string]Coordination{
+		"white": evaluateCoordination(board, chess.White),
+		"black": evaluateCoordination(board, chess.Black),
+	})
+	logger.Debug("Piece coordination sent", slog.String("gameID", gameID))
+}
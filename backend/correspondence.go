@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// convertToCorrespondence records a player's vote to turn a casual timed
+// game into a correspondence one, and converts it once both players have
+// voted: each side then has config.CorrespondenceMoveTime for every move,
+// starting with the one in progress. TimeControl is left as it was, so a
+// rematch is played at the original time control.
+func convertToCorrespondence(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	game.Lock()
+	player := getPlayer(ws, game)
+	var problem string
+	switch {
+	case player == nil:
+		problem = "only players can convert a game"
+	case game.Ranked:
+		problem = "only casual games can be converted"
+	case game.Clock == nil:
+		problem = "game has no clock"
+	case game.Clock.ClockMode == "correspondence":
+		problem = "game is already correspondence"
+	case game.Game.Outcome() != chess.NoOutcome:
+		problem = "game already over"
+	}
+	if problem != "" {
+		game.Unlock()
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": problem})
+		return
+	}
+
+	if game.CorrespondenceConversionVote == nil {
+		game.CorrespondenceConversionVote = make(map[chess.Color]bool)
+	}
+	game.CorrespondenceConversionVote[player.Color] = true
+	if !game.CorrespondenceConversionVote[player.Color.Other()] {
+		if other := opponent(game, player.Color); other != nil {
+			sendToPlayer(other, map[string]string{"status": "correspondenceOffer", "from": colorName(player.Color)})
+		}
+		game.Unlock()
+		gamesMutex.Unlock()
+		logger.Info("Correspondence conversion offered", slog.String("gameID", gameID))
+		return
+	}
+
+	perMove := config.CorrespondenceMoveTime
+	clock := game.Clock
+	clock.ClockMode = "correspondence"
+	clock.Increment = perMove
+	clock.Remaining = [2]time.Duration{perMove, perMove}
+	clock.TurnStarted = time.Now()
+	if clock.running() {
+		armFlag(gameID, game)
+	}
+	game.CorrespondenceConversionVote = nil
+	converted := map[string]interface{}{"type": "converted_to_correspondence", "gameID": gameID, "daysPerMove": int(perMove / (24 * time.Hour))}
+	for _, p := range game.Players {
+		sendToPlayer(p, converted)
+	}
+	for _, conn := range append(append([]*websocket.Conn(nil), game.Coaches...), game.Spectators...) {
+		sendJSON(conn, converted)
+	}
+	game.Unlock()
+	gamesMutex.Unlock()
+
+	logger.Info("Game converted to correspondence", slog.String("gameID", gameID))
+	broadcastGameState(gameID)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestConvertToCorrespondence(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create", "timeControl": "1+0"})
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID := created["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		readUntil(t, ws, isState)
+	}
+	white, black := creator, joiner
+	if created["color"] != "w" {
+		white, black = joiner, creator
+	}
+	play(t, white, black, gameID, "e4")
+
+	convert := map[string]string{"action": "convert_to_correspondence", "gameID": gameID}
+	send(t, white, convert)
+	if offer := readUntil(t, black, hasStatus("correspondenceOffer")); offer["from"] != "white" {
+		t.Errorf("offer = %v, want one from white", offer)
+	}
+	send(t, black, convert)
+	for _, ws := range []*websocket.Conn{white, black} {
+		msg := readUntil(t, ws, func(msg map[string]interface{}) bool { return msg["type"] == "converted_to_correspondence" })
+		if msg["daysPerMove"] != float64(3) {
+			t.Errorf("converted with %v days per move, want 3", msg["daysPerMove"])
+		}
+	}
+	perMove := float64((3 * 24 * time.Hour).Milliseconds())
+	state := readUntil(t, white, isState)
+	if state["clockMode"] != "correspondence" || state["whiteTime"] != perMove {
+		t.Errorf("state after converting: clock mode %v, white time %v; want correspondence, %v", state["clockMode"], state["whiteTime"], perMove)
+	}
+	if black := state["blackTime"].(float64); black > perMove || black < perMove-float64(time.Second.Milliseconds()) {
+		t.Errorf("black, to move, has %vms, want about %v", black, perMove)
+	}
+
+	// The time used on a move is given back in full.
+	send(t, black, map[string]string{"action": "move", "gameID": gameID, "move": "e5"})
+	state = readUntil(t, white, func(msg map[string]interface{}) bool { return isState(msg) && msg["moveCount"] == float64(2) })
+	if state["blackTime"] != perMove {
+		t.Errorf("black has %v after moving, want %v", state["blackTime"], perMove)
+	}
+
+	send(t, white, convert)
+	if msg := readUntil(t, white, hasError); msg["error"] != "game is already correspondence" {
+		t.Errorf("converting twice: %v", msg["error"])
+	}
+}
+
+func TestConvertUntimedGame(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+	send(t, white, map[string]string{"action": "convert_to_correspondence", "gameID": gameID})
+	if msg := readUntil(t, white, hasError); msg["error"] != "game has no clock" {
+		t.Errorf("converting an untimed game: %v", msg["error"])
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// stateDelta returns the keys of state that differ from prev, ignoring
+// prev's sequence number. Keys that were dropped are sent as null. The move
+// list is sent as just its new tail, with movesFrom giving the index of
+// its first move, or whole from 0 after a takeback.
+func stateDelta(prev, state map[string]interface{}) map[string]interface{} {
+	delta := make(map[string]interface{})
+	for key, value := range state {
+		if old, ok := prev[key]; !ok || !reflect.DeepEqual(old, value) {
+			delta[key] = value
+		}
+	}
+	for key := range prev {
+		if _, ok := state[key]; !ok && key != "seq" {
+			delta[key] = nil
+		}
+	}
+	if moves, ok := delta["moves"].([]string); ok {
+		oldMoves, _ := prev["moves"].([]string)
+		from := 0
+		if len(oldMoves) <= len(moves) && reflect.DeepEqual(oldMoves, moves[:len(oldMoves)]) {
+			from = len(oldMoves)
+		}
+		delta["moves"] = moves[from:]
+		delta["movesFrom"] = from
+	}
+	return delta
+}
+
+// recordState numbers state if it differs from the last one broadcast for
+// game, returning what changed. A repeat of the last state keeps its
+// number and gives an empty delta. The caller must hold game's lock.
+func recordState(game *Game, state map[string]interface{}) map[string]interface{} {
+	delta := stateDelta(game.lastState, state)
+	if len(delta) > 0 || game.lastState == nil {
+		game.StateSeq++
+		game.lastState = state
+	}
+	state["seq"] = game.StateSeq
+	return delta
+}
+
+// sendState sends ws, player's connection if it is a player's, the game
+// state numbered game.StateSeq. Connections
+// that asked for deltas with syncState and already have the previous state
+// get only what changed, or nothing if it has not; everyone else gets
+// the whole state. A delta connection's seq only moves on once its state
+// is queued, so one that had a state dropped gets the whole of the next.
+// The caller must hold game's lock.
+func sendState(ws *websocket.Conn, player *Player, game *Game, state, delta map[string]interface{}) bool {
+	seen, wantsDelta := game.deltaClients[ws]
+	if !wantsDelta {
+		return sendTo(ws, player, stateFor(player, state))
+	}
+	var sent bool
+	switch {
+	case seen == game.StateSeq:
+		return true
+	case seen != 0 && seen == game.StateSeq-1 && len(delta) > 0:
+		// delta leads from seen only if this broadcast moved the seq on.
+		sent = sendTo(ws, player, map[string]interface{}{"seq": game.StateSeq, "delta": delta})
+	default:
+		sent = sendTo(ws, player, stateFor(player, state))
+	}
+	if sent {
+		game.deltaClients[ws] = game.StateSeq
+	}
+	return sent
+}
+
+// syncState switches ws to delta broadcasts for a game it takes part in.
+// If sinceSeq is the current state it is told nothing changed; otherwise
+// it gets the whole state to apply later deltas to.
+func syncState(ws *websocket.Conn, gameID, sinceSeq string) {
+	gamesMutex.RLock()
+	defer gamesMutex.RUnlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	game.Lock()
+	defer game.Unlock()
+
+	player := getPlayer(ws, game)
+	if player == nil && !isGameCoach(ws, game) && !isSpectator(ws, game) {
+		sendJSON(ws, map[string]string{"error": "not in this game"})
+		return
+	}
+	if game.lastState == nil {
+		sendJSON(ws, map[string]string{"error": "game has not started"})
+		return
+	}
+	if game.deltaClients == nil {
+		game.deltaClients = make(map[*websocket.Conn]uint64)
+	}
+
+	// Until a state reaches ws, its seq of 0 gets it the whole of the next.
+	var sent bool
+	if seq, err := strconv.ParseUint(sinceSeq, 10, 64); err == nil && seq == game.StateSeq {
+		sent = sendTo(ws, player, map[string]interface{}{"seq": game.StateSeq, "unchanged": true})
+	} else {
+		sent = sendTo(ws, player, stateFor(player, game.lastState))
+	}
+	if sent {
+		game.deltaClients[ws] = game.StateSeq
+	} else {
+		game.deltaClients[ws] = 0
+	}
+	logger.Debug("State synced", slog.String("gameID", gameID), slog.Uint64("seq", game.StateSeq))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// hasSeq matches whole states and deltas alike.
+func hasSeq(msg map[string]interface{}) bool {
+	_, ok := msg["seq"]
+	return ok
+}
+
+func TestDeltaAfterSync(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4")
+
+	send(t, white, map[string]string{"action": "syncState", "gameID": gameID})
+	readUntil(t, white, isState)
+	send(t, black, map[string]string{"action": "move", "gameID": gameID, "move": "e5"})
+	delta := readUntil(t, white, hasSeq)
+	changes, ok := delta["delta"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %v after syncing, want a delta", delta)
+	}
+	if moves, _ := changes["moves"].([]interface{}); len(moves) != 1 || moves[0] != "e5" || changes["movesFrom"] != float64(1) {
+		t.Errorf("delta moves %v from %v, want [e5] from 1", changes["moves"], changes["movesFrom"])
+	}
+}
+
+// TestDroppedStateSendsWholeStateNext drops a state on its way to a delta
+// client. The client must keep its old seq, so that the next broadcast
+// sends it the whole state even though that broadcast changes nothing,
+// rather than a delta against the state it never got.
+func TestDroppedStateSendsWholeStateNext(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4")
+	send(t, white, map[string]string{"action": "syncState", "gameID": gameID})
+	readUntil(t, white, isState)
+
+	gamesMutex.RLock()
+	game := games[gameID]
+	game.Lock()
+	conn := opponent(game, chess.Black).Conn
+	seen := game.deltaClients[conn]
+	game.Unlock()
+	gamesMutex.RUnlock()
+
+	// A queue that nothing reads is always full, so every message to
+	// white is dropped.
+	writersMutex.Lock()
+	queue := writers[conn]
+	writers[conn] = &writeQueues{control: make(chan outgoing), normal: make(chan outgoing)}
+	writersMutex.Unlock()
+	send(t, black, map[string]string{"action": "move", "gameID": gameID, "move": "e5"})
+	readUntil(t, black, func(msg map[string]interface{}) bool { return isState(msg) && msg["moveCount"] == float64(2) })
+	game.Lock()
+	if got := game.deltaClients[conn]; got != seen {
+		t.Errorf("seq after a dropped state = %d, want %d", got, seen)
+	}
+	game.Unlock()
+
+	writersMutex.Lock()
+	writers[conn] = queue
+	writersMutex.Unlock()
+	broadcastGameState(gameID)
+	state := readUntil(t, white, hasSeq)
+	if !isState(state) || state["moveCount"] != float64(2) {
+		t.Fatalf("got %v after a dropped state, want the whole state", state)
+	}
+
+	// Having caught up, white gets deltas again.
+	send(t, white, map[string]string{"action": "move", "gameID": gameID, "move": "Nf3"})
+	if delta := readUntil(t, white, hasSeq); delta["delta"] == nil {
+		t.Errorf("got %v after catching up, want a delta", delta)
+	}
+}
+
+// TestSyncStateUnchanged checks that a client that already has the current
+// state is told so rather than sent it again.
+func TestSyncStateUnchanged(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	state := play(t, white, black, gameID, "e4")
+
+	send(t, white, map[string]string{"action": "syncState", "gameID": gameID, "sinceSeq": strconv.FormatFloat(state["seq"].(float64), 'f', -1, 64)})
+	if reply := readUntil(t, white, hasSeq); reply["unchanged"] != true {
+		t.Errorf("reply to a current sinceSeq = %v, want unchanged", reply)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+var errAmbiguousDescription = errors.New("ambiguous_description")
+
+var pieceWords = map[string]chess.PieceType{
+	"king":    chess.King,
+	"kings":   chess.King,
+	"queen":   chess.Queen,
+	"queens":  chess.Queen,
+	"rook":    chess.Rook,
+	"rooks":   chess.Rook,
+	"bishop":  chess.Bishop,
+	"bishops": chess.Bishop,
+	"knight":  chess.Knight,
+	"knights": chess.Knight,
+	"pawn":    chess.Pawn,
+	"pawns":   chess.Pawn,
+}
+
+var colorWords = map[string]chess.Color{
+	"white": chess.White,
+	"black": chess.Black,
+}
+
+// fenFromDescription builds a FEN from a description such as
+// "white king on e1, white rook on a1, black king on e8". Each clause names a
+// color, a piece and one or more squares; an optional "black to move" clause
+// sets the side to move. Castling and en passant rights are never granted.
+func fenFromDescription(description string) (string, error) {
+	pieces := map[chess.Square]chess.Piece{}
+	turn := chess.White
+
+	clauses := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return r == ',' || r == ';' || r == '.'
+	})
+	for _, clause := range clauses {
+		words := strings.FieldsFunc(clause, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+
+		color, pieceType := chess.NoColor, chess.NoPieceType
+		var squares []chess.Square
+		toMove := false
+		for _, word := range words {
+			if c, ok := colorWords[word]; ok {
+				if color != chess.NoColor && color != c {
+					return "", errAmbiguousDescription
+				}
+				color = c
+			} else if p, ok := pieceWords[word]; ok {
+				if pieceType != chess.NoPieceType && pieceType != p {
+					return "", errAmbiguousDescription
+				}
+				pieceType = p
+			} else if sq, ok := parseSquare(word); ok {
+				squares = append(squares, sq)
+			} else if word == "move" || word == "play" {
+				toMove = true
+			}
+		}
+
+		if toMove && pieceType == chess.NoPieceType && len(squares) == 0 {
+			if color == chess.NoColor {
+				return "", errAmbiguousDescription
+			}
+			turn = color
+			continue
+		}
+		if color == chess.NoColor || pieceType == chess.NoPieceType || len(squares) == 0 {
+			return "", errAmbiguousDescription
+		}
+		for _, sq := range squares {
+			if _, taken := pieces[sq]; taken {
+				return "", errAmbiguousDescription
+			}
+			if pieceType == chess.Pawn && (sq.Rank() == chess.Rank1 || sq.Rank() == chess.Rank8) {
+				return "", errAmbiguousDescription
+			}
+			pieces[sq] = chess.NewPiece(pieceType, color)
+		}
+	}
+
+	board := chess.NewBoard(pieces)
+	if err := checkPosition(board, turn); err != nil {
+		return "", errAmbiguousDescription
+	}
+
+	fen := board.String() + " " + turn.String() + " - - 0 1"
+	if _, err := chess.FEN(fen); err != nil {
+		return "", errAmbiguousDescription
+	}
+	return fen, nil
+}
+
+// checkPosition rejects boards that notnil/chess would accept but that
+// cannot arise in a game: each side needs exactly one king, and the side
+// that has just moved cannot be left in check.
+func checkPosition(board *chess.Board, turn chess.Color) error {
+	kings := map[chess.Color]int{}
+	for _, piece := range board.SquareMap() {
+		if piece.Type() == chess.King {
+			kings[piece.Color()]++
+		}
+	}
+	if kings[chess.White] != 1 || kings[chess.Black] != 1 {
+		return errors.New("each side needs exactly one king")
+	}
+	if analysis.AttackCounts(board, turn)[analysis.KingSquare(board, turn.Other())] > 0 {
+		return errors.New("the side not to move is in check")
+	}
+	return nil
+}
+
+func generateFENFromDescription(ws *websocket.Conn, description string) {
+	fen, err := fenFromDescription(description)
+	if err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		logger.Warn("Could not generate FEN from description", slog.String("description", description))
+		return
+	}
+	sendJSON(ws, map[string]string{"fen": fen})
+}
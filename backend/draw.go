@@ -0,0 +1,146 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// opponent returns the player facing color, or nil if the seat is empty.
+func opponent(game *Game, color chess.Color) *Player {
+	for _, player := range game.Players {
+		if player.Color != color {
+			return player
+		}
+	}
+	return nil
+}
+
+func offerDraw(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	switch {
+	case player == nil:
+		sendJSON(ws, map[string]string{"error": "only players can offer a draw"})
+		return
+	case game.Game.Outcome() != chess.NoOutcome:
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	case game.DrawOffer != chess.NoColor:
+		sendJSON(ws, map[string]string{"error": "a draw offer is already pending"})
+		return
+	case game.Game.Position().Turn() != player.Color:
+		sendJSON(ws, map[string]string{"error": "you can only offer a draw on your turn"})
+		return
+	}
+
+	game.DrawOffer = player.Color
+	if other := opponent(game, player.Color); other != nil {
+		sendToPlayer(other, map[string]string{"status": "drawOffer", "from": colorName(player.Color)})
+	}
+	logger.Info("Draw offered", slog.String("gameID", gameID))
+}
+
+func acceptDraw(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	if player == nil || game.DrawOffer != player.Color.Other() {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "no draw offer to accept"})
+		return
+	}
+	game.DrawOffer = chess.NoColor
+	err := game.Game.Draw(chess.DrawOffer)
+	if err == nil {
+		clearTakebackRequest(game)
+	}
+	gamesMutex.Unlock()
+	if err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Draw agreed", slog.String("gameID", gameID))
+	broadcastGameState(gameID)
+}
+
+// drawClaims maps the reasons a player may give in claimDraw to the
+// method notnil/chess records the draw under.
+var drawClaims = map[string]chess.Method{
+	"fiftyMoveRule":       chess.FiftyMoveRule,
+	"threefoldRepetition": chess.ThreefoldRepetition,
+}
+
+// claimDraw ends the game drawn at a player's claim under the fifty-move
+// rule or threefold repetition. The fivefold repetition and seventy-five
+// move rules need no claim; notnil/chess ends the game on them by itself.
+func claimDraw(ws *websocket.Conn, gameID, reason string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	method, known := drawClaims[reason]
+	switch {
+	case getPlayer(ws, game) == nil:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "only players can claim a draw"})
+		return
+	case !known:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "unknown draw claim"})
+		return
+	case game.Game.Outcome() != chess.NoOutcome:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	}
+	if err := game.Game.Draw(method); err != nil {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "draw condition not met"})
+		return
+	}
+	game.DrawOffer = chess.NoColor
+	clearTakebackRequest(game)
+	gamesMutex.Unlock()
+
+	logger.Info("Draw claimed", slog.String("gameID", gameID), slog.String("reason", reason))
+	broadcastGameState(gameID)
+}
+
+func declineDraw(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	if player == nil || game.DrawOffer != player.Color.Other() {
+		sendJSON(ws, map[string]string{"error": "no draw offer to decline"})
+		return
+	}
+	game.DrawOffer = chess.NoColor
+	if other := opponent(game, player.Color); other != nil {
+		sendToPlayer(other, map[string]string{"status": "drawDeclined", "from": colorName(player.Color)})
+	}
+	logger.Info("Draw declined", slog.String("gameID", gameID))
+}
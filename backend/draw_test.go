@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// knightShuffle returns the starting position to the board every four
+// half-moves.
+var knightShuffle = strings.Fields("Nf3 Nf6 Ng1 Ng8 Nf3 Nf6 Ng1 Ng8 Nf3 Nf6 Ng1 Ng8 Nf3 Nf6 Ng1 Ng8")
+
+func TestClaimDraw(t *testing.T) {
+	for _, c := range []struct {
+		name, fen, reason string
+		moves             []string
+	}{
+		// The rook move takes the half-move clock from 99 to 100.
+		{"fifty-move rule", "8/8/4k3/8/8/4K3/8/R7 w - - 99 80", "fiftyMoveRule", []string{"Ra2"}},
+		{"threefold repetition", "", "threefoldRepetition", knightShuffle[:8]},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			useConfig(t, nil)
+			srv := newTestServer(t)
+			white, black, gameID := startTestGameFrom(t, srv, c.fen)
+			claim := map[string]string{"action": "claimDraw", "gameID": gameID, "reason": c.reason}
+
+			send(t, white, claim)
+			if msg := readUntil(t, white, hasError); msg["error"] != "draw condition not met" {
+				t.Errorf("claiming too early: %v", msg["error"])
+			}
+
+			play(t, white, black, gameID, c.moves...)
+			send(t, white, claim)
+			state := readUntil(t, black, func(msg map[string]interface{}) bool {
+				return isState(msg) && msg["status"] != "ongoing"
+			})
+			if state["status"] != "draw" || state["method"] != c.reason {
+				t.Errorf("after the claim: status %v by %v, want draw by %s", state["status"], state["method"], c.reason)
+			}
+		})
+	}
+}
+
+func TestClaimDrawUnknownReason(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+	send(t, white, map[string]string{"action": "claimDraw", "gameID": gameID, "reason": "boredom"})
+	if msg := readUntil(t, white, hasError); msg["error"] != "unknown draw claim" {
+		t.Errorf("claiming a draw out of boredom: %v", msg["error"])
+	}
+}
+
+func TestAutomaticDraws(t *testing.T) {
+	for _, c := range []struct {
+		name, fen, method string
+		moves             []string
+	}{
+		// The rook move takes the half-move clock from 149 to 150.
+		{"seventy-five-move rule", "8/8/4k3/8/8/4K3/8/R7 w - - 149 110", "seventyFiveMoveRule", []string{"Ra2"}},
+		{"fivefold repetition", "", "fivefoldRepetition", knightShuffle},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			useConfig(t, nil)
+			srv := newTestServer(t)
+			white, black, gameID := startTestGameFrom(t, srv, c.fen)
+			state := play(t, white, black, gameID, c.moves...)
+			if state["status"] != "draw" || state["method"] != c.method {
+				t.Errorf("status %v by %v, want draw by %s", state["status"], state["method"], c.method)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// eloK is the most a rating can move in one game.
+	eloK = 32
+	// defaultRating is where players without a ranked game start.
+	defaultRating = 1200
+	// rankedRatingWindow is how far apart two ranked players' ratings may
+	// be for matchmaking to pair them.
+	rankedRatingWindow = 200
+)
+
+var (
+	// ratings holds each player's rating by Subject. dirtyRatings marks
+	// those changed since they were last written to the store.
+	ratings      = make(map[string]int)
+	dirtyRatings = make(map[string]bool)
+	ratingsMutex sync.Mutex
+)
+
+// NewRatings returns both players' ratings after a game with outcome,
+// using the standard Elo formula with K=eloK. Whatever white gains black
+// loses, so the ratings pool stays constant.
+func NewRatings(whiteRating, blackRating int, outcome chess.Outcome) (newWhite, newBlack int) {
+	var score float64
+	switch outcome {
+	case chess.WhiteWon:
+		score = 1
+	case chess.BlackWon:
+		score = 0
+	case chess.Draw:
+		score = 0.5
+	default:
+		return whiteRating, blackRating
+	}
+	expected := 1 / (1 + math.Pow(10, float64(blackRating-whiteRating)/400))
+	delta := int(math.Round(eloK * (score - expected)))
+	return whiteRating + delta, blackRating - delta
+}
+
+// ratingOf returns playerID's rating, or defaultRating if they have none.
+func ratingOf(playerID string) int {
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+	if rating, ok := ratings[playerID]; ok {
+		return rating
+	}
+	return defaultRating
+}
+
+// updateRatings applies the result of a finished ranked game to both
+// players' ratings. The caller must hold the game's lock.
+func updateRatings(gameID string, game *Game) {
+	white, black := opponent(game, chess.Black), opponent(game, chess.White)
+	if white == nil || black == nil || white.Subject == "" || black.Subject == "" {
+		return
+	}
+
+	ratingsMutex.Lock()
+	whiteRating, ok := ratings[white.Subject]
+	if !ok {
+		whiteRating = defaultRating
+	}
+	blackRating, ok := ratings[black.Subject]
+	if !ok {
+		blackRating = defaultRating
+	}
+	white.RatingBefore, black.RatingBefore = whiteRating, blackRating
+	white.Rating, black.Rating = NewRatings(whiteRating, blackRating, game.Game.Outcome())
+	ratings[white.Subject], ratings[black.Subject] = white.Rating, black.Rating
+	dirtyRatings[white.Subject], dirtyRatings[black.Subject] = true, true
+	ratingsMutex.Unlock()
+
+	logger.Info("Ratings updated", slog.String("gameID", gameID), slog.Int("whiteRating", white.Rating), slog.Int("blackRating", black.Rating))
+}
+
+// loadRatings reads every stored rating into the ratings map.
+func loadRatings(store Store) error {
+	stored, err := store.LoadRatings()
+	if err != nil {
+		return err
+	}
+	ratingsMutex.Lock()
+	for playerID, rating := range stored {
+		if _, ok := ratings[playerID]; !ok {
+			ratings[playerID] = rating
+		}
+	}
+	ratingsMutex.Unlock()
+	return nil
+}
+
+// saveRatings writes the ratings changed since the last save to store,
+// keeping them marked to retry if the write fails.
+func saveRatings(store Store) {
+	ratingsMutex.Lock()
+	changed := make(map[string]int, len(dirtyRatings))
+	for playerID := range dirtyRatings {
+		changed[playerID] = ratings[playerID]
+	}
+	clear(dirtyRatings)
+	ratingsMutex.Unlock()
+	if len(changed) == 0 {
+		return
+	}
+
+	if err := store.SaveRatings(changed); err != nil {
+		logger.Error("Error saving ratings", slog.Any("error", err))
+		ratingsMutex.Lock()
+		for playerID := range changed {
+			dirtyRatings[playerID] = true
+		}
+		ratingsMutex.Unlock()
+	}
+}
+
+// handleGetRating serves GET /ratings/{playerID}, where playerID is the
+// subject of the player's token.
+func handleGetRating(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	playerID := r.PathValue("playerID")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"playerID": playerID,
+		"rating":   ratingOf(playerID),
+	}); err != nil {
+		logger.Error("Error sending rating", slog.Any("error", err))
+	}
+}
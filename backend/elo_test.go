@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestNewRatings(t *testing.T) {
+	tests := []struct {
+		name                 string
+		white, black         int
+		outcome              chess.Outcome
+		wantWhite, wantBlack int
+	}{
+		{"white win, equal", 1200, 1200, chess.WhiteWon, 1216, 1184},
+		{"black win, equal", 1200, 1200, chess.BlackWon, 1184, 1216},
+		{"draw, equal", 1200, 1200, chess.Draw, 1200, 1200},
+		{"favourite wins", 1400, 1200, chess.WhiteWon, 1408, 1192},
+		{"underdog wins", 1400, 1200, chess.BlackWon, 1376, 1224},
+		{"draw against an underdog", 1400, 1200, chess.Draw, 1392, 1208},
+		{"draw against a favourite", 1200, 1400, chess.Draw, 1208, 1392},
+		{"no result", 1400, 1200, chess.NoOutcome, 1400, 1200},
+	}
+	for _, tt := range tests {
+		white, black := NewRatings(tt.white, tt.black, tt.outcome)
+		if white != tt.wantWhite || black != tt.wantBlack {
+			t.Errorf("%s: NewRatings(%d, %d, %s) = %d, %d, want %d, %d",
+				tt.name, tt.white, tt.black, tt.outcome, white, black, tt.wantWhite, tt.wantBlack)
+		}
+	}
+}
+
+// TestNewRatingsKFactor checks that no game moves a rating by more than K,
+// that a certain result moves it by almost exactly K, and that the pool
+// stays constant.
+func TestNewRatingsKFactor(t *testing.T) {
+	if white, black := NewRatings(800, 2800, chess.WhiteWon); white != 800+eloK || black != 2800-eloK {
+		t.Errorf("huge upset: got %d, %d, want the full K of %d", white, black, eloK)
+	}
+	if white, black := NewRatings(2800, 800, chess.WhiteWon); white != 2800 || black != 800 {
+		t.Errorf("expected win: got %d, %d, want no change", white, black)
+	}
+	for _, outcome := range []chess.Outcome{chess.WhiteWon, chess.BlackWon, chess.Draw} {
+		for gap := -1000; gap <= 1000; gap += 50 {
+			white, black := NewRatings(1500+gap, 1500, outcome)
+			change := white - (1500 + gap)
+			if change > eloK || change < -eloK {
+				t.Errorf("%s at gap %d: white moved %d, more than K", outcome, gap, change)
+			}
+			if white+black != 3000+gap {
+				t.Errorf("%s at gap %d: pool went from %d to %d", outcome, gap, 3000+gap, white+black)
+			}
+		}
+	}
+}
+
+func TestHandleGetRating(t *testing.T) {
+	ratingsMutex.Lock()
+	ratings["rated-player"] = 1337
+	ratingsMutex.Unlock()
+	t.Cleanup(func() {
+		ratingsMutex.Lock()
+		delete(ratings, "rated-player")
+		ratingsMutex.Unlock()
+	})
+
+	for playerID, want := range map[string]float64{"rated-player": 1337, "new-player": defaultRating} {
+		r := httptest.NewRequest("GET", "/ratings/"+playerID, nil)
+		r.SetPathValue("playerID", playerID)
+		w := httptest.NewRecorder()
+		handleGetRating(w, r)
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["playerID"] != playerID || body["rating"] != want {
+			t.Errorf("GET /ratings/%s = %v, want rating %v", playerID, body, want)
+		}
+	}
+}
+
+func TestRankedGameUpdatesRatings(t *testing.T) {
+	useConfig(t, func(c *Config) { c.JWTSecret = []byte("test secret") })
+	t.Cleanup(func() {
+		ratingsMutex.Lock()
+		for _, subject := range []string{"ranked-a", "ranked-b"} {
+			delete(ratings, subject)
+			delete(dirtyRatings, subject)
+		}
+		ratingsMutex.Unlock()
+	})
+	useArchive(t, newMemoryStore())
+	srv := newTestServer(t)
+	a, b := dialAuthenticated(t, srv, "ranked-a"), dialAuthenticated(t, srv, "ranked-b")
+	send(t, a, map[string]string{"action": "findGame", "ranked": "true"})
+	readUntil(t, a, hasStatus("searching"))
+	send(t, b, map[string]string{"action": "findGame", "ranked": "true"})
+	matched := readUntil(t, a, hasStatus("matched"))
+
+	white, winner := a, "ranked-b"
+	if matched["color"] == "b" {
+		white, winner = b, "ranked-a"
+	}
+	send(t, white, map[string]string{"action": "resign", "gameID": matched["gameID"].(string)})
+	state := readUntil(t, white, hasStatus("resigned"))
+	if state["ranked"] != true || state["whiteRating"] != float64(1184) || state["blackRating"] != float64(1216) {
+		t.Errorf("final state: ranked %v, ratings %v and %v, want 1184 and 1216", state["ranked"], state["whiteRating"], state["blackRating"])
+	}
+	if got := ratingOf(winner); got != 1216 {
+		t.Errorf("winner's rating = %d, want 1216", got)
+	}
+	// The archive keeps the rating each player went in with.
+	data, err := exportGames(winner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll(); err != nil || len(rows) != 2 || rows[1][10] != "1200" || rows[1][11] != "1216" {
+		t.Errorf("winner's export: %v, %v, want 1200 before and 1216 after", rows, err)
+	}
+}
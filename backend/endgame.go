@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+type EndgameClassification struct {
+	Type              string `json:"type"`
+	TheoreticalResult string `json:"theoreticalResult"`
+	Technique         string `json:"technique"`
+}
+
+// knownEndgames maps a material signature, stronger side first, to its
+// textbook verdict.
+var knownEndgames = map[string]EndgameClassification{
+	"KK":    {"KK", "draw", "Insufficient material"},
+	"KPK":   {"KPK", "depends_on_position", "Rule of the square; the stronger king must reach a key square"},
+	"KNK":   {"KNK", "draw", "Insufficient material"},
+	"KBK":   {"KBK", "draw", "Insufficient material"},
+	"KNNK":  {"KNNK", "draw", "Two knights cannot force mate against correct defence"},
+	"KBBK":  {"KBBK", "win_for_stronger", "Drive the king into a corner with the bishops side by side"},
+	"KBNK":  {"KBNK", "win_for_stronger", "Deletang's triangle method; mate in a corner of the bishop's colour"},
+	"KRK":   {"KRK", "win_for_stronger", "Box the king in with the rook and drive it to the edge"},
+	"KQK":   {"KQK", "win_for_stronger", "Restrict the king with the queen, avoid stalemate and bring your king up"},
+	"KRKB":  {"KRKB", "draw", "The defender heads for the corner opposite the bishop's colour"},
+	"KRKN":  {"KRKN", "draw", "Keep the knight close to the king"},
+	"KQKR":  {"KQKR", "win_for_stronger", "Philidor's technique: separate the rook from the king"},
+	"KQKP":  {"KQKP", "depends_on_position", "Approach with checks; rook and bishop pawns on the seventh may draw"},
+	"KRPKR": {"KRPKR", "depends_on_position", "Lucena position applicable for the attacker, Philidor defence for the defender"},
+	"KBPKB": {"KBPKB", "depends_on_position", "Opposite-coloured bishops usually draw"},
+}
+
+// materialSignature writes a side's pieces as in "KRP", strongest first.
+func materialSignature(m MaterialCount) string {
+	return "K" + strings.Repeat("Q", m.Queen) + strings.Repeat("R", m.Rooks) +
+		strings.Repeat("B", m.Bishops) + strings.Repeat("N", m.Knights) + strings.Repeat("P", m.Pawns)
+}
+
+func classifyEndgame(pos *chess.Position) EndgameClassification {
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	strong, weak := white, black
+	if black.centipawns() > white.centipawns() {
+		strong, weak = black, white
+	}
+
+	if known, ok := knownEndgames[materialSignature(strong)+materialSignature(weak)]; ok {
+		return known
+	}
+	if gamePhase(pos) != "endgame" {
+		return EndgameClassification{Type: "not_endgame", TheoreticalResult: "unclear", Technique: "Too much material remains for an endgame classification"}
+	}
+
+	queens := white.Queen + black.Queen
+	rooks := white.Rooks + black.Rooks
+	bishops := white.Bishops + black.Bishops
+	knights := white.Knights + black.Knights
+	switch {
+	case queens+rooks+bishops+knights == 0:
+		return EndgameClassification{"pawn_endgame", "depends_on_position", "Count tempi, use the opposition and create an outside passed pawn"}
+	case rooks > 0 && queens+bishops+knights == 0:
+		return EndgameClassification{"rook_endgame", "depends_on_position", "Activate your rook, put it behind passed pawns and cut off the enemy king"}
+	case queens > 0 && rooks+bishops+knights == 0:
+		return EndgameClassification{"queen_endgame", "depends_on_position", "Keep your king sheltered from perpetual check and push the most advanced passed pawn"}
+	case bishops > 0 && queens+rooks+knights == 0:
+		return EndgameClassification{"bishop_endgame", "depends_on_position", "Place your pawns on the opposite colour of your bishop"}
+	case knights > 0 && queens+rooks+bishops == 0:
+		return EndgameClassification{"knight_endgame", "depends_on_position", "Knight endings play like pawn endings; an outside passed pawn is decisive"}
+	}
+	return EndgameClassification{"mixed_endgame", "depends_on_position", "Activate your king and trade into a favourable simpler ending"}
+}
+
+func getEndgameClassification(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, classifyEndgame(pos))
+	logger.Debug("Endgame classification sent", slog.String("gameID", gameID))
+}
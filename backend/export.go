@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// exportColumns heads the CSV export of a player's games. accuracy is left
+// empty, as the server has no engine to measure it with.
+var exportColumns = []string{"gameID", "date", "color", "opponent", "timeControl", "variant", "outcome", "moves", "accuracy", "opening", "ratingBefore", "ratingAfter"}
+
+// exportRow is the row for archived of one of its players.
+func exportRow(archived *archivedGame, player archivedPlayer) []string {
+	opponent := ""
+	for _, other := range archived.Players {
+		if other.Color != player.Color {
+			opponent = other.Name
+			if opponent == "" {
+				opponent = other.Subject
+			}
+		}
+	}
+	outcome := "draw"
+	switch {
+	case outcomeWinner(archived.Outcome) == colorName(player.Color):
+		outcome = "win"
+	case outcomeWinner(archived.Outcome) != "":
+		outcome = "loss"
+	}
+	opening := ""
+	if archived.StartingFEN == chess.StartingPosition().String() {
+		if o := ecoBook().Find(archived.Moves); o != nil {
+			opening = o.Title()
+		}
+	}
+	ratingBefore, ratingAfter := "", ""
+	if player.RatingBefore != 0 {
+		ratingBefore, ratingAfter = strconv.Itoa(player.RatingBefore), strconv.Itoa(player.RatingAfter)
+	}
+	return []string{
+		archived.ID,
+		archived.EndedAt.UTC().Format("2006-01-02"),
+		colorName(player.Color),
+		opponent,
+		archived.TimeControl,
+		archived.Variant,
+		outcome,
+		strconv.Itoa((len(archived.Moves) + 1) / 2),
+		"",
+		opening,
+		ratingBefore,
+		ratingAfter,
+	}
+}
+
+// exportGames returns playerID's archived games as CSV, oldest first,
+// with moves counting full moves.
+func exportGames(playerID string) ([]byte, error) {
+	archive.Lock()
+	loadArchive()
+	type played struct {
+		archived *archivedGame
+		player   archivedPlayer
+	}
+	var history []played
+	for _, archived := range archive.games {
+		for _, player := range archived.Players {
+			if player.Subject == playerID {
+				history = append(history, played{archived, player})
+			}
+		}
+	}
+	archive.Unlock()
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].archived.EndedAt.Equal(history[j].archived.EndedAt) {
+			return history[i].archived.EndedAt.Before(history[j].archived.EndedAt)
+		}
+		return history[i].archived.ID < history[j].archived.ID
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(exportColumns)
+	for _, game := range history {
+		w.Write(exportRow(game.archived, game.player))
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportCSV sends ws the CSV export of playerID's games, base64 encoded,
+// or of ws's own account if no playerID is given.
+func exportCSV(ws *websocket.Conn, playerID string) {
+	if playerID == "" {
+		identitiesMutex.Lock()
+		playerID = identities[ws].Subject
+		identitiesMutex.Unlock()
+	}
+	if playerID == "" {
+		sendJSON(ws, map[string]string{"error": "playerID required"})
+		return
+	}
+	data, err := exportGames(playerID)
+	if err != nil {
+		sendJSON(ws, map[string]string{"error": "could not export games"})
+		logger.Error("Error exporting games", slog.String("playerID", playerID), slog.Any("error", err))
+		return
+	}
+	sendJSON(ws, map[string]string{"csv": base64.StdEncoding.EncodeToString(data)})
+	logger.Debug("Games exported", slog.String("playerID", playerID))
+}
+
+// handleExportCSV serves GET /player/{id}/export.csv, where id is the
+// subject of the player's token.
+func handleExportCSV(w http.ResponseWriter, r *http.Request) {
+	playerID := r.PathValue("id")
+	data, err := exportGames(playerID)
+	if err != nil {
+		logger.Error("Error exporting games", slog.String("playerID", playerID), slog.Any("error", err))
+		http.Error(w, "could not export games", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="games.csv"`)
+	w.Write(data)
+}
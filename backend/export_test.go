@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestExportCSV(t *testing.T) {
+	useConfig(t, func(c *Config) { c.JWTSecret = []byte("test secret") })
+	srv := newTestServer(t)
+	store := newMemoryStore()
+	useArchive(t, store)
+
+	// A ranked win read back from the store, then an unranked draw from a
+	// set-up position archived since.
+	won := chess.NewGame()
+	for _, move := range []string{"e4", "e5", "Nf3"} {
+		if err := won.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	won.Resign(chess.Black)
+	data, err := encodeGame(&Game{
+		Game:        won,
+		StartingFEN: chess.StartingPosition().String(),
+		Variant:     "standard",
+		TimeControl: "5+3",
+		Ranked:      true,
+		EndedAt:     time.Date(2025, 1, 7, 18, 0, 0, 0, time.UTC),
+		Players: []*Player{
+			{Subject: "alice", Name: "Alice", Color: chess.White, RatingBefore: 1200, Rating: 1216},
+			{Subject: "bob", Name: "Bob", Color: chess.Black, RatingBefore: 1200, Rating: 1184},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.ArchiveGame("won", data)
+	drawn := gameFromFEN(t, "4k3/8/8/8/8/8/8/4K3 b - - 0 1")
+	drawn.Draw(chess.DrawOffer)
+	archiveGame("drawn", &Game{
+		Game:        drawn,
+		StartingFEN: "4k3/8/8/8/8/8/8/4K3 b - - 0 1",
+		Variant:     "standard",
+		EndedAt:     time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC),
+		Players: []*Player{
+			{Subject: "carol", Color: chess.White},
+			{Subject: "alice", Name: "Alice", Color: chess.Black},
+		},
+	})
+
+	want := [][]string{
+		exportColumns,
+		{"won", "2025-01-07", "white", "Bob", "5+3", "standard", "win", "2", "", "King's Knight Opening", "1200", "1216"},
+		{"drawn", "2025-02-01", "black", "carol", "", "standard", "draw", "0", "", "", "", ""},
+	}
+	resp, err := http.Get(srv.URL + "/player/alice/export.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/csv; charset=utf-8" || !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Errorf("served with headers %v", resp.Header)
+	}
+	if rows, err := csv.NewReader(strings.NewReader(string(body))).ReadAll(); err != nil || !reflect.DeepEqual(rows, want) {
+		t.Errorf("GET export.csv = %v, %v, want %v", rows, err, want)
+	}
+
+	alice := dialAuthenticated(t, srv, "alice")
+	send(t, alice, map[string]string{"action": "export_csv"})
+	msg := readUntil(t, alice, func(msg map[string]interface{}) bool { return msg["csv"] != nil })
+	decoded, err := base64.StdEncoding.DecodeString(msg["csv"].(string))
+	if err != nil || string(decoded) != string(body) {
+		t.Errorf("export_csv sent %q, %v, want the same CSV", decoded, err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// afterItalian is the position after 1.e4 e5 2.Nf3 Nc6 3.Bc4 Bc5.
+const afterItalian = "r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+
+func TestCreateFromFEN(t *testing.T) {
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create", "fen": afterItalian})
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID := created["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	var state map[string]interface{}
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		state = readUntil(t, ws, isState)
+	}
+	if state["fen"] != afterItalian || state["startingFen"] != afterItalian {
+		t.Errorf("state has fen %v and startingFen %v, want both %q", state["fen"], state["startingFen"], afterItalian)
+	}
+
+	white, black := creator, joiner
+	if created["color"] != "w" {
+		white, black = joiner, creator
+	}
+	// Both moves are legal from the standard start but not here: e4 is
+	// already occupied and the g1 knight has left.
+	for _, move := range []string{"e4", "Nh3"} {
+		send(t, white, map[string]string{"action": "move", "gameID": gameID, "move": move})
+		if msg := readUntil(t, white, func(msg map[string]interface{}) bool { return hasError(msg) || isState(msg) }); !hasError(msg) {
+			t.Errorf("%s was accepted in %s", move, afterItalian)
+		}
+	}
+	state = play(t, white, black, gameID, "O-O", "Nf6")
+	if want := "r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 w kq - 6 5"; state["fen"] != want {
+		t.Errorf("fen after O-O Nf6 = %v, want %q", state["fen"], want)
+	}
+}
+
+func TestCreateRejectsInvalidFEN(t *testing.T) {
+	srv := newTestServer(t)
+	useGames(t)
+	ws := dial(t, srv)
+	for _, c := range []struct{ fen, reason string }{
+		{"not a position", ""},
+		{"8/8/8/8/8/8/8/4K3 w - - 0 1", "each side needs exactly one king"},
+		{"4k3/8/8/8/8/8/8/3KK3 w - - 0 1", "each side needs exactly one king"},
+		{"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", "the side not to move is in check"},
+	} {
+		send(t, ws, map[string]string{"action": "create", "fen": c.fen})
+		msg := readUntil(t, ws, func(msg map[string]interface{}) bool { return hasError(msg) || msg["status"] == "created" })
+		reason, _ := msg["error"].(string)
+		if !strings.HasPrefix(reason, "invalid FEN: ") || !strings.HasSuffix(reason, c.reason) {
+			t.Errorf("creating from %q: got %v, want an invalid FEN error ending %q", c.fen, msg, c.reason)
+		}
+	}
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	if len(games) != 0 {
+		t.Errorf("%d games were created from invalid FENs", len(games))
+	}
+}
+
+func TestCreateVariants(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	useGames(t)
+	ws := dial(t, srv)
+	for _, c := range []struct{ variant, reply string }{
+		{"chess960", "created"},
+		{"crazyhouse", "unsupported variant"},
+		{"standard", "created"},
+		{"", "created"},
+	} {
+		send(t, ws, map[string]string{"action": "create", "variant": c.variant})
+		msg := readUntil(t, ws, func(msg map[string]interface{}) bool { return hasError(msg) || msg["status"] == "created" })
+		if msg["error"] != c.reply && msg["status"] != c.reply {
+			t.Errorf("creating variant %q: got %v, want %q", c.variant, msg, c.reply)
+		}
+	}
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	variants := map[string]int{}
+	for _, game := range games {
+		variants[game.Variant]++
+	}
+	if len(games) != 3 || variants["standard"] != 2 || variants["chess960"] != 1 {
+		t.Errorf("created games of variants %v, want 2 standard and 1 chess960", variants)
+	}
+}
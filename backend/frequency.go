@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// PositionFrequency is how often a position arose in the archived games,
+// the share of those games each result took, and the dates of the first
+// and last of them.
+type PositionFrequency struct {
+	Occurrences int     `json:"occurrences"`
+	WhiteWins   float64 `json:"whiteWins"`
+	Draws       float64 `json:"draws"`
+	BlackWins   float64 `json:"blackWins"`
+	FirstSeen   string  `json:"firstSeen,omitempty"`
+	LastSeen    string  `json:"lastSeen,omitempty"`
+}
+
+// positionFrequency looks pos up in the archive's position index.
+func positionFrequency(pos *chess.Position) PositionFrequency {
+	archive.Lock()
+	defer archive.Unlock()
+	loadArchive()
+
+	var freq PositionFrequency
+	var whiteWins, draws, blackWins int
+	var first, last *archivedGame
+	for _, id := range archive.positions[positionKey(pos)] {
+		archived := archive.games[id]
+		freq.Occurrences++
+		switch archived.Outcome {
+		case chess.WhiteWon:
+			whiteWins++
+		case chess.BlackWon:
+			blackWins++
+		case chess.Draw:
+			draws++
+		}
+		if first == nil || archived.EndedAt.Before(first.EndedAt) {
+			first = archived
+		}
+		if last == nil || archived.EndedAt.After(last.EndedAt) {
+			last = archived
+		}
+	}
+	if freq.Occurrences == 0 {
+		return freq
+	}
+	share := func(n int) float64 {
+		return math.Round(100*float64(n)/float64(freq.Occurrences)) / 100
+	}
+	freq.WhiteWins, freq.Draws, freq.BlackWins = share(whiteWins), share(draws), share(blackWins)
+	freq.FirstSeen = first.EndedAt.Format("2006-01-02")
+	freq.LastSeen = last.EndedAt.Format("2006-01-02")
+	return freq
+}
+
+func getPositionFrequency(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, positionFrequency(pos))
+	logger.Debug("Position frequency sent", slog.String("gameID", gameID))
+}
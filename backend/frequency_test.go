@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestPositionFrequency(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+
+	// One game was archived before the server started, on another day.
+	store := newMemoryStore()
+	old := gameFromFEN(t, chess.StartingPosition().String())
+	for _, move := range []string{"e4", "e5", "Nf3"} {
+		if err := old.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old.Resign(chess.Black)
+	data, err := encodeGame(&Game{Game: old, StartingFEN: chess.StartingPosition().String(), EndedAt: time.Date(2024, 3, 21, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.ArchiveGame("old", data)
+	useArchive(t, store)
+
+	// Scholar's mate passes through 1.e4 e5 too; the resignation after
+	// 1.e4 does not.
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#")
+	white, black, gameID = startTestGame(t, srv)
+	play(t, white, black, gameID, "e4")
+	send(t, black, map[string]string{"action": "resign", "gameID": gameID})
+	readUntil(t, white, hasStatus("resigned"))
+
+	white, black, gameID = startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5")
+	send(t, white, map[string]string{"action": "position_frequency", "gameID": gameID})
+	freq := readUntil(t, white, func(msg map[string]interface{}) bool { _, ok := msg["occurrences"]; return ok })
+	today := time.Now().Format("2006-01-02")
+	if freq["occurrences"] != float64(2) || freq["whiteWins"] != 1.0 || freq["draws"] != 0.0 || freq["blackWins"] != 0.0 ||
+		freq["firstSeen"] != "2024-03-21" || freq["lastSeen"] != today {
+		t.Errorf("frequency of 1.e4 e5: got %v", freq)
+	}
+	if got := positionFrequency(chess.StartingPosition()); got.Occurrences != 3 {
+		t.Errorf("the starting position occurred in %d games, want 3", got.Occurrences)
+	}
+	if got := positionFrequency(gameFromFEN(t, "8/8/8/4k3/8/8/8/3QK3 w - - 0 1").Position()); got != (PositionFrequency{}) {
+		t.Errorf("frequency of an unseen position: got %+v", got)
+	}
+
+	// Finished games reach the store too.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		archived, _ := store.LoadArchive()
+		if len(archived) == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d games in the store, want 3", len(archived))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPositionKey(t *testing.T) {
+	a := gameFromFEN(t, "8/8/8/4k3/8/8/8/3QK3 w - - 0 1").Position()
+	b := gameFromFEN(t, "8/8/8/4k3/8/8/8/3QK3 w - - 12 40").Position()
+	if positionKey(a) != positionKey(b) {
+		t.Errorf("keys %q and %q differ only in the move clocks", positionKey(a), positionKey(b))
+	}
+}
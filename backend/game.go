@@ -2,12 +2,146 @@ package main
 
 import (
 	"sync"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/notnil/chess"
 )
 
 type Game struct {
 	Game    *chess.Game
 	Players []*Player
+	Coaches []*websocket.Conn
+
+	// StartingFEN is the position the game was created from.
+	StartingFEN string
+
+	// Variant is "standard" or "chess960", which starts from a shuffled
+	// back rank but cannot castle.
+	Variant string
+
+	// CreatedAt dates the game for its PGN headers.
+	CreatedAt time.Time
+
+	// StartedAt is when both players were seated and play began, and
+	// EndedAt when the result was first broadcast.
+	StartedAt time.Time
+	EndedAt   time.Time
+
+	// Spectators receive every game state but cannot move.
+	// SpectatorChatTimes rate limits their chat like Player.ChatTimes.
+	Spectators         []*websocket.Conn
+	SpectatorChatTimes map[*websocket.Conn][]time.Time
+
+	// ChatHistory keeps the last chatHistorySize chat messages.
+	ChatHistory []ChatMessage
+
+	// Adjudicated is set when an admin decided the result with the
+	// set_game_result action rather than it being played out.
+	Adjudicated        bool
+	AdjudicationReason string
+
+	// Clock is nil for untimed games. TimeControl is the setting it was
+	// created from, such as "5+3", for rematches.
+	Clock       *GameClock
+	TimeControl string
+
+	// CorrespondenceConversionVote holds the players who asked to convert
+	// the game to correspondence; it is converted once both have.
+	CorrespondenceConversionVote map[chess.Color]bool
+
+	// TimedOut is set when a player lost on time.
+	TimedOut bool
+
+	// DrawOffer is the side with a draw offer pending, or chess.NoColor.
+	DrawOffer chess.Color
+
+	// TakebackRequest is the side asking to take back a move, or
+	// chess.NoColor. TakebackTimer declines it if the opponent has not
+	// answered within Config.TakebackTimeout.
+	TakebackRequest chess.Color
+	TakebackTimer   *time.Timer
+	// afterFunc starts TakebackTimer in place of time.AfterFunc when set,
+	// so that tests can fire it without waiting.
+	afterFunc func(time.Duration, func()) *time.Timer
+
+	// RematchOffer is the side offering a rematch of a finished game, or
+	// chess.NoColor.
+	RematchOffer chess.Color
+
+	// AbortRequested is set while AbortRequester waits up to
+	// config.AbortTimeout for the opponent to agree to abort the game.
+	AbortRequested bool
+	AbortRequester chess.Color
+	abortTimer     *time.Timer
+
+	// AwaitingReady is set from when a second player joins until both
+	// players have sent ready. readyTimer reopens the seat if that takes
+	// longer than Config.ReadyTimeout.
+	AwaitingReady bool
+	readyTimer    *time.Timer
+
+	// Abandoned is set when a player forfeited by not reconnecting in time.
+	Abandoned bool
+
+	// PracticeMode is "random" when the server plays the second seat
+	// against a single player, and empty in normal games.
+	PracticeMode string
+
+	// ApplyMoveDelay holds each move for Config.TargetLatency minus the
+	// mover's measured latency, evening out connection speed in bullet.
+	ApplyMoveDelay bool
+
+	// StalemateMeansWin awards stalemate to the stalemating side, a
+	// training rule that rewards clean technique against a bare king.
+	StalemateMeansWin bool
+
+	// MoveTimes holds how long each half-move took, timed from
+	// MoveStartedAt: the start of the game or the previous move. Moves
+	// that were already on the board when the game started count as
+	// zero.
+	MoveTimes     []time.Duration
+	MoveStartedAt time.Time
+
+	// LastComplexity is the position complexity at the last broadcast,
+	// used to spot sudden jumps.
+	LastComplexity int
+
+	// MobilityHistory holds both sides' legal move counts after each of
+	// the last mobilityHistoryLength moves.
+	MobilityHistory []MobilitySnapshot
+
+	// Ranked games move both players' ratings.
+	Ranked bool
+
+	// guestTimer deletes a game with a guest in it; see watchGuestGame.
+	// deleteGame stops it.
+	guestTimer *time.Timer
+
+	// StateSeq numbers the game states broadcast, going up each time one
+	// differs from lastState, the one before it. deltaClients holds the
+	// last seq sent to each connection that asked for deltas instead of
+	// whole states.
+	StateSeq     uint64
+	lastState    map[string]interface{}
+	deltaClients map[*websocket.Conn]uint64
+
+	// endRecorded is set once the end of the game has been dealt with:
+	// its length has gone into the chess_game_duration_seconds metric,
+	// ratings have moved and the webhook has been called.
+	endRecorded bool
+
+	// moveBucket rate limits move attempts; see moveLimiter.
+	moveBucket *tokenBucket
+
+	// sseClients holds the event channel of each Server-Sent Events
+	// stream, by client ID, and sseEvents the latest events sent to them,
+	// oldest first. sseDone is closed, and sseEnded set, once the game is
+	// deleted, ending every stream.
+	sseClients map[string]chan string
+	sseEvents  []sseEvent
+	sseDone    chan struct{}
+	sseEnded   bool
+
 	sync.Mutex
 }
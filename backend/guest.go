@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// guestIdentity is the temporary identity of a connection without a
+// token. ID is "guest-" and a random UUID; it reclaims the player's seats
+// until Expires, much as PlayerID reclaims one seat.
+type guestIdentity struct {
+	ID      string
+	Expires time.Time
+}
+
+// guests holds the identity of each guest connection that has taken a
+// seat. It is guarded by identitiesMutex, like identities.
+var guests = make(map[*websocket.Conn]guestIdentity)
+
+func newGuestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("guest-%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// guestOf returns the guest identity of ws, making one the first time a
+// guest takes a seat or once the old one has expired. Authenticated
+// connections get the zero identity.
+func guestOf(ws *websocket.Conn) guestIdentity {
+	identitiesMutex.Lock()
+	defer identitiesMutex.Unlock()
+	if _, ok := identities[ws]; ok {
+		return guestIdentity{}
+	}
+	guest, ok := guests[ws]
+	if !ok || !time.Now().Before(guest.Expires) {
+		guest = guestIdentity{ID: newGuestID(), Expires: time.Now().Add(config.GuestTTL)}
+		guests[ws] = guest
+	}
+	return guest
+}
+
+// seatGuest gives player the guest identity of their connection, if it
+// has one.
+func seatGuest(player *Player) {
+	guest := guestOf(player.Conn)
+	player.GuestID, player.GuestExpires = guest.ID, guest.Expires
+}
+
+// adoptGuest makes player's guest identity that of ws, which has just
+// reclaimed their seat.
+func adoptGuest(ws *websocket.Conn, player *Player) {
+	if player.GuestID == "" {
+		return
+	}
+	identitiesMutex.Lock()
+	if _, ok := identities[ws]; !ok {
+		guests[ws] = guestIdentity{ID: player.GuestID, Expires: player.GuestExpires}
+	}
+	identitiesMutex.Unlock()
+}
+
+// watchGuestGame deletes a game with a guest in it config.GuestTTL after
+// it was created, so that games guests leave behind do not pile up. The
+// caller must hold gamesMutex.
+func watchGuestGame(gameID string, game *Game) {
+	if game.guestTimer != nil {
+		return
+	}
+	for _, player := range game.Players {
+		if player.GuestID != "" {
+			game.guestTimer = time.AfterFunc(max(config.GuestTTL-time.Since(game.CreatedAt), 0), func() {
+				expireGuestGame(gameID, game)
+			})
+			return
+		}
+	}
+}
+
+func expireGuestGame(gameID string, game *Game) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	if games[gameID] != game {
+		return
+	}
+	for _, conn := range removeAbortedGame(gameID, game) {
+		sendJSON(conn, map[string]string{"status": "expired", "gameID": gameID})
+		if !inOtherGames(conn, gameID) {
+			closeConnection(conn, CloseGameEnded, "guest game expired")
+		}
+	}
+	logger.Info("Guest game expired", slog.String("gameID", gameID))
+}
+
+// withGuestID adds player's guest ID, if any, to reply, their answer on
+// taking a seat.
+func withGuestID(reply map[string]string, player *Player) map[string]string {
+	if player.GuestID != "" {
+		reply["guestID"] = player.GuestID
+	}
+	return reply
+}
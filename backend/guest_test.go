@@ -0,0 +1,145 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+var guestIDPattern = regexp.MustCompile(`^guest-[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestGuestIDs(t *testing.T) {
+	useConfig(t, func(c *Config) { c.JWTSecret = []byte("test secret") })
+	srv := newTestServer(t)
+
+	guest := dial(t, srv)
+	send(t, guest, map[string]string{"action": "create"})
+	created := readUntil(t, guest, hasStatus("created"))
+	guestID, _ := created["guestID"].(string)
+	if !guestIDPattern.MatchString(guestID) {
+		t.Errorf("guest ID %q is not guest- and a UUID", guestID)
+	}
+	member := dialAuthenticated(t, srv, "alice")
+	send(t, member, map[string]string{"action": "join", "gameID": created["gameID"].(string)})
+	if joined := readUntil(t, member, hasStatus("joined")); joined["guestID"] != nil {
+		t.Errorf("authenticated player got guest ID %v", joined["guestID"])
+	}
+}
+
+func TestGuestReconnect(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create"})
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID, guestID := created["gameID"].(string), created["guestID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	readUntil(t, joiner, isState)
+
+	color := chess.White
+	if created["color"] != "w" {
+		color = chess.Black
+	}
+
+	creator.Close()
+	waitForHeldSeat(t, gameID, color)
+	back := dial(t, srv)
+	send(t, back, map[string]string{"action": "reconnect", "gameID": gameID, "guestID": guestID})
+	if reply := readUntil(t, back, hasStatus("reconnected")); reply["playerID"] != created["playerID"] || reply["guestID"] != guestID {
+		t.Errorf("reconnecting by guest ID: got %v", reply)
+	}
+
+	// Once the guest ID has expired, only the seat's player ID will do.
+	gamesMutex.RLock()
+	game := games[gameID]
+	game.Lock()
+	opponent(game, color.Other()).GuestExpires = time.Now()
+	game.Unlock()
+	gamesMutex.RUnlock()
+	back.Close()
+	waitForHeldSeat(t, gameID, color)
+	late := dial(t, srv)
+	send(t, late, map[string]string{"action": "reconnect", "gameID": gameID, "guestID": guestID})
+	if reply := readUntil(t, late, hasError); reply["error"] != "guest ID expired" {
+		t.Errorf("reconnecting by an expired guest ID: %v", reply["error"])
+	}
+	send(t, late, map[string]string{"action": "reconnect", "gameID": gameID, "playerID": created["playerID"].(string)})
+	readUntil(t, late, hasStatus("reconnected"))
+}
+
+func TestGuestGameExpires(t *testing.T) {
+	useConfig(t, func(c *Config) { c.GuestTTL = 50 * time.Millisecond })
+	srv := newTestServer(t)
+	guest := dial(t, srv)
+	send(t, guest, map[string]string{"action": "create"})
+	gameID := readUntil(t, guest, hasStatus("created"))["gameID"].(string)
+
+	if msg := readUntil(t, guest, hasStatus("expired")); msg["gameID"] != gameID {
+		t.Errorf("expired %v, want %s", msg["gameID"], gameID)
+	}
+	gamesMutex.RLock()
+	_, exists := games[gameID]
+	gamesMutex.RUnlock()
+	if exists {
+		t.Error("the expired game was not deleted")
+	}
+}
+
+func TestGuestGameExpiryKeepsOtherGames(t *testing.T) {
+	useConfig(t, func(c *Config) { c.MaxGamesPerPlayer = 2 })
+	srv := newTestServer(t)
+	guest := dial(t, srv)
+	var gameIDs []string
+	for i := 0; i < 2; i++ {
+		send(t, guest, map[string]string{"action": "create"})
+		gameIDs = append(gameIDs, readUntil(t, guest, hasStatus("created"))["gameID"].(string))
+	}
+
+	gamesMutex.RLock()
+	game := games[gameIDs[0]]
+	gamesMutex.RUnlock()
+	expireGuestGame(gameIDs[0], game)
+	readUntil(t, guest, hasStatus("expired"))
+	send(t, guest, map[string]string{"action": "syncState", "gameID": gameIDs[1]})
+	if msg := readUntil(t, guest, hasError); msg["error"] != "game has not started" {
+		t.Errorf("syncing the other game: %v", msg["error"])
+	}
+}
+
+func TestDeletedGuestGameStopsTimer(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	guest := dial(t, srv)
+	send(t, guest, map[string]string{"action": "create"})
+	gameID := readUntil(t, guest, hasStatus("created"))["gameID"].(string)
+	gamesMutex.RLock()
+	game := games[gameID]
+	gamesMutex.RUnlock()
+
+	guest.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		gamesMutex.RLock()
+		_, exists := games[gameID]
+		gamesMutex.RUnlock()
+		if !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the game was not deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	game.Lock()
+	defer game.Unlock()
+	if game.guestTimer.Stop() {
+		t.Error("the guest timer was still running after the game was deleted")
+	}
+}
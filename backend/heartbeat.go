@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pongWait and pingInterval are variables so tests can shorten them.
+var (
+	// pongWait is how long a connection may go without sending a message
+	// or answering a ping before it is treated as dead.
+	pongWait = 60 * time.Second
+	// pingInterval is how often each connection is pinged. It is well
+	// inside pongWait so a single late pong does not drop anyone.
+	pingInterval = 25 * time.Second
+)
+
+// startHeartbeat pings ws straight away and then every pingInterval until
+// the returned function is called. Each ping carries the time it was sent,
+// so its pong both extends the read deadline and refreshes the
+// connection's latency.
+func startHeartbeat(ws *websocket.Conn) func() {
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(appData string) error {
+		ws.SetReadDeadline(time.Now().Add(pongWait))
+		if sent, err := strconv.ParseInt(appData, 10, 64); err == nil {
+			recordLatency(ws, time.Since(time.Unix(0, sent))/2)
+		}
+		touchPlayer(ws)
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			sendPing(ws)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func sendPing(ws *websocket.Conn) {
+	payload := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+	if err := ws.WriteControl(websocket.PingMessage, payload, time.Now().Add(time.Second)); err != nil {
+		logger.Debug("Error sending ping", slog.Any("error", err))
+	}
+}
+
+// touchPlayer records that ws was heard from on every seat it holds,
+// locking only the games playerGames lists for it.
+func touchPlayer(ws *websocket.Conn) {
+	now := time.Now()
+	gamesMutex.RLock()
+	defer gamesMutex.RUnlock()
+
+	for _, gameID := range playerGames[ws] {
+		game, exists := games[gameID]
+		if !exists {
+			continue
+		}
+		game.Lock()
+		for _, player := range game.Players {
+			if player.Conn == ws {
+				player.LastSeen = now
+			}
+		}
+		game.Unlock()
+	}
+}
+
+// isTimeout reports whether a read failed because the peer went quiet for
+// longer than pongWait.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
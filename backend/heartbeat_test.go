@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// TestStaleConnectionRemoved checks that a player who stops answering
+// pings is disconnected, while their opponent, whose client keeps reading
+// and so answers pings, stays connected.
+func TestStaleConnectionRemoved(t *testing.T) {
+	savedWait, savedInterval := pongWait, pingInterval
+	pongWait, pingInterval = 300*time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { pongWait, pingInterval = savedWait, savedInterval })
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+	start := time.Now()
+
+	// Only white's client keeps reading, so black never answers a ping.
+	whiteClosed := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := white.ReadMessage(); err != nil {
+				whiteClosed <- err
+				return
+			}
+		}
+	}()
+
+	seats := func() (live, stale *Player) {
+		gamesMutex.RLock()
+		defer gamesMutex.RUnlock()
+		game := games[gameID]
+		game.Lock()
+		defer game.Unlock()
+		for _, p := range game.Players {
+			copied := &Player{Conn: p.Conn, LastSeen: p.LastSeen}
+			if p.Color == chess.White {
+				live = copied
+			} else {
+				stale = copied
+			}
+		}
+		return live, stale
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		live, stale := seats()
+		if live.Conn == nil {
+			t.Fatal("the live connection was removed")
+		}
+		if stale.Conn == nil {
+			if !live.LastSeen.After(start) {
+				t.Errorf("the live player was last seen at %v, before the test started at %v", live.LastSeen, start)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the stale connection was not removed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case err := <-whiteClosed:
+		t.Fatalf("the live connection was closed: %v", err)
+	case <-time.After(2 * pongWait):
+	}
+	if live, _ := seats(); live.Conn == nil {
+		t.Error("the live connection was removed after the stale one")
+	}
+}
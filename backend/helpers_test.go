@@ -0,0 +1,298 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMain(m *testing.M) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// useConfig runs the test with a fresh default configuration, changed by
+// set, and with rate limits high enough not to get in the way.
+func useConfig(t testing.TB, set func(*Config)) {
+	t.Helper()
+	saved := config
+	config = defaultConfig()
+	config.MoveRateLimit = 100
+	config.ConnectionRateLimit = 100
+	config.MaxGamesPerPlayer = 5
+	if set != nil {
+		set(config)
+	}
+	t.Cleanup(func() { config = saved })
+}
+
+// useGames runs the test with an empty games map, so that games left by
+// other tests do not show up in listings.
+func useGames(t testing.TB) {
+	t.Helper()
+	gamesMutex.Lock()
+	saved := games
+	games = make(map[string]*Game)
+	gamesMutex.Unlock()
+	t.Cleanup(func() {
+		gamesMutex.Lock()
+		games = saved
+		gamesMutex.Unlock()
+	})
+}
+
+// useArchive runs the test with an empty archive kept in store, so that
+// games finished by other tests are not found.
+func useArchive(t testing.TB, store Store) {
+	t.Helper()
+	archive.Lock()
+	savedStore, savedLoaded, savedGames, savedPositions, savedActivity := archive.store, archive.loaded, archive.games, archive.positions, archive.activity
+	archive.store, archive.loaded, archive.games, archive.positions, archive.activity = store, false, make(map[string]*archivedGame), nil, make(map[string]*activityRing)
+	archive.Unlock()
+	t.Cleanup(func() {
+		archive.Lock()
+		archive.store, archive.loaded, archive.games, archive.positions, archive.activity = savedStore, savedLoaded, savedGames, savedPositions, savedActivity
+		archive.Unlock()
+	})
+}
+
+// newTestServer serves testHandler over plain HTTP.
+func newTestServer(t testing.TB) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(testHandler(t))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testHandler serves the same endpoints as main. Its cleanup, which runs
+// after that of a server registered later, waits for every WebSocket
+// handler to return, which httptest does not do for hijacked connections,
+// so none outlive the test's configuration.
+func testHandler(t testing.TB) http.Handler {
+	t.Helper()
+	var handlers sync.WaitGroup
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		handlers.Add(1)
+		defer handlers.Done()
+		handleConnections(w, r)
+	})
+	mux.HandleFunc("GET /pos/{encoded}", handleSharedPosition)
+	mux.HandleFunc("GET /games", handleListGames)
+	mux.HandleFunc("GET /games/{gameID}/stream", handleGameStream)
+	mux.HandleFunc("POST /auth/token", handleIssueToken)
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /ratings/{playerID}", handleGetRating)
+	mux.HandleFunc("GET /stats", handleStats)
+	mux.HandleFunc("GET /player/{id}/heatmap", handleActivityHeatmap)
+	mux.HandleFunc("GET /player/{id}/export.csv", handleExportCSV)
+	t.Cleanup(handlers.Wait)
+	return mux
+}
+
+func dial(t testing.TB, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	return dialWith(t, srv, nil)
+}
+
+// dialWith opens a WebSocket to srv sending header with the handshake.
+func dialWith(t testing.TB, srv *httptest.Server, header http.Header) *websocket.Conn {
+	t.Helper()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func send(t testing.TB, ws *websocket.Conn, msg map[string]string) {
+	t.Helper()
+	if err := ws.WriteJSON(msg); err != nil {
+		t.Fatalf("sending %v: %v", msg, err)
+	}
+}
+
+// readUntil reads from ws until a message satisfies match, failing the
+// test if none arrives within two seconds.
+func readUntil(t testing.TB, ws *websocket.Conn, match func(map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer ws.SetReadDeadline(time.Time{})
+	for {
+		var msg map[string]interface{}
+		if err := ws.ReadJSON(&msg); err != nil {
+			t.Fatalf("reading: %v", err)
+		}
+		if match(msg) {
+			return msg
+		}
+	}
+}
+
+// hasStatus matches messages with the given status.
+func hasStatus(status string) func(map[string]interface{}) bool {
+	return func(msg map[string]interface{}) bool { return msg["status"] == status }
+}
+
+// hasError matches error replies.
+func hasError(msg map[string]interface{}) bool {
+	_, ok := msg["error"]
+	return ok
+}
+
+// isState matches game state broadcasts.
+func isState(msg map[string]interface{}) bool {
+	_, ok := msg["fen"]
+	return ok
+}
+
+// startTestGame creates a game, seats two players and has both say they
+// are ready, returning the players by color once it has started.
+func startTestGame(t testing.TB, srv *httptest.Server) (white, black *websocket.Conn, gameID string) {
+	t.Helper()
+	return startTestGameFrom(t, srv, "")
+}
+
+// startTestGameFrom is startTestGame for a game starting from fen, or
+// from the standard position if fen is empty.
+func startTestGameFrom(t testing.TB, srv *httptest.Server, fen string) (white, black *websocket.Conn, gameID string) {
+	t.Helper()
+	creator, joiner := dial(t, srv), dial(t, srv)
+	create := map[string]string{"action": "create"}
+	if fen != "" {
+		create["fen"] = fen
+	}
+	send(t, creator, create)
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID = created["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		readUntil(t, ws, isState)
+	}
+	if created["color"] == "w" {
+		return creator, joiner, gameID
+	}
+	return joiner, creator, gameID
+}
+
+// play makes each move in turn, alternating between the players from
+// white, and waits for the state broadcast after each.
+func play(t testing.TB, white, black *websocket.Conn, gameID string, moves ...string) map[string]interface{} {
+	t.Helper()
+	var state map[string]interface{}
+	for i, move := range moves {
+		mover := white
+		if i%2 == 1 {
+			mover = black
+		}
+		send(t, mover, map[string]string{"action": "move", "gameID": gameID, "move": move})
+		state = readUntil(t, white, func(msg map[string]interface{}) bool {
+			if hasError(msg) {
+				t.Fatalf("playing %s: %v", move, msg["error"])
+			}
+			return isState(msg) && msg["moveCount"] == float64(i+1)
+		})
+	}
+	return state
+}
+
+// serverConns returns n server-side WebSocket connections with their
+// writers running, as handleConnections leaves them, whose clients read
+// and discard everything they are sent.
+func serverConns(t testing.TB, n int) []*websocket.Conn {
+	t.Helper()
+	accepted := make(chan *websocket.Conn)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		startWriter(ws)
+		accepted <- ws
+	}))
+	t.Cleanup(srv.Close)
+
+	conns := make([]*websocket.Conn, n)
+	for i := range conns {
+		client := dial(t, srv)
+		go func() {
+			for {
+				if _, _, err := client.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		ws := <-accepted
+		t.Cleanup(func() {
+			stopWriter(ws)
+			ws.Close()
+		})
+		conns[i] = ws
+	}
+	return conns
+}
+
+// dialAuthenticated opens a WebSocket to srv with a token for subject,
+// signed with the configured JWT secret.
+// testClaims are claims for subject, shown as name, that expire at expires.
+func testClaims(subject, name string, expires time.Time) Claims {
+	return Claims{Name: name, RegisteredClaims: jwt.RegisteredClaims{Subject: subject, ExpiresAt: jwt.NewNumericDate(expires)}}
+}
+
+func dialAuthenticated(t testing.TB, srv *httptest.Server, subject string) *websocket.Conn {
+	t.Helper()
+	token, err := signToken(testClaims(subject, "", time.Now().Add(time.Minute)), config.JWTSecret)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return dialWith(t, srv, http.Header{"Authorization": {"Bearer " + token}})
+}
+
+// gameFromFEN starts a game from fen.
+func gameFromFEN(t testing.TB, fen string) *chess.Game {
+	t.Helper()
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", fen, err)
+	}
+	return chess.NewGame(opt)
+}
+
+// waitForHeldSeat waits for the server to notice that color's player in
+// gameID has dropped out and hold their seat.
+func waitForHeldSeat(t testing.TB, gameID string, color chess.Color) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		gamesMutex.RLock()
+		game := games[gameID]
+		game.Lock()
+		held := opponent(game, color.Other()).Conn == nil
+		game.Unlock()
+		gamesMutex.RUnlock()
+		if held {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the %s seat was not held", colorName(color))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+type MaterialImbalance struct {
+	Imbalance  string `json:"imbalance"`
+	Favors     string `json:"favors"`
+	Conditions string `json:"conditions"`
+	Score      string `json:"score"`
+	Advice     string `json:"advice"`
+}
+
+// imbalanceVerdict scores an imbalance in pawns for the side holding the
+// first-named material.
+type imbalanceVerdict struct {
+	score      float64
+	conditions string
+	advice     string
+}
+
+// imbalanceRule picks between two verdicts depending on whether suits
+// reports that the position suits the first-named material.
+type imbalanceRule struct {
+	suits    func(pos *chess.Position) bool
+	verdicts [2]imbalanceVerdict
+}
+
+// imbalanceRules follows Silman's reading of the standard imbalances.
+var imbalanceRules = map[string]imbalanceRule{
+	"two_bishops_vs_bishop_knight": {isOpenPosition, [2]imbalanceVerdict{
+		{0.35, "Open position favors the two bishops", "Open the position with pawn exchanges to activate your bishop pair"},
+		{0.10, "Closed pawn chains blunt the bishop pair", "Prepare a pawn break before the knight settles on an outpost"},
+	}},
+	"bishop_vs_knight": {isOpenPosition, [2]imbalanceVerdict{
+		{0.20, "Open lines favor the bishop over the knight", "Trade pawns and keep play on both wings where the bishop is fastest"},
+		{-0.20, "Closed position favors the knight over the bishop", "Find the knight a protected outpost and fix enemy pawns on the bishop's colour"},
+	}},
+	"rook_vs_two_minors": {isEndgamePosition, [2]imbalanceVerdict{
+		{0.25, "With few pieces left the rook outpaces the minor pieces", "Trade the remaining pieces and create a passed pawn for the rook to support"},
+		{-0.50, "Two minor pieces coordinate better than a rook in the middlegame", "Keep pieces on and attack with the minor pieces before the rook finds open files"},
+	}},
+	"rook_vs_minor_piece": {isOpenPosition, [2]imbalanceVerdict{
+		{2.00, "Open files let the extra exchange tell", "Double rooks on an open file and trade into an endgame"},
+		{1.50, "A closed position limits the rook", "Open a file for the rook; the minor piece can hold a blocked position"},
+	}},
+	"queen_vs_rook_minor": {isOpenPosition, [2]imbalanceVerdict{
+		{0.50, "An open position lets the queen hunt loose pieces and the king", "Look for double attacks on undefended pieces and an exposed king"},
+		{0.00, "A closed position lets the rook and minor piece defend each other", "Create a second weakness before the pieces can coordinate"},
+	}},
+	"queen_vs_two_rooks": {isOpenPosition, [2]imbalanceVerdict{
+		{0.25, "An open position gives the queen targets for forks", "Attack undefended pawns and pieces so the rooks cannot double"},
+		{-0.50, "Two rooks dominate when they can double on files", "Double the rooks on a file and target pinned or fixed pawns"},
+	}},
+}
+
+// isOpenPosition reports whether at most two pawns remain on the d and e
+// files, which frees the long diagonals and central files.
+func isOpenPosition(pos *chess.Position) bool {
+	central := 0
+	for sq, piece := range pos.Board().SquareMap() {
+		if piece.Type() == chess.Pawn && (sq.File() == chess.FileD || sq.File() == chess.FileE) {
+			central++
+		}
+	}
+	return central <= 2
+}
+
+func isEndgamePosition(pos *chess.Position) bool {
+	return gamePhase(pos) == "endgame"
+}
+
+// imbalanceName names the imbalance between a and b, reporting whether a
+// holds the first-named material.
+func imbalanceName(a, b MaterialCount) (string, bool) {
+	for _, first := range []bool{true, false} {
+		x, y := a, b
+		if !first {
+			x, y = b, a
+		}
+		dQ, dR := x.Queen-y.Queen, x.Rooks-y.Rooks
+		dB, dN := x.Bishops-y.Bishops, x.Knights-y.Knights
+		switch {
+		case dQ == 0 && dR == 0 && dB == 1 && dN == -1 && x.Bishops == 2:
+			return "two_bishops_vs_bishop_knight", first
+		case dQ == 0 && dR == 0 && dB == 1 && dN == -1:
+			return "bishop_vs_knight", first
+		case dQ == 0 && dR == 1 && dB+dN == -2:
+			return "rook_vs_two_minors", first
+		case dQ == 0 && dR == 1 && dB+dN == -1:
+			return "rook_vs_minor_piece", first
+		case dQ == 1 && dR == -1 && dB+dN == -1:
+			return "queen_vs_rook_minor", first
+		case dQ == 1 && dR == -2 && dB+dN == 0:
+			return "queen_vs_two_rooks", first
+		}
+	}
+	return "", false
+}
+
+func evaluateImbalance(pos *chess.Position) MaterialImbalance {
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	name, whiteFirst := imbalanceName(white, black)
+	rule, ok := imbalanceRules[name]
+	if !ok {
+		return MaterialImbalance{
+			Imbalance:  "none",
+			Favors:     "neither",
+			Conditions: "The pieces are matched one for one",
+			Score:      "0.00",
+			Advice:     "Look to other imbalances such as pawn structure, space and development",
+		}
+	}
+
+	verdict := rule.verdicts[1]
+	if rule.suits(pos) {
+		verdict = rule.verdicts[0]
+	}
+	score := verdict.score
+	if !whiteFirst {
+		score = -score
+	}
+	favors := "neither"
+	if score > 0 {
+		favors = "white"
+	} else if score < 0 {
+		favors = "black"
+	}
+	return MaterialImbalance{
+		Imbalance:  name,
+		Favors:     favors,
+		Conditions: verdict.conditions,
+		Score:      fmt.Sprintf("%+.2f", score),
+		Advice:     verdict.advice,
+	}
+}
+
+func computeMaterialImbalanceEvaluation(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, evaluateImbalance(pos))
+	logger.Debug("Material imbalance evaluation sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rohit746/chess/backend/testutil"
+)
+
+// startClientGame seats two test clients in a new untimed game and starts
+// it, returning them by color.
+func startClientGame(t *testing.T, creator, joiner *testutil.TestClient) (white, black *testutil.TestClient, gameID string) {
+	t.Helper()
+	created := creator.CreateGame(testutil.Untimed)
+	joiner.JoinGame(created.GameID)
+	creator.Ready(created.GameID)
+	joiner.Ready(created.GameID)
+	creator.WaitForState()
+	joiner.WaitForState()
+	if created.Color == "w" {
+		return creator, joiner, created.GameID
+	}
+	return joiner, creator, created.GameID
+}
+
+func TestGetPGN(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+
+	creator, joiner := testutil.Dial(t, srv.URL), testutil.Dial(t, srv.URL)
+	creator.Name, joiner.Name = "Alice", "Bob"
+	white, black, gameID := startClientGame(t, creator, joiner)
+	for i, move := range []string{"e4", "e5", "Nf3", "Nc6"} {
+		[]*testutil.TestClient{white, black}[i%2].MakeMove(gameID, move)
+	}
+
+	black.Send(map[string]string{"action": "getPGN", "gameID": gameID})
+	pgn := black.WaitFor(func(msg map[string]interface{}) bool { return msg["pgn"] != nil })["pgn"].(string)
+	for _, tag := range []string{
+		`[Event "Casual game"]`,
+		`[White "` + white.Name + `"]`,
+		`[Black "` + black.Name + `"]`,
+		`[Result "*"]`,
+	} {
+		if !strings.Contains(pgn, tag) {
+			t.Errorf("PGN lacks %s:\n%s", tag, pgn)
+		}
+	}
+	_, movetext, _ := strings.Cut(pgn, "\n\n")
+	var moves []string
+	for _, field := range strings.Fields(movetext) {
+		if !strings.HasSuffix(field, ".") && !strings.HasPrefix(field, "{") && !strings.HasSuffix(field, "}") && field != "*" {
+			moves = append(moves, field)
+		}
+	}
+	if got := strings.Join(moves, " "); got != "e4 e5 Nf3 Nc6" {
+		t.Errorf("PGN moves are %q, want %q:\n%s", got, "e4 e5 Nf3 Nc6", pgn)
+	}
+
+	black.Send(map[string]string{"action": "getPGN", "gameID": "missing"})
+	if msg := black.WaitFor(hasError); msg["error"] != "game not found" {
+		t.Errorf("unknown game: got %v", msg)
+	}
+}
+
+// TestGetPGNHidesPlayerIDs checks that the PGN leaves out the player IDs,
+// which are the secrets that reclaim a seat.
+func TestGetPGNHidesPlayerIDs(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+
+	creator, joiner := testutil.Dial(t, srv.URL), testutil.Dial(t, srv.URL)
+	created := creator.CreateGame(testutil.Untimed)
+	joined := joiner.JoinGame(created.GameID)
+	joiner.Send(map[string]string{"action": "getPGN", "gameID": created.GameID})
+	pgn := joiner.WaitFor(func(msg map[string]interface{}) bool { return msg["pgn"] != nil })["pgn"].(string)
+	for _, id := range []string{created.PlayerID, joined.PlayerID} {
+		if id == "" || strings.Contains(pgn, id) {
+			t.Errorf("PGN exposes player ID %q:\n%s", id, pgn)
+		}
+	}
+}
+
+func TestStateMoveHistory(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+
+	white, black, gameID := startClientGame(t, testutil.Dial(t, srv.URL), testutil.Dial(t, srv.URL))
+	white.MakeMove(gameID, "e2e4")
+	state := black.WaitForState()
+	if len(state.Moves) != 1 || state.Moves[0] != "e4" {
+		t.Errorf("moves after e2e4 = %v, want [e4]", state.Moves)
+	}
+	if state.MoveCount != 1 || state.LastMove != "e4" {
+		t.Errorf("moveCount = %v and lastMove = %v after e2e4, want 1 and e4", state.MoveCount, state.LastMove)
+	}
+
+	// The move that ends the game is listed too.
+	white, black, gameID = startClientGame(t, testutil.Dial(t, srv.URL), testutil.Dial(t, srv.URL))
+	for i, move := range []string{"f3", "e5", "g4"} {
+		[]*testutil.TestClient{white, black}[i%2].MakeMove(gameID, move)
+	}
+	state = black.MakeMove(gameID, "Qh4#")
+	if state.Status != "checkmate" || len(state.Moves) != 4 || state.Moves[3] != "Qh4#" || state.LastMove != "Qh4#" {
+		t.Errorf("state after Qh4# = status %v, moves %v, lastMove %v", state.Status, state.Moves, state.LastMove)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type KingSafety struct {
+	Score      int    `json:"score"`
+	Shield     string `json:"shield"`
+	OpenFiles  int    `json:"openFiles"`
+	Commentary string `json:"commentary,omitempty"`
+}
+
+// evaluateKingSafety scores the safety of color's king from 0 (exposed) to
+// 100 (safe) using its pawn shield, the open files around it and the number
+// of enemy attacks on the squares next to it.
+func evaluateKingSafety(board *chess.Board, color chess.Color) KingSafety {
+	king := analysis.KingSquare(board, color)
+	if king == chess.NoSquare {
+		return KingSafety{}
+	}
+	forward := 1
+	if color == chess.Black {
+		forward = -1
+	}
+
+	shield := 0
+	for df := -1; df <= 1; df++ {
+		for dr := 1; dr <= 2; dr++ {
+			sq, ok := analysis.OffsetSquare(king, df, dr*forward)
+			if !ok {
+				continue
+			}
+			if p := board.Piece(sq); p.Type() == chess.Pawn && p.Color() == color {
+				shield++
+				break
+			}
+		}
+	}
+
+	own := analysis.PawnFiles(board, color)
+	enemy := analysis.PawnFiles(board, color.Other())
+	openFiles, halfOpenFiles := 0, 0
+	exposedFile := -1
+	for f := int(king.File()) - 1; f <= int(king.File())+1; f++ {
+		if f < 0 || f > 7 || own[f] > 0 {
+			continue
+		}
+		if enemy[f] == 0 {
+			openFiles++
+		} else {
+			halfOpenFiles++
+		}
+		if exposedFile < 0 || f == int(king.File()) {
+			exposedFile = f
+		}
+	}
+
+	attacks := analysis.AttackCounts(board, color.Other())
+	zoneAttacks := attacks[king]
+	for _, o := range analysis.KingOffsets {
+		if sq, ok := analysis.OffsetSquare(king, o[0], o[1]); ok {
+			zoneAttacks += attacks[sq]
+		}
+	}
+
+	// The shield only matters while the king sits near its own back rank.
+	missingShield := 0
+	if analysis.RelativeRank(king, color) <= 1 {
+		missingShield = 3 - shield
+	}
+	score := 100 - 15*missingShield - 15*openFiles - 8*halfOpenFiles - 5*zoneAttacks
+	if score < 0 {
+		score = 0
+	}
+
+	safety := KingSafety{Score: score, OpenFiles: openFiles}
+	switch {
+	case shield == 3:
+		safety.Shield = "intact"
+	case shield == 2:
+		safety.Shield = "weakened"
+	default:
+		safety.Shield = "broken"
+	}
+	if score < 50 {
+		name := color.Name()
+		if exposedFile >= 0 {
+			safety.Commentary = fmt.Sprintf("%s king is exposed on the %s-file", name, chess.File(exposedFile))
+		} else {
+			safety.Commentary = fmt.Sprintf("%s king is under pressure", name)
+		}
+	}
+	return safety
+}
+
+func getKingSafety(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	board := pos.Board()
+	sendJSON(ws, map[string]KingSafety{
+		"white": evaluateKingSafety(board, chess.White),
+		"black": evaluateKingSafety(board, chess.Black),
+	})
+	logger.Debug("King safety sent", slog.String("gameID", gameID))
+}
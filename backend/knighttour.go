@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+var errNoKnightTour = errors.New("no knight's tour found")
+
+// knightTour returns a sequence visiting all 64 squares once by knight moves
+// from start, using Warnsdorff's rule: always jump to the square with the
+// fewest onward moves. Ties go to the square furthest from the centre, which
+// keeps the knight on the rim while it can still get back.
+func knightTour(start chess.Square) ([]chess.Square, error) {
+	var visited [64]bool
+	degree := func(sq chess.Square) int {
+		n := 0
+		for _, o := range analysis.KnightOffsets {
+			if next, ok := analysis.OffsetSquare(sq, o[0], o[1]); ok && !visited[next] {
+				n++
+			}
+		}
+		return n
+	}
+	centreDistance := func(sq chess.Square) int {
+		df, dr := 2*int(sq.File())-7, 2*int(sq.Rank())-7
+		return df*df + dr*dr
+	}
+
+	tour := []chess.Square{start}
+	visited[start] = true
+	for current := start; len(tour) < 64; {
+		best, bestDegree := chess.NoSquare, 9
+		for _, o := range analysis.KnightOffsets {
+			next, ok := analysis.OffsetSquare(current, o[0], o[1])
+			if !ok || visited[next] {
+				continue
+			}
+			d := degree(next)
+			if d < bestDegree || (d == bestDegree && centreDistance(next) > centreDistance(best)) {
+				best, bestDegree = next, d
+			}
+		}
+		if best == chess.NoSquare {
+			return nil, errNoKnightTour
+		}
+		visited[best] = true
+		tour = append(tour, best)
+		current = best
+	}
+	return tour, nil
+}
+
+func computeKnightTour(ws *websocket.Conn, startSquare string) {
+	start, ok := parseSquare(startSquare)
+	if !ok {
+		sendJSON(ws, map[string]string{"error": "invalid start square"})
+		return
+	}
+	tour, err := knightTour(start)
+	if err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		logger.Warn("Knight's tour failed", slog.String("square", startSquare))
+		return
+	}
+	squares := make([]string, len(tour))
+	for i, sq := range tour {
+		squares[i] = sq.String()
+	}
+	sendJSON(ws, map[string]interface{}{"tour": squares})
+}
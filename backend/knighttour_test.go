@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// knightTourBudget is how long a tour may take to compute.
+const knightTourBudget = 100 * time.Millisecond
+
+func TestKnightTourFromEverySquare(t *testing.T) {
+	for start := chess.A1; start <= chess.H8; start++ {
+		began := time.Now()
+		tour, err := knightTour(start)
+		if elapsed := time.Since(began); elapsed > knightTourBudget {
+			t.Errorf("tour from %s took %v, over %v", start, elapsed, knightTourBudget)
+		}
+		if err != nil {
+			t.Errorf("tour from %s: %v", start, err)
+			continue
+		}
+		if len(tour) != 64 || tour[0] != start {
+			t.Errorf("tour from %s has %d squares starting at %s", start, len(tour), tour[0])
+			continue
+		}
+		var visits [64]int
+		for i, sq := range tour {
+			visits[sq]++
+			if i > 0 && !isKnightMove(tour[i-1], sq) {
+				t.Errorf("tour from %s: %s to %s is not a knight move", start, tour[i-1], sq)
+			}
+		}
+		for sq, n := range visits {
+			if n != 1 {
+				t.Errorf("tour from %s visits %s %d times", start, chess.Square(sq), n)
+			}
+		}
+	}
+}
+
+func isKnightMove(from, to chess.Square) bool {
+	df := int(to.File()) - int(from.File())
+	dr := int(to.Rank()) - int(from.Rank())
+	return df*df+dr*dr == 5
+}
+
+func TestComputeKnightTour(t *testing.T) {
+	useConfig(t, nil)
+	ws := dial(t, newTestServer(t))
+
+	began := time.Now()
+	send(t, ws, map[string]string{"action": "compute_knight_tour", "startSquare": "a1"})
+	reply := readUntil(t, ws, func(msg map[string]interface{}) bool { return msg["tour"] != nil })
+	if elapsed := time.Since(began); elapsed > knightTourBudget {
+		t.Errorf("reply took %v, over %v", elapsed, knightTourBudget)
+	}
+	tour := reply["tour"].([]interface{})
+	if len(tour) != 64 || tour[0] != "a1" {
+		t.Errorf("got a tour of %d squares from %v, want 64 from a1", len(tour), tour[0])
+	}
+
+	send(t, ws, map[string]string{"action": "compute_knight_tour", "startSquare": "z9"})
+	if reply := readUntil(t, ws, hasError); reply["error"] != "invalid start square" {
+		t.Errorf("bad square: got %v", reply["error"])
+	}
+}
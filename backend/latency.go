@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	latencies      = make(map[*websocket.Conn]time.Duration)
+	latenciesMutex sync.Mutex
+)
+
+// recordLatency stores the one-way latency of ws, measured as half the
+// round trip of its latest heartbeat ping.
+func recordLatency(ws *websocket.Conn, latency time.Duration) {
+	latenciesMutex.Lock()
+	latencies[ws] = latency
+	latenciesMutex.Unlock()
+}
+
+func forgetLatency(ws *websocket.Conn) {
+	latenciesMutex.Lock()
+	delete(latencies, ws)
+	latenciesMutex.Unlock()
+}
+
+// moveDelay returns how long to hold a move from ws so that it arrives as if
+// sent over a link with the configured target latency. It is zero unless the game was created
+// with applyMoveDelay.
+func moveDelay(ws *websocket.Conn, gameID string) time.Duration {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	enabled := exists && game.ApplyMoveDelay
+	gamesMutex.Unlock()
+	if !enabled {
+		return 0
+	}
+
+	latenciesMutex.Lock()
+	latency := latencies[ws]
+	latenciesMutex.Unlock()
+
+	if delay := config.TargetLatency - latency; delay > 0 {
+		return delay
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultLeaderboardRadius is how many players either side of the
+	// requested one are shown when no radius is given, and
+	// maxLeaderboardRadius the most that may be asked for.
+	defaultLeaderboardRadius = 5
+	maxLeaderboardRadius     = 25
+)
+
+// LeaderboardEntry is a rated player's place on the leaderboard. IsMe
+// marks the player the leaderboard was centred on.
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	PlayerID string `json:"playerID"`
+	Rating   int    `json:"rating"`
+	IsMe     bool   `json:"isMe,omitempty"`
+}
+
+// leaderboardNearby ranks every rated player, highest rating first with
+// ties in player ID order, and returns playerID's entry with up to radius
+// players either side of it. It reports false if playerID has no rating.
+func leaderboardNearby(playerID string, radius int) ([]LeaderboardEntry, bool) {
+	ratingsMutex.Lock()
+	ranked := make([]LeaderboardEntry, 0, len(ratings))
+	for id, rating := range ratings {
+		ranked = append(ranked, LeaderboardEntry{PlayerID: id, Rating: rating})
+	}
+	ratingsMutex.Unlock()
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Rating != ranked[j].Rating {
+			return ranked[i].Rating > ranked[j].Rating
+		}
+		return ranked[i].PlayerID < ranked[j].PlayerID
+	})
+	at := -1
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+		if ranked[i].PlayerID == playerID {
+			ranked[i].IsMe = true
+			at = i
+		}
+	}
+	if at < 0 {
+		return nil, false
+	}
+	return ranked[max(at-radius, 0):min(at+radius+1, len(ranked))], true
+}
+
+// getLeaderboardNearby sends ws the players ranked around playerID, or
+// around ws's own account if no playerID is given.
+func getLeaderboardNearby(ws *websocket.Conn, playerID, radiusStr string) {
+	if playerID == "" {
+		identitiesMutex.Lock()
+		playerID = identities[ws].Subject
+		identitiesMutex.Unlock()
+	}
+	if playerID == "" {
+		sendJSON(ws, map[string]string{"error": "playerID required"})
+		return
+	}
+	radius := defaultLeaderboardRadius
+	if radiusStr != "" {
+		r, err := strconv.Atoi(radiusStr)
+		if err != nil || r < 0 || r > maxLeaderboardRadius {
+			sendJSON(ws, map[string]string{"error": "invalid radius"})
+			return
+		}
+		radius = r
+	}
+
+	entries, ok := leaderboardNearby(playerID, radius)
+	if !ok {
+		sendJSON(ws, map[string]string{"error": "player not rated"})
+		return
+	}
+	sendJSON(ws, map[string]interface{}{"playerID": playerID, "leaderboard": entries})
+	logger.Debug("Nearby leaderboard sent", slog.String("playerID", playerID), slog.Int("entries", len(entries)))
+}
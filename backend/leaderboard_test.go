@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// useRatings runs the test with only the given ratings.
+func useRatings(t *testing.T, set map[string]int) {
+	t.Helper()
+	ratingsMutex.Lock()
+	saved := ratings
+	ratings = set
+	ratingsMutex.Unlock()
+	t.Cleanup(func() {
+		ratingsMutex.Lock()
+		ratings = saved
+		ratingsMutex.Unlock()
+	})
+}
+
+func TestLeaderboardNearby(t *testing.T) {
+	set := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		set[fmt.Sprintf("p%02d", i)] = 2000 - 10*i
+	}
+	set["tied"] = 1900
+	useRatings(t, set)
+
+	entries, ok := leaderboardNearby("p10", 2)
+	if !ok || len(entries) != 5 {
+		t.Fatalf("leaderboardNearby(p10, 2) = %v, %v, want five entries", entries, ok)
+	}
+	// p10 and tied share 1900; the tie goes by player ID.
+	want := []LeaderboardEntry{
+		{Rank: 9, PlayerID: "p08", Rating: 1920},
+		{Rank: 10, PlayerID: "p09", Rating: 1910},
+		{Rank: 11, PlayerID: "p10", Rating: 1900, IsMe: true},
+		{Rank: 12, PlayerID: "tied", Rating: 1900},
+		{Rank: 13, PlayerID: "p11", Rating: 1890},
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	// Near the top and bottom, fewer players are shown on that side.
+	if entries, _ := leaderboardNearby("p00", 3); len(entries) != 4 || !entries[0].IsMe || entries[0].Rank != 1 {
+		t.Errorf("leaderboardNearby(p00, 3) = %+v, want p00 first of four", entries)
+	}
+	if entries, _ := leaderboardNearby("p19", 3); len(entries) != 4 || !entries[3].IsMe || entries[3].Rank != 21 {
+		t.Errorf("leaderboardNearby(p19, 3) = %+v, want p19 last of four", entries)
+	}
+	if _, ok := leaderboardNearby("unrated", 3); ok {
+		t.Error("found an unrated player on the leaderboard")
+	}
+}
+
+func TestGetLeaderboardNearby(t *testing.T) {
+	useConfig(t, func(c *Config) { c.JWTSecret = []byte("test secret") })
+	useRatings(t, map[string]int{"alice": 1495, "bob": 1510, "carol": 1300})
+	srv := newTestServer(t)
+
+	ws := dialAuthenticated(t, srv, "alice")
+	send(t, ws, map[string]string{"action": "get_leaderboard_nearby", "radius": "1"})
+	msg := readUntil(t, ws, func(msg map[string]interface{}) bool { return hasError(msg) || msg["leaderboard"] != nil })
+	entries, _ := msg["leaderboard"].([]interface{})
+	if msg["playerID"] != "alice" || len(entries) != 3 {
+		t.Fatalf("own leaderboard = %v, want alice among three", msg)
+	}
+	if me := entries[1].(map[string]interface{}); me["playerID"] != "alice" || me["rank"] != float64(2) || me["isMe"] != true {
+		t.Errorf("own entry = %v, want alice ranked 2", me)
+	}
+
+	guest := dial(t, srv)
+	for _, tt := range []struct{ playerID, radius, want string }{
+		{"", "", "playerID required"},
+		{"nobody", "", "player not rated"},
+		{"bob", "-1", "invalid radius"},
+		{"bob", "1000", "invalid radius"},
+	} {
+		send(t, guest, map[string]string{"action": "get_leaderboard_nearby", "playerID": tt.playerID, "radius": tt.radius})
+		if msg := readUntil(t, guest, hasError); msg["error"] != tt.want {
+			t.Errorf("playerID %q radius %q: got %v, want %s", tt.playerID, tt.radius, msg["error"], tt.want)
+		}
+	}
+}
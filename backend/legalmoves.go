@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// legalMoves lists the destinations of the legal moves from square, or
+// every legal move as "from-to" when square is empty. Promotions to
+// different pieces share one entry.
+func legalMoves(pos *chess.Position, square chess.Square) []string {
+	moves := []string{}
+	seen := map[string]bool{}
+	for _, m := range pos.ValidMoves() {
+		var entry string
+		switch {
+		case square == chess.NoSquare:
+			entry = m.S1().String() + "-" + m.S2().String()
+		case m.S1() == square:
+			entry = m.S2().String()
+		default:
+			continue
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			moves = append(moves, entry)
+		}
+	}
+	return moves
+}
+
+// getLegalMoves answers a player, coach or spectator of the game with the
+// legal moves from squareName, or from every square if it is empty.
+// Players may not ask about their opponent's pieces.
+func getLegalMoves(ws *websocket.Conn, gameID, squareName string) {
+	square := chess.NoSquare
+	if squareName != "" {
+		var ok bool
+		if square, ok = parseSquare(squareName); !ok {
+			sendJSON(ws, map[string]string{"error": "invalid square"})
+			return
+		}
+	}
+
+	gamesMutex.RLock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.RUnlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to query legal moves of non-existent game", slog.String("gameID", gameID))
+		return
+	}
+	game.Lock()
+	player := getPlayer(ws, game)
+	member := player != nil || isSpectator(ws, game) || isGameCoach(ws, game)
+	pos := game.Game.Position()
+	game.Unlock()
+	gamesMutex.RUnlock()
+
+	if !member {
+		sendJSON(ws, map[string]string{"error": "not in this game"})
+		return
+	}
+	if player != nil && square != chess.NoSquare {
+		if piece := pos.Board().Piece(square); piece != chess.NoPiece && piece.Color() != player.Color {
+			sendJSON(ws, map[string]string{"error": "not your turn"})
+			return
+		}
+	}
+	sendJSON(ws, map[string][]string{"legalMoves": legalMoves(pos, square)})
+}
@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func BenchmarkLegalMoves(b *testing.B) {
+	gameID, game := benchGame(b, 0)
+	white := game.Players[0].Conn
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getLegalMoves(white, gameID, "")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+func TestLegalMoves(t *testing.T) {
+	start := chess.NewGame().Position()
+	pinned := gameFromFEN(t, "k3r3/8/8/8/8/8/4N3/4K3 w - - 0 1").Position()
+	for _, c := range []struct {
+		name   string
+		pos    *chess.Position
+		square chess.Square
+		want   []string
+	}{
+		{"pawn in the starting position", start, chess.E2, []string{"e3", "e4"}},
+		{"knight in the starting position", start, chess.G1, []string{"f3", "h3"}},
+		{"pinned knight", pinned, chess.E2, []string{}},
+		{"empty square", start, chess.E4, []string{}},
+	} {
+		got := legalMoves(c.pos, c.square)
+		slices.Sort(got)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: legal moves = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	all := legalMoves(start, chess.NoSquare)
+	if len(all) != 20 {
+		t.Errorf("the starting position has %d legal moves, want 20: %v", len(all), all)
+	}
+	for _, want := range []string{"e2-e4", "g1-f3"} {
+		if !slices.Contains(all, want) {
+			t.Errorf("legal moves %v are missing %s", all, want)
+		}
+	}
+}
+
+func TestGetLegalMoves(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+	spectator := dial(t, srv)
+	send(t, spectator, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, spectator, hasStatus("spectating"))
+	outsider := dial(t, srv)
+
+	query := func(ws *websocket.Conn, square string) map[string]interface{} {
+		t.Helper()
+		send(t, ws, map[string]string{"action": "getLegalMoves", "gameID": gameID, "square": square})
+		return readUntil(t, ws, func(msg map[string]interface{}) bool {
+			return hasError(msg) || msg["legalMoves"] != nil
+		})
+	}
+
+	if reply := query(white, "e2"); len(reply["legalMoves"].([]interface{})) != 2 {
+		t.Errorf("white's moves from e2: %v", reply)
+	}
+	if reply := query(white, "e7"); reply["error"] != "not your turn" {
+		t.Errorf("white asking about e7: %v", reply)
+	}
+	if reply := query(spectator, "e7"); !reflect.DeepEqual(reply["legalMoves"], []interface{}{}) {
+		t.Errorf("spectator asking about e7 with white to move: %v", reply)
+	}
+	if reply := query(white, "e4"); !reflect.DeepEqual(reply["legalMoves"], []interface{}{}) {
+		t.Errorf("white asking about an empty square: %v", reply)
+	}
+	if reply := query(white, ""); len(reply["legalMoves"].([]interface{})) != 20 {
+		t.Errorf("white's moves in the starting position: %v", reply)
+	}
+	if reply := query(white, "z9"); reply["error"] != "invalid square" {
+		t.Errorf("asking about z9: %v", reply)
+	}
+	if reply := query(outsider, "e2"); reply["error"] != "not in this game" {
+		t.Errorf("outsider asking: %v", reply)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// maxLessonPositions and maxLessonStudents bound the size of a lesson.
+	maxLessonPositions = 50
+	maxLessonStudents  = 100
+	// lessonMaterialGain is how many centipawns a student must be up on the
+	// start of a "win material" position, after the server's reply.
+	lessonMaterialGain = 200
+)
+
+// lessonGoal is what a lesson objective asks the student to do.
+type lessonGoal int
+
+const (
+	goalCheckmate lessonGoal = iota + 1
+	goalPromote
+	goalWinMaterial
+)
+
+// parseObjective reads the goal from the wording of an objective, such as
+// "Mate in two" or "Promote the pawn". The server has no engine, so only
+// goals it can check from the moves themselves are accepted.
+func parseObjective(objective string) (lessonGoal, bool) {
+	text := strings.ToLower(objective)
+	switch {
+	case strings.Contains(text, "stalemate"):
+		return 0, false
+	case strings.Contains(text, "mate"):
+		return goalCheckmate, true
+	case strings.Contains(text, "promot"), strings.Contains(text, "queen the"):
+		return goalPromote, true
+	case strings.Contains(text, "win") && (strings.Contains(text, "material") || strings.Contains(text, "piece") ||
+		strings.Contains(text, "exchange") || strings.Contains(text, "queen") || strings.Contains(text, "rook")):
+		return goalWinMaterial, true
+	}
+	return 0, false
+}
+
+// LessonPosition is one exercise of a lesson. The student plays the side
+// to move in FEN, and the server answers with random legal moves.
+type LessonPosition struct {
+	FEN       string `json:"fen"`
+	Objective string `json:"objective"`
+	goal      lessonGoal
+}
+
+// StudentProgress is how far a student has got through a lesson. Position
+// is the index of the exercise being played, Moves the student's moves in
+// it so far, and Failures the attempts that ended without meeting an
+// objective.
+type StudentProgress struct {
+	Name      string   `json:"name"`
+	Position  int      `json:"position"`
+	Moves     []string `json:"moves"`
+	Completed int      `json:"completed"`
+	Failures  int      `json:"failures"`
+	Finished  bool     `json:"finished"`
+
+	conn  *websocket.Conn
+	game  *chess.Game
+	start int
+}
+
+// Lesson is a set of positions a coach has students work through. The
+// coach is sent each student's progress as it changes.
+type Lesson struct {
+	ID          string
+	Title       string
+	Positions   []LessonPosition
+	MaxStudents int
+	Coach       *websocket.Conn
+	Students    []*StudentProgress
+}
+
+var (
+	lessons      = make(map[string]*Lesson)
+	lessonsMutex sync.Mutex
+)
+
+func (l *Lesson) student(ws *websocket.Conn) *StudentProgress {
+	for _, s := range l.Students {
+		if s.conn == ws {
+			return s
+		}
+	}
+	return nil
+}
+
+func createLesson(ws *websocket.Conn, msg map[string]string) {
+	if !isCoach(msg["coachToken"]) {
+		sendJSON(ws, map[string]string{"error": "unauthorized"})
+		logger.Warn("Unauthorized lesson creation attempt")
+		return
+	}
+	title := strings.TrimSpace(msg["title"])
+	if title == "" {
+		sendJSON(ws, map[string]string{"error": "title required"})
+		return
+	}
+	var positions []LessonPosition
+	if err := json.Unmarshal([]byte(msg["positions"]), &positions); err != nil || len(positions) == 0 {
+		sendJSON(ws, map[string]string{"error": "positions required"})
+		return
+	}
+	if len(positions) > maxLessonPositions {
+		sendJSON(ws, map[string]string{"error": "too many positions"})
+		return
+	}
+	for i := range positions {
+		prefix := "position " + strconv.Itoa(i+1) + ": "
+		opt, err := chess.FEN(positions[i].FEN)
+		if err != nil {
+			sendJSON(ws, map[string]string{"error": prefix + "invalid FEN: " + err.Error()})
+			return
+		}
+		pos := chess.NewGame(opt).Position()
+		if err := checkPosition(pos.Board(), pos.Turn()); err != nil {
+			sendJSON(ws, map[string]string{"error": prefix + "invalid FEN: " + err.Error()})
+			return
+		}
+		if pos.Status() != chess.NoMethod {
+			sendJSON(ws, map[string]string{"error": prefix + "the game is already over"})
+			return
+		}
+		goal, ok := parseObjective(positions[i].Objective)
+		if !ok {
+			sendJSON(ws, map[string]string{"error": prefix + "unsupported objective"})
+			return
+		}
+		positions[i].goal = goal
+	}
+	maxStudents, err := strconv.Atoi(msg["maxStudents"])
+	if err != nil || maxStudents < 1 || maxStudents > maxLessonStudents {
+		sendJSON(ws, map[string]string{"error": "maxStudents must be between 1 and " + strconv.Itoa(maxLessonStudents)})
+		return
+	}
+
+	lesson := &Lesson{
+		ID:          ksuid.New().String(),
+		Title:       title,
+		Positions:   positions,
+		MaxStudents: maxStudents,
+		Coach:       ws,
+	}
+	lessonsMutex.Lock()
+	lessons[lesson.ID] = lesson
+	lessonsMutex.Unlock()
+
+	sendJSON(ws, map[string]string{"status": "lessonCreated", "lessonID": lesson.ID})
+	logger.Info("Lesson created", slog.String("lessonID", lesson.ID), slog.Int("positions", len(positions)))
+}
+
+func joinLesson(ws *websocket.Conn, lessonID, name string) {
+	if err := validateName(name); err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+
+	lessonsMutex.Lock()
+	defer lessonsMutex.Unlock()
+
+	lesson, exists := lessons[lessonID]
+	switch {
+	case !exists:
+		sendJSON(ws, map[string]string{"error": "lesson not found"})
+		return
+	case lesson.Coach == ws:
+		sendJSON(ws, map[string]string{"error": "the coach cannot join their own lesson"})
+		return
+	case lesson.student(ws) != nil:
+		sendJSON(ws, map[string]string{"error": "already in this lesson"})
+		return
+	case len(lesson.Students) >= lesson.MaxStudents:
+		sendJSON(ws, map[string]string{"error": "lesson full"})
+		return
+	}
+	if name == "" {
+		name = "Student " + strconv.Itoa(len(lesson.Students)+1)
+	}
+	student := &StudentProgress{Name: name, conn: ws}
+	lesson.Students = append(lesson.Students, student)
+
+	sendJSON(ws, map[string]interface{}{
+		"status":    "lessonJoined",
+		"lessonID":  lesson.ID,
+		"title":     lesson.Title,
+		"positions": len(lesson.Positions),
+	})
+	startLessonPosition(lesson, student)
+	sendLessonProgress(lesson)
+	logger.Info("Student joined lesson", slog.String("lessonID", lesson.ID), slog.Int("students", len(lesson.Students)))
+}
+
+// startLessonPosition sets student up to play their current position from
+// the start and sends it to them. The caller must hold lessonsMutex.
+func startLessonPosition(lesson *Lesson, student *StudentProgress) {
+	position := lesson.Positions[student.Position]
+	// The FEN was checked when the lesson was created.
+	opt, _ := chess.FEN(position.FEN)
+	student.game = chess.NewGame(opt)
+	student.Moves = nil
+	pos := student.game.Position()
+	student.start = lessonBalance(pos, pos.Turn())
+	sendJSON(student.conn, map[string]interface{}{
+		"type":      "lesson_position",
+		"lessonID":  lesson.ID,
+		"index":     student.Position,
+		"fen":       position.FEN,
+		"objective": position.Objective,
+	})
+}
+
+// lessonBalance is color's material lead in pos, in centipawns.
+func lessonBalance(pos *chess.Position, color chess.Color) int {
+	return countMaterial(pos, color).centipawns() - countMaterial(pos, color.Other()).centipawns()
+}
+
+// sendLessonProgress sends the coach every student's progress. The caller
+// must hold lessonsMutex.
+func sendLessonProgress(lesson *Lesson) {
+	students := make([]StudentProgress, 0, len(lesson.Students))
+	for _, s := range lesson.Students {
+		progress := *s
+		progress.Moves = append([]string{}, s.Moves...)
+		students = append(students, progress)
+	}
+	sendJSON(lesson.Coach, map[string]interface{}{
+		"type":     "lesson_progress",
+		"lessonID": lesson.ID,
+		"students": students,
+	})
+}
+
+// lessonMove plays a student's move in SAN, answers it with a random legal
+// move, and checks the position's objective. A solved position moves the
+// student on to the next one; an attempt that ends without solving it is
+// counted as a failure and the position starts again.
+func lessonMove(ws *websocket.Conn, lessonID, move string) {
+	lessonsMutex.Lock()
+	defer lessonsMutex.Unlock()
+
+	lesson, exists := lessons[lessonID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "lesson not found"})
+		return
+	}
+	student := lesson.student(ws)
+	if student == nil {
+		sendJSON(ws, map[string]string{"error": "not in this lesson"})
+		return
+	}
+	if student.Finished {
+		sendJSON(ws, map[string]string{"error": "lesson already completed"})
+		return
+	}
+
+	game := student.game
+	color := game.Position().Turn()
+	if err := game.MoveStr(move); err != nil {
+		sendJSON(ws, map[string]string{"error": "Invalid move"})
+		return
+	}
+	moves := game.Moves()
+	played := chess.AlgebraicNotation{}.Encode(game.Positions()[len(moves)-1], moves[len(moves)-1])
+	student.Moves = append(student.Moves, played)
+
+	goal := lesson.Positions[student.Position].goal
+	solved := false
+	switch goal {
+	case goalCheckmate:
+		solved = game.Method() == chess.Checkmate
+	case goalPromote:
+		solved = moves[len(moves)-1].Promo() != chess.NoPieceType
+	}
+
+	reply := ""
+	if !solved && game.Outcome() == chess.NoOutcome {
+		valid := game.ValidMoves()
+		m := valid[rand.Intn(len(valid))]
+		reply = chess.AlgebraicNotation{}.Encode(game.Position(), m)
+		game.Move(m)
+		if goal == goalWinMaterial {
+			solved = lessonBalance(game.Position(), color)-student.start >= lessonMaterialGain
+		}
+	}
+
+	result := map[string]interface{}{
+		"type":     "lesson_move",
+		"lessonID": lesson.ID,
+		"move":     played,
+		"fen":      game.Position().String(),
+	}
+	if reply != "" {
+		result["reply"] = reply
+	}
+	sendJSON(ws, result)
+
+	index := student.Position
+	switch {
+	case solved:
+		student.Completed++
+		sendJSON(ws, map[string]interface{}{"type": "lesson_solved", "lessonID": lesson.ID, "index": index})
+		if student.Position+1 < len(lesson.Positions) {
+			student.Position++
+			startLessonPosition(lesson, student)
+		} else {
+			student.Finished = true
+			sendJSON(ws, map[string]string{"type": "lesson_complete", "lessonID": lesson.ID})
+			logger.Info("Student completed lesson", slog.String("lessonID", lesson.ID), slog.String("name", student.Name))
+		}
+	case game.Outcome() != chess.NoOutcome:
+		student.Failures++
+		sendJSON(ws, map[string]interface{}{
+			"type":     "lesson_failed",
+			"lessonID": lesson.ID,
+			"index":    index,
+			"reason":   game.Method().String(),
+		})
+		startLessonPosition(lesson, student)
+	}
+	sendLessonProgress(lesson)
+}
+
+// leaveLessons takes ws out of every lesson on disconnect. A lesson whose
+// coach leaves ends, and its students are told so.
+func leaveLessons(ws *websocket.Conn) {
+	lessonsMutex.Lock()
+	defer lessonsMutex.Unlock()
+
+	for id, lesson := range lessons {
+		if lesson.Coach == ws {
+			delete(lessons, id)
+			for _, s := range lesson.Students {
+				sendJSON(s.conn, map[string]string{"type": "lesson_ended", "lessonID": id})
+			}
+			logger.Info("Lesson ended", slog.String("lessonID", id))
+			continue
+		}
+		for i, s := range lesson.Students {
+			if s.conn == ws {
+				lesson.Students = append(lesson.Students[:i], lesson.Students[i+1:]...)
+				sendLessonProgress(lesson)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func isLessonMessage(kind string) func(map[string]interface{}) bool {
+	return func(msg map[string]interface{}) bool {
+		if hasError(msg) {
+			return true
+		}
+		return msg["type"] == kind
+	}
+}
+
+func TestLesson(t *testing.T) {
+	useConfig(t, func(c *Config) { c.CoachToken = "coach-secret" })
+	srv := newTestServer(t)
+	positions := `[
+		{"fen": "k7/8/1Q6/8/8/8/8/1R4K1 w - - 0 1", "objective": "Mate in one"},
+		{"fen": "8/4P3/8/8/8/8/k7/6K1 w - - 0 1", "objective": "Promote the pawn"}
+	]`
+
+	coach := dial(t, srv)
+	send(t, coach, map[string]string{"action": "create_lesson", "title": "Endings", "positions": positions, "maxStudents": "1"})
+	if msg := readUntil(t, coach, hasError); msg["error"] != "unauthorized" {
+		t.Fatalf("lesson without a coach token: %v", msg)
+	}
+	send(t, coach, map[string]string{"action": "create_lesson", "coachToken": "coach-secret", "title": "Endings",
+		"positions": `[{"fen": "k7/8/1Q6/8/8/8/8/1R4K1 w - - 0 1", "objective": "Find the best move"}]`, "maxStudents": "1"})
+	if msg := readUntil(t, coach, hasError); msg["error"] != "position 1: unsupported objective" {
+		t.Fatalf("lesson with an unsupported objective: %v", msg)
+	}
+	send(t, coach, map[string]string{"action": "create_lesson", "coachToken": "coach-secret", "title": "Endings", "positions": positions, "maxStudents": "1"})
+	created := readUntil(t, coach, func(msg map[string]interface{}) bool {
+		if hasError(msg) {
+			t.Fatalf("creating lesson: %v", msg["error"])
+		}
+		return msg["status"] == "lessonCreated"
+	})
+	lessonID := created["lessonID"].(string)
+
+	student := dial(t, srv)
+	send(t, student, map[string]string{"action": "join_lesson", "lessonID": lessonID, "name": "Ana"})
+	readUntil(t, student, hasStatus("lessonJoined"))
+	if msg := readUntil(t, student, isLessonMessage("lesson_position")); msg["index"] != 0.0 || msg["objective"] != "Mate in one" {
+		t.Fatalf("first position: %v", msg)
+	}
+	latecomer := dial(t, srv)
+	send(t, latecomer, map[string]string{"action": "join_lesson", "lessonID": lessonID})
+	if msg := readUntil(t, latecomer, hasError); msg["error"] != "lesson full" {
+		t.Fatalf("joining a full lesson: %v", msg)
+	}
+
+	// Qc7 stalemates, so the attempt fails and the position starts again.
+	send(t, student, map[string]string{"action": "lesson_move", "lessonID": lessonID, "move": "Qc7"})
+	if msg := readUntil(t, student, isLessonMessage("lesson_failed")); msg["reason"] != "Stalemate" {
+		t.Fatalf("stalemating: %v", msg)
+	}
+	if msg := readUntil(t, student, isLessonMessage("lesson_position")); msg["index"] != 0.0 {
+		t.Fatalf("after failing: %v", msg)
+	}
+
+	send(t, student, map[string]string{"action": "lesson_move", "lessonID": lessonID, "move": "Qb7#"})
+	readUntil(t, student, isLessonMessage("lesson_solved"))
+	if msg := readUntil(t, student, isLessonMessage("lesson_position")); msg["index"] != 1.0 {
+		t.Fatalf("after solving: %v", msg)
+	}
+	send(t, student, map[string]string{"action": "lesson_move", "lessonID": lessonID, "move": "e8=Q"})
+	readUntil(t, student, isLessonMessage("lesson_complete"))
+
+	progress := readUntil(t, coach, func(msg map[string]interface{}) bool {
+		if msg["type"] != "lesson_progress" {
+			return false
+		}
+		students := msg["students"].([]interface{})
+		return len(students) == 1 && students[0].(map[string]interface{})["finished"] == true
+	})
+	ana := progress["students"].([]interface{})[0].(map[string]interface{})
+	if ana["name"] != "Ana" || ana["completed"] != 2.0 || ana["failures"] != 1.0 {
+		t.Errorf("coach got progress %v", ana)
+	}
+
+	coach.Close()
+	readUntil(t, student, isLessonMessage("lesson_ended"))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	defaultLobbyLimit = 20
+	maxLobbyLimit     = 100
+)
+
+type GameSummary struct {
+	GameID    string `json:"gameID"`
+	Status    string `json:"status"`
+	Players   int    `json:"players"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// lobbyStatus is "waiting" for a game with a free seat, "finished" once it
+// has an outcome and "ongoing" otherwise. Practice games never wait as
+// nobody can join them.
+func lobbyStatus(game *Game) string {
+	switch {
+	case game.Game.Outcome() != chess.NoOutcome:
+		return "finished"
+	case len(game.Players) < 2 && game.PracticeMode == "":
+		return "waiting"
+	}
+	return "ongoing"
+}
+
+// queryInt reads a non-negative integer query parameter.
+func queryInt(r *http.Request, name string, fallback int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil && n >= 0 {
+		return n
+	}
+	return fallback
+}
+
+// handleListGames serves GET /games: the games waiting for a second player,
+// or every game with ?status=all, oldest first and paginated with limit and
+// offset.
+func handleListGames(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("status") == "all"
+	limit := min(queryInt(r, "limit", defaultLobbyLimit), maxLobbyLimit)
+	offset := queryInt(r, "offset", 0)
+
+	gamesMutex.RLock()
+	summaries := []GameSummary{}
+	created := map[string]time.Time{}
+	for gameID, game := range games {
+		status := lobbyStatus(game)
+		if !all && status != "waiting" {
+			continue
+		}
+		summaries = append(summaries, GameSummary{
+			GameID:    gameID,
+			Status:    status,
+			Players:   len(game.Players),
+			CreatedAt: game.CreatedAt.Format(time.RFC3339),
+		})
+		created[gameID] = game.CreatedAt
+	}
+	gamesMutex.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := created[summaries[i].GameID], created[summaries[j].GameID]
+		if a.Equal(b) {
+			return summaries[i].GameID < summaries[j].GameID
+		}
+		return a.Before(b)
+	})
+	summaries = summaries[min(offset, len(summaries)):]
+	summaries = summaries[:min(limit, len(summaries))]
+
+	// The lobby is read by the frontend from its own origin.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summaries); err != nil {
+		logger.Error("Error writing game list", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listGames fetches GET /games with the given query from srv.
+func listGames(t *testing.T, srv *httptest.Server, query string) []GameSummary {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/games" + query)
+	if err != nil {
+		t.Fatalf("GET /games%s: %v", query, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /games%s: status %d", query, resp.StatusCode)
+	}
+	var summaries []GameSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summaries); err != nil {
+		t.Fatalf("decoding /games%s: %v", query, err)
+	}
+	return summaries
+}
+
+func TestListGames(t *testing.T) {
+	useConfig(t, nil)
+	useGames(t)
+	srv := newTestServer(t)
+
+	waiting := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		ws := dial(t, srv)
+		send(t, ws, map[string]string{"action": "create"})
+		created := readUntil(t, ws, hasStatus("created"))
+		waiting[created["gameID"].(string)] = true
+	}
+	_, _, ongoing := startTestGame(t, srv)
+	white, _, finished := startTestGame(t, srv)
+	send(t, white, map[string]string{"action": "resign", "gameID": finished})
+	readUntil(t, white, hasStatus("resigned"))
+
+	lobby := listGames(t, srv, "")
+	if len(lobby) != len(waiting) {
+		t.Fatalf("lobby lists %d games, want the %d waiting: %+v", len(lobby), len(waiting), lobby)
+	}
+	for _, s := range lobby {
+		if !waiting[s.GameID] || s.Status != "waiting" || s.Players != 1 {
+			t.Errorf("unexpected lobby entry %+v", s)
+		}
+		if _, err := time.Parse(time.RFC3339, s.CreatedAt); err != nil {
+			t.Errorf("createdAt %q: %v", s.CreatedAt, err)
+		}
+	}
+
+	all := listGames(t, srv, "?status=all")
+	statuses := map[string]GameSummary{}
+	for _, s := range all {
+		statuses[s.GameID] = s
+	}
+	if len(all) != 5 || len(statuses) != 5 {
+		t.Fatalf("?status=all lists %+v, want 5 games", all)
+	}
+	if s := statuses[ongoing]; s.Status != "ongoing" || s.Players != 2 {
+		t.Errorf("started game listed as %+v", s)
+	}
+	if s := statuses[finished]; s.Status != "finished" || s.Players != 2 {
+		t.Errorf("resigned game listed as %+v", s)
+	}
+
+	var paged []GameSummary
+	for offset := 0; offset < len(all); offset += 2 {
+		page := listGames(t, srv, "?status=all&limit=2&offset="+strconv.Itoa(offset))
+		if len(page) > 2 {
+			t.Fatalf("page at offset %d has %d games", offset, len(page))
+		}
+		paged = append(paged, page...)
+	}
+	if len(paged) != len(all) {
+		t.Fatalf("pages hold %d games, want %d", len(paged), len(all))
+	}
+	for i := range all {
+		if paged[i] != all[i] {
+			t.Errorf("paged entry %d is %+v, want %+v", i, paged[i], all[i])
+		}
+	}
+	if page := listGames(t, srv, "?status=all&offset=10"); len(page) != 0 {
+		t.Errorf("offset past the end lists %+v", page)
+	}
+}
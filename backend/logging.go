@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// logger is the server's structured logger, set up by main from the
+// configuration.
+var logger = slog.Default()
+
+// newLogger writes JSON lines when cfg.LogFormat is "json" and text
+// otherwise, at cfg.LogLevel.
+func newLogger(cfg *Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.LogLevel}
+	if cfg.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
@@ -1,23 +1,111 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func main() {
+	cfg, err := LoadConfig()
+	if err != nil {
+		logger.Error("Invalid configuration", slog.Any("error", err))
+		os.Exit(1)
+	}
+	config = cfg
+	logger = newLogger(cfg)
 	rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	// Use Heroku's assigned port or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	store, err := newStore(cfg)
+	if err != nil {
+		logger.Error("Error opening store", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(store, os.Args[2:]))
+	}
+	if _, err := migrateStore(store, false); err != nil {
+		logger.Error("Error migrating store", slog.Any("error", err))
 	}
+	// The snapshot wins over the store for a game in both, as restoreGames
+	// skips games already restored. Shutdown writes the snapshot after
+	// saving to the store, and it is removed once restored, so any
+	// snapshot found here is the latest copy of its games.
+	if err := restoreSnapshot(cfg.SnapshotPath); err != nil {
+		logger.Error("Error restoring game snapshot", slog.Any("error", err))
+	}
+	if err := restoreGames(store); err != nil {
+		logger.Error("Error restoring games", slog.Any("error", err))
+	}
+	if err := loadRatings(store); err != nil {
+		logger.Error("Error loading ratings", slog.Any("error", err))
+	}
+	openArchive(store)
+	go flushGames(store)
 
 	http.HandleFunc("/ws", handleConnections)
-	log.Printf("Server started on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	http.HandleFunc("GET /pos/{encoded}", handleSharedPosition)
+	http.HandleFunc("GET /games", handleListGames)
+	http.HandleFunc("GET /games/{gameID}/stream", handleGameStream)
+	http.HandleFunc("POST /auth/token", handleIssueToken)
+	http.Handle("GET /metrics", promhttp.Handler())
+	http.HandleFunc("GET /ratings/{playerID}", handleGetRating)
+	http.HandleFunc("GET /stats", handleStats)
+	http.HandleFunc("GET /player/{id}/heatmap", handleActivityHeatmap)
+	http.HandleFunc("GET /player/{id}/export.csv", handleExportCSV)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Heroku assigns PORT; see LoadConfig for the defaults.
+	server := &http.Server{Addr: ":" + cfg.Port}
+	var acme *autocert.Manager
+	if cfg.ACMEDomain != "" {
+		acme = acmeManager(cfg)
+		server.TLSConfig = acme.TLSConfig()
+	}
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Error("Error listening", slog.Any("error", err))
+		os.Exit(1)
+	}
+	go func() {
+		if err := serve(server, ln, cfg); !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Server error", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}()
+	logger.Info("Server started", slog.String("port", cfg.Port), slog.Bool("tls", cfg.useTLS()))
+
+	// With TLS on, plain HTTP only redirects to HTTPS, and answers the
+	// ACME HTTP-01 challenges.
+	var redirect *http.Server
+	if cfg.useTLS() {
+		handler := redirectToHTTPS(cfg.Port)
+		if acme != nil {
+			handler = acme.HTTPHandler(handler)
+		}
+		redirect = &http.Server{Addr: ":" + cfg.HTTPRedirectPort, Handler: handler}
+		go func() {
+			if err := redirect.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("Redirect server error", slog.Any("error", err))
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	if redirect != nil {
+		redirect.Close()
+	}
+	shutdown(server, store)
 }
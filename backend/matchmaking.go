@@ -0,0 +1,162 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/segmentio/ksuid"
+)
+
+var (
+	waitingQueue []*Player
+	queueMutex   sync.Mutex
+)
+
+// leaveQueue removes ws from the matchmaking queue, reporting whether it
+// was waiting.
+func leaveQueue(ws *websocket.Conn) bool {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	for i, player := range waitingQueue {
+		if player.Conn == ws {
+			waitingQueue = append(waitingQueue[:i], waitingQueue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// findGame pairs ws with the longest waiting player who wants the same time
+// control, or queues it until someone else arrives. Ranked players are
+// only paired with each other, within rankedRatingWindow.
+func findGame(ws *websocket.Conn, msg map[string]string) {
+	if !allowGameCreation(ws) {
+		return
+	}
+	ranked := msg["ranked"] == "true"
+	// A guest ID lapses after config.GuestTTL, so a rating would not
+	// follow them for long.
+	if ranked && !isAuthenticated(ws) {
+		sendJSON(ws, map[string]string{"error": "ranked games require authentication"})
+		return
+	}
+	if err := validateName(msg["name"]); err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+	timeControl := msg["timeControl"]
+	if timeControl != "" {
+		if _, err := parseTimeControl(timeControl); err != nil {
+			sendJSON(ws, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	player := &Player{
+		Conn:         ws,
+		ConfirmMoves: msg["confirmMoves"] == "true",
+		TimeControl:  timeControl,
+		Ranked:       ranked,
+	}
+	gamesMutex.RLock()
+	allowed := allowAnotherGame(ws)
+	gamesMutex.RUnlock()
+	if !allowed {
+		return
+	}
+	player.PlayerID, player.Subject, player.Name = identify(ws, msg["name"])
+	seatGuest(player)
+	if ranked {
+		player.Rating = ratingOf(player.Subject)
+	}
+
+	queueMutex.Lock()
+	for _, waiting := range waitingQueue {
+		if waiting.Conn == ws {
+			queueMutex.Unlock()
+			sendJSON(ws, map[string]string{"error": "already searching"})
+			return
+		}
+	}
+	var opponent *Player
+	for i, waiting := range waitingQueue {
+		if waiting.TimeControl == timeControl && waiting.Name != player.Name && (waiting.Subject == "" || waiting.Subject != player.Subject) && ratedAlike(waiting, player) {
+			opponent = waiting
+			waitingQueue = append(waitingQueue[:i], waitingQueue[i+1:]...)
+			break
+		}
+	}
+	if opponent == nil {
+		waitingQueue = append(waitingQueue, player)
+		queueMutex.Unlock()
+		sendJSON(ws, map[string]string{"status": "searching"})
+		logger.Info("Player queued for matchmaking", slog.String("timeControl", timeControl), slog.Bool("ranked", ranked))
+		return
+	}
+	queueMutex.Unlock()
+
+	startMatchedGame(opponent, player)
+}
+
+// ratedAlike reports whether a and b may be paired: both unranked, or both
+// ranked with ratings no more than rankedRatingWindow apart.
+func ratedAlike(a, b *Player) bool {
+	if a.Ranked != b.Ranked {
+		return false
+	}
+	diff := a.Rating - b.Rating
+	if diff < 0 {
+		diff = -diff
+	}
+	return !a.Ranked || diff <= rankedRatingWindow
+}
+
+// startMatchedGame seats two players from the queue in a new game with
+// random colors.
+func startMatchedGame(a, b *Player) {
+	a.Color = randomColor()
+	b.Color = a.Color.Other()
+	var clock *GameClock
+	if a.TimeControl != "" {
+		clock, _ = parseTimeControl(a.TimeControl)
+	}
+	chessGame := chess.NewGame()
+	game := &Game{
+		Game:        chessGame,
+		Players:     []*Player{a, b},
+		StartingFEN: chessGame.Position().String(),
+		Variant:     "standard",
+		CreatedAt:   time.Now(),
+		Clock:       clock,
+		TimeControl: a.TimeControl,
+		Ranked:      a.Ranked,
+	}
+
+	gameID := ksuid.New().String()
+	gamesMutex.Lock()
+	games[gameID] = game
+	indexGame(a.Conn, gameID)
+	indexGame(b.Conn, gameID)
+	watchGuestGame(gameID, game)
+	gamesCreated.Inc()
+	startClock(gameID, game)
+	gamesMutex.Unlock()
+
+	for _, player := range game.Players {
+		sendToPlayer(player, withGuestID(map[string]string{"status": "matched", "gameID": gameID, "color": player.Color.String(), "playerID": player.PlayerID}, player))
+	}
+	logger.Info("Matched players into game", slog.String("gameID", gameID))
+
+	broadcastGameState(gameID)
+}
+
+func cancelFind(ws *websocket.Conn) {
+	if !leaveQueue(ws) {
+		sendJSON(ws, map[string]string{"error": "not searching"})
+		return
+	}
+	sendJSON(ws, map[string]string{"status": "searchCancelled"})
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type CheckmatePattern struct {
+	Pattern         string   `json:"pattern"`
+	MateInN         int      `json:"mateInN"`
+	MatingSequence  []string `json:"matingSequence"`
+	MatedColor      string   `json:"matedColor"`
+	MatingPieceType string   `json:"matingPiece"`
+}
+
+// findMate searches for a forced mate for the side to move in at most depth
+// moves and returns the main line in SAN, or nil if there is none.
+//
+// A mating move always gives check, and from mateSearchChecksOnly moves out
+// only checks are tried at all, which keeps the deeper searches fast enough
+// to run on request.
+func findMate(pos *chess.Position, depth int) []string {
+	for _, m := range pos.ValidMoves() {
+		if !m.HasTag(chess.Check) && (depth == 1 || depth >= mateSearchChecksOnly) {
+			continue
+		}
+		next := pos.Update(m)
+		san := chess.AlgebraicNotation{}.Encode(pos, m)
+		if next.Status() == chess.Checkmate {
+			return []string{san}
+		}
+		if depth == 1 || next.Status() != chess.NoMethod {
+			continue
+		}
+
+		// Every defence must still allow mate in depth-1.
+		var line []string
+		refuted := false
+		for _, reply := range next.ValidMoves() {
+			rest := findMate(next.Update(reply), depth-1)
+			if rest == nil {
+				refuted = true
+				break
+			}
+			if line == nil {
+				line = append([]string{san, chess.AlgebraicNotation{}.Encode(next, reply)}, rest...)
+			}
+		}
+		if !refuted && line != nil {
+			return line
+		}
+	}
+	return nil
+}
+
+// classifyMate names the pattern of a checkmated position.
+func classifyMate(pos *chess.Position) (pattern string, matingPiece chess.PieceType) {
+	board := pos.Board()
+	mated := pos.Turn()
+	king := analysis.KingSquare(board, mated)
+
+	var checkers []chess.Square
+	for sq, piece := range board.SquareMap() {
+		if piece.Color() != mated.Other() {
+			continue
+		}
+		for _, target := range analysis.AttackedSquares(board, sq, piece) {
+			if target == king {
+				checkers = append(checkers, sq)
+			}
+		}
+	}
+	if len(checkers) == 0 {
+		return "none", chess.NoPieceType
+	}
+	checker := checkers[0]
+	checkerType := board.Piece(checker).Type()
+	if len(checkers) > 1 {
+		return "double_check_mate", checkerType
+	}
+
+	// Classify the king's flight squares.
+	enemyAttacks := analysis.AttackCounts(board, mated.Other())
+	ownBlocked := 0
+	flights := 0
+	for _, o := range analysis.KingOffsets {
+		sq, ok := analysis.OffsetSquare(king, o[0], o[1])
+		if !ok {
+			continue
+		}
+		flights++
+		if p := board.Piece(sq); p != chess.NoPiece && p.Color() == mated {
+			ownBlocked++
+		}
+	}
+	df := int(checker.File()) - int(king.File())
+	dr := int(checker.Rank()) - int(king.Rank())
+	adjacent := df >= -1 && df <= 1 && dr >= -1 && dr <= 1
+	onEdgeFile := king.File() == chess.FileA || king.File() == chess.FileH
+	onBackRank := analysis.RelativeRank(king, mated) == 0
+	inCorner := onEdgeFile && (king.Rank() == chess.Rank1 || king.Rank() == chess.Rank8)
+	knightGuardsChecker := false
+	knightGuardsFlights := false
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() != chess.Knight || piece.Color() != mated.Other() {
+			continue
+		}
+		for _, target := range analysis.AttackedSquares(board, sq, piece) {
+			if target == checker {
+				knightGuardsChecker = true
+			}
+			if d := squareDistance(target, king); d == 1 {
+				knightGuardsFlights = true
+			}
+		}
+	}
+	bishops := 0
+	for _, piece := range board.SquareMap() {
+		if piece.Type() == chess.Bishop && piece.Color() == mated.Other() {
+			bishops++
+		}
+	}
+
+	switch {
+	case checkerType == chess.Knight && ownBlocked == flights:
+		return "smothered_mate", checkerType
+	case checkerType == chess.Rook && adjacent && inCorner && knightGuardsChecker:
+		return "arabian_mate", checkerType
+	case checkerType == chess.Rook && adjacent && (df == 0 || dr == 0) && hookSupported(board, checker, mated.Other()):
+		return "hook_mate", checkerType
+	case (checkerType == chess.Rook || checkerType == chess.Queen) && onEdgeFile && df == 0 && knightGuardsFlights:
+		return "anastasia_mate", checkerType
+	case checkerType == chess.Queen && df == 0 && !adjacent && epauletteBlocked(board, king, mated):
+		return "epaulette_mate", checkerType
+	case checkerType == chess.Rook && onBackRank && dr == 0 && len(guards(board, checker, mated.Other(), chess.Bishop)) > 0:
+		return "opera_mate", checkerType
+	case (checkerType == chess.Rook || checkerType == chess.Queen) && ownBlocked == 0 && ladderSupported(board, king, checker, mated.Other()):
+		return "ladder_mate", checkerType
+	case (checkerType == chess.Rook || checkerType == chess.Queen) && onBackRank && dr == 0:
+		return "back_rank_mate", checkerType
+	case (checkerType == chess.Rook || checkerType == chess.Queen) && !adjacent && ownBlocked >= 2:
+		return "corridor_mate", checkerType
+	case checkerType == chess.Bishop && bishops >= 2:
+		return "boden_mate", checkerType
+	case checkerType == chess.Queen && adjacent && df != 0 && dr != 0 && dovetailBlocked(board, king, -df, -dr, mated):
+		return "dovetail_mate", checkerType
+	case checkerType == chess.Queen && adjacent && len(guards(board, checker, mated.Other(), chess.Pawn)) > 0:
+		return "damiano_mate", checkerType
+	case checkerType == chess.Queen && adjacent && enemyAttacks[checker] > 0:
+		return "kiss_of_death", checkerType
+	case checkerType == chess.Pawn:
+		return "pawn_mate", checkerType
+	}
+	return "other", checkerType
+}
+
+// epauletteBlocked reports whether both squares beside the king along its
+// rank are occupied by its own pieces.
+func epauletteBlocked(board *chess.Board, king chess.Square, color chess.Color) bool {
+	for _, df := range []int{-1, 1} {
+		sq, ok := analysis.OffsetSquare(king, df, 0)
+		if !ok {
+			return false
+		}
+		if p := board.Piece(sq); p == chess.NoPiece || p.Color() != color {
+			return false
+		}
+	}
+	return true
+}
+
+// guards returns the squares of color's pieces of type t that defend sq.
+func guards(board *chess.Board, sq chess.Square, color chess.Color, t chess.PieceType) []chess.Square {
+	var found []chess.Square
+	for from, piece := range board.SquareMap() {
+		if piece.Color() != color || piece.Type() != t {
+			continue
+		}
+		for _, target := range analysis.AttackedSquares(board, from, piece) {
+			if target == sq {
+				found = append(found, from)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// hookSupported reports whether the rook on sq is defended by a knight that
+// is itself defended by a pawn.
+func hookSupported(board *chess.Board, sq chess.Square, color chess.Color) bool {
+	for _, knight := range guards(board, sq, color, chess.Knight) {
+		if len(guards(board, knight, color, chess.Pawn)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ladderSupported reports whether the king stands on an edge of the board,
+// checked along that edge from checker, while another rook or queen of
+// color holds the line next to it.
+func ladderSupported(board *chess.Board, king, checker chess.Square, color chess.Color) bool {
+	var covers func(sq chess.Square) bool
+	switch {
+	case (king.Rank() == chess.Rank1 || king.Rank() == chess.Rank8) && checker.Rank() == king.Rank():
+		next := king.Rank() + 1
+		if king.Rank() == chess.Rank8 {
+			next = king.Rank() - 1
+		}
+		covers = func(sq chess.Square) bool { return sq.Rank() == next }
+	case (king.File() == chess.FileA || king.File() == chess.FileH) && checker.File() == king.File():
+		next := king.File() + 1
+		if king.File() == chess.FileH {
+			next = king.File() - 1
+		}
+		covers = func(sq chess.Square) bool { return sq.File() == next }
+	default:
+		return false
+	}
+	for sq, piece := range board.SquareMap() {
+		if sq == checker || piece.Color() != color || (piece.Type() != chess.Rook && piece.Type() != chess.Queen) {
+			continue
+		}
+		if covers(sq) {
+			return true
+		}
+	}
+	return false
+}
+
+// dovetailBlocked reports whether the king's own pieces stand on the two
+// squares beside it facing away from a queen that checks it diagonally
+// from the direction (-df, -dr).
+func dovetailBlocked(board *chess.Board, king chess.Square, df, dr int, color chess.Color) bool {
+	for _, o := range [][2]int{{df, 0}, {0, dr}} {
+		sq, ok := analysis.OffsetSquare(king, o[0], o[1])
+		if !ok {
+			return false
+		}
+		if p := board.Piece(sq); p == chess.NoPiece || p.Color() != color {
+			return false
+		}
+	}
+	return true
+}
+
+// squareDistance returns the number of king moves between two squares.
+func squareDistance(a, b chess.Square) int {
+	df := int(a.File()) - int(b.File())
+	dr := int(a.Rank()) - int(b.Rank())
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
+
+// mateSearchDepth is the longest forced mate, in moves, that the pattern
+// search looks for.
+const mateSearchDepth = 3
+
+// mateSearchChecksOnly is the search depth from which findMate only tries
+// checking moves.
+const mateSearchChecksOnly = 3
+
+func getCheckmatePatterns(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+
+	sequence := []string{}
+	final := pos
+	if pos.Status() != chess.Checkmate {
+		var line []string
+		for depth := 1; depth <= mateSearchDepth && line == nil; depth++ {
+			line = findMate(pos, depth)
+		}
+		if line == nil {
+			sendJSON(ws, map[string]interface{}{"pattern": "none"})
+			return
+		}
+		sequence = line
+		for _, san := range line {
+			move, err := chess.AlgebraicNotation{}.Decode(final, san)
+			if err != nil {
+				logger.Error("Error replaying mating line", slog.Any("error", err))
+				break
+			}
+			final = final.Update(move)
+		}
+	}
+
+	pattern, matingPiece := classifyMate(final)
+	sendJSON(ws, CheckmatePattern{
+		Pattern:         pattern,
+		MateInN:         (len(sequence) + 1) / 2,
+		MatingSequence:  sequence,
+		MatedColor:      colorName(final.Turn()),
+		MatingPieceType: pieceNames[matingPiece],
+	})
+	logger.Debug("Checkmate pattern sent", slog.String("gameID", gameID), slog.String("pattern", pattern))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestClassifyMate(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		fen     string
+	}{
+		{"smothered_mate", "6rk/5Npp/8/8/8/8/8/6K1 b - - 0 1"},
+		{"arabian_mate", "7k/7R/5N2/8/8/8/8/6K1 b - - 0 1"},
+		{"hook_mate", "3Rk3/4pp2/4N3/3P4/8/8/8/6K1 b - - 0 1"},
+		{"anastasia_mate", "8/4N1pk/8/7R/8/8/8/6K1 b - - 0 1"},
+		{"epaulette_mate", "3rkr2/8/4Q3/8/8/8/8/4K3 b - - 0 1"},
+		{"opera_mate", "3Rkb2/5p2/8/6B1/8/8/8/6K1 b - - 0 1"},
+		{"ladder_mate", "R5k1/1R6/8/8/8/8/8/6K1 b - - 0 1"},
+		{"back_rank_mate", "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1"},
+		{"corridor_mate", "8/8/1p6/kp6/1p6/8/8/R5K1 b - - 0 1"},
+		{"boden_mate", "2kr4/3p4/B7/8/5B2/8/8/6K1 b - - 0 1"},
+		{"double_check_mate", "6rk/5Np1/8/8/8/8/8/6KR b - - 0 1"},
+		{"dovetail_mate", "8/4p3/4kp2/3Q4/2P5/8/8/6K1 b - - 0 1"},
+		{"damiano_mate", "5rk1/7Q/6P1/8/8/8/8/6K1 b - - 0 1"},
+		{"kiss_of_death", "4k3/4Q3/4K3/8/8/8/8/8 b - - 0 1"},
+		{"pawn_mate", "6bk/6P1/6K1/8/8/8/8/8 b - - 0 1"},
+	} {
+		pos := gameFromFEN(t, c.fen).Position()
+		if pos.Status() != chess.Checkmate {
+			t.Errorf("%s: %s is not checkmate", c.pattern, c.fen)
+			continue
+		}
+		if got, _ := classifyMate(pos); got != c.pattern {
+			t.Errorf("classifyMate(%s) = %s, want %s", c.fen, got, c.pattern)
+		}
+	}
+}
+
+// TestFindMateInThree plays Philidor's legacy: a double check drives the
+// king into the corner, the queen is sacrificed on g8 and the knight mates.
+func TestFindMateInThree(t *testing.T) {
+	const fen = "5rk1/5Npp/8/8/8/1Q6/8/6K1 w - - 0 1"
+	pos := gameFromFEN(t, fen).Position()
+	if line := findMate(pos, 2); line != nil {
+		t.Fatalf("findMate found a mate in two: %v", line)
+	}
+	line := findMate(pos, mateSearchDepth)
+	if len(line) != 5 {
+		t.Fatalf("findMate(%d) = %v, want a five-ply mate", mateSearchDepth, line)
+	}
+
+	game := gameFromFEN(t, fen)
+	for _, san := range line {
+		if err := game.MoveStr(san); err != nil {
+			t.Fatalf("mating line move %s: %v", san, err)
+		}
+	}
+	if pattern, piece := classifyMate(game.Position()); pattern != "smothered_mate" || piece != chess.Knight {
+		t.Errorf("classifyMate after %v = %s by %v, want smothered_mate by a knight", line, pattern, piece)
+	}
+}
+
+func TestGetCheckmatePatterns(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGameFrom(t, srv, "5rk1/5Npp/8/8/8/1Q6/8/6K1 w - - 0 1")
+	send(t, white, map[string]string{"action": "get_checkmate_patterns", "gameID": gameID})
+	reply := readUntil(t, white, func(msg map[string]interface{}) bool { return msg["pattern"] != nil })
+	if reply["pattern"] != "smothered_mate" || reply["mateInN"] != float64(3) || reply["matedColor"] != "black" || reply["matingPiece"] != "knight" {
+		t.Errorf("checkmate patterns = %v, want black smothered by a knight in 3", reply)
+	}
+}
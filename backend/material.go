@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+var pieceNames = map[chess.PieceType]string{
+	chess.King:   "king",
+	chess.Queen:  "queen",
+	chess.Rook:   "rook",
+	chess.Bishop: "bishop",
+	chess.Knight: "knight",
+	chess.Pawn:   "pawn",
+}
+
+type MaterialCount struct {
+	Pawns   int `json:"pawns"`
+	Knights int `json:"knights"`
+	Bishops int `json:"bishops"`
+	Rooks   int `json:"rooks"`
+	Queen   int `json:"queen"`
+}
+
+type MaterialDifference struct {
+	Centipawns  int    `json:"centipawns"`
+	Description string `json:"description"`
+}
+
+type MaterialEval struct {
+	White      MaterialCount      `json:"white"`
+	Black      MaterialCount      `json:"black"`
+	Difference MaterialDifference `json:"difference"`
+}
+
+func (m MaterialCount) centipawns() int {
+	return m.Pawns*analysis.PieceValues[chess.Pawn] +
+		m.Knights*analysis.PieceValues[chess.Knight] +
+		m.Bishops*analysis.PieceValues[chess.Bishop] +
+		m.Rooks*analysis.PieceValues[chess.Rook] +
+		m.Queen*analysis.PieceValues[chess.Queen]
+}
+
+// nonPawnMaterial returns the centipawn value of everything but the pawns.
+func nonPawnMaterial(m MaterialCount) int {
+	return m.centipawns() - m.Pawns*analysis.PieceValues[chess.Pawn]
+}
+
+func countMaterial(pos *chess.Position, color chess.Color) MaterialCount {
+	var count MaterialCount
+	for _, piece := range pos.Board().SquareMap() {
+		if piece.Color() != color {
+			continue
+		}
+		switch piece.Type() {
+		case chess.Pawn:
+			count.Pawns++
+		case chess.Knight:
+			count.Knights++
+		case chess.Bishop:
+			count.Bishops++
+		case chess.Rook:
+			count.Rooks++
+		case chess.Queen:
+			count.Queen++
+		}
+	}
+	return count
+}
+
+// evaluateMaterial counts the pieces of both sides and describes the balance
+// from white's point of view.
+func evaluateMaterial(pos *chess.Position) MaterialEval {
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	diff := white.centipawns() - black.centipawns()
+	return MaterialEval{
+		White: white,
+		Black: black,
+		Difference: MaterialDifference{
+			Centipawns:  diff,
+			Description: describeMaterial(white, black, diff),
+		},
+	}
+}
+
+func describeMaterial(white, black MaterialCount, diff int) string {
+	if diff == 0 {
+		return "Material is even"
+	}
+	side, ahead, behind := "White", white, black
+	if diff < 0 {
+		side, ahead, behind, diff = "Black", black, white, -diff
+	}
+
+	// A single extra piece is described by name, anything else in pawns.
+	extra := []struct {
+		name string
+		n    int
+	}{
+		{"pawn", ahead.Pawns - behind.Pawns},
+		{"knight", ahead.Knights - behind.Knights},
+		{"bishop", ahead.Bishops - behind.Bishops},
+		{"rook", ahead.Rooks - behind.Rooks},
+		{"queen", ahead.Queen - behind.Queen},
+	}
+	name := ""
+	for _, e := range extra {
+		if e.n == 0 {
+			continue
+		}
+		if e.n != 1 || name != "" {
+			name = ""
+			break
+		}
+		name = e.name
+	}
+	if name != "" {
+		return fmt.Sprintf("%s is up a %s", side, name)
+	}
+	return fmt.Sprintf("%s is up the equivalent of %.1f pawns", side, float64(diff)/100)
+}
+
+func getMaterialEval(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, evaluateMaterial(pos))
+	logger.Debug("Material evaluation sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Message is an incoming client message. Handlers index it by key like a
+// plain string map; JSON numbers and booleans are kept in their literal form
+// ("5", "true") and arrays or objects as their raw JSON text, so clients can
+// send natural JSON without the connection failing to decode it.
+type Message map[string]string
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	msg := make(Message, len(raw))
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			msg[key] = s
+			continue
+		}
+		value = bytes.TrimSpace(value)
+		if bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		msg[key] = string(value)
+	}
+	*m = msg
+	return nil
+}
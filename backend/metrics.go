@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The server's metrics, registered with the default registerer and served
+// at GET /metrics by promhttp.Handler.
+var (
+	gamesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chess_total_games_created",
+		Help: "Games created since the server started.",
+	})
+	movesMade = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chess_total_moves_made",
+		Help: "Moves played since the server started.",
+	})
+	connectionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chess_websocket_connections_active",
+		Help: "Open WebSocket connections.",
+	})
+	moveValidationErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chess_move_validation_errors_total",
+		Help: "Moves rejected as illegal or malformed.",
+	})
+	broadcastErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chess_broadcast_errors_total",
+		Help: "Game state broadcasts that failed to send.",
+	})
+
+	// gameDuration is in seconds, from bullet to long classical games.
+	gameDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "chess_game_duration_seconds",
+		Help:    "Time from the start to the end of finished games.",
+		Buckets: []float64{60, 180, 300, 600, 900, 1800, 3600, 7200},
+	})
+
+	// Active games are counted from the games map when scraped, so that
+	// the count cannot drift from the map's many delete sites.
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "chess_active_games",
+		Help: "Games currently held by the server.",
+	}, func() float64 {
+		gamesMutex.RLock()
+		defer gamesMutex.RUnlock()
+		return float64(len(games))
+	})
+)
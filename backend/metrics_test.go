@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrapeMetrics fetches srv's /metrics and returns each sample by name.
+func scrapeMetrics(t testing.TB, srv *httptest.Server) map[string]float64 {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(line, " ")
+		v, err := strconv.ParseFloat(value, 64)
+		if !found || err != nil {
+			t.Fatalf("bad sample line %q", line)
+		}
+		samples[name] = v
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	useConfig(t, nil)
+	useGames(t)
+	srv := newTestServer(t)
+	before := scrapeMetrics(t, srv)
+
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5", "Nf3")
+	send(t, black, map[string]string{"action": "move", "gameID": gameID, "move": "Ke3"})
+	readUntil(t, black, hasError)
+
+	during := scrapeMetrics(t, srv)
+	for name, want := range map[string]float64{
+		"chess_total_games_created":          1,
+		"chess_total_moves_made":             3,
+		"chess_move_validation_errors_total": 1,
+	} {
+		if got := during[name] - before[name]; got != want {
+			t.Errorf("%s rose by %v, want %v", name, got, want)
+		}
+	}
+	if during["chess_active_games"] != 1 {
+		t.Errorf("chess_active_games = %v, want 1", during["chess_active_games"])
+	}
+	if got := during["chess_websocket_connections_active"] - before["chess_websocket_connections_active"]; got != 2 {
+		t.Errorf("chess_websocket_connections_active rose by %v, want 2", got)
+	}
+
+	send(t, black, map[string]string{"action": "resign", "gameID": gameID})
+	readUntil(t, white, hasStatus("resigned"))
+	after := scrapeMetrics(t, srv)
+	if got := after["chess_game_duration_seconds_count"] - before["chess_game_duration_seconds_count"]; got != 1 {
+		t.Errorf("chess_game_duration_seconds_count rose by %v, want 1", got)
+	}
+	if after[`chess_game_duration_seconds_bucket{le="+Inf"}`] != after["chess_game_duration_seconds_count"] {
+		t.Errorf("+Inf bucket %v differs from count %v", after[`chess_game_duration_seconds_bucket{le="+Inf"}`], after["chess_game_duration_seconds_count"])
+	}
+}
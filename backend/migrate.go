@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+)
+
+// currentSchemaVersion is the gameRecord version this server writes.
+const currentSchemaVersion = 2
+
+// migrations upgrades a stored game from the version it is indexed by to
+// the next one.
+var migrations = map[int]func([]byte) ([]byte, error){
+	1: migrateV1toV2,
+}
+
+// migrateV1toV2 marks a record from before versioning as version 2, which
+// only added schemaVersion. Every other field is kept as it was, including
+// any this server does not know.
+func migrateV1toV2(data []byte) ([]byte, error) {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	record["schemaVersion"] = json.RawMessage("2")
+	return json.Marshal(record)
+}
+
+// recordVersion returns the schema version of a stored game.
+func recordVersion(data []byte) (int, error) {
+	var record struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return 0, err
+	}
+	if record.SchemaVersion == 0 {
+		return 1, nil
+	}
+	return record.SchemaVersion, nil
+}
+
+// migrateGame brings a stored game up to currentSchemaVersion, reporting
+// whether it had to change.
+func migrateGame(data []byte) ([]byte, bool, error) {
+	from, err := recordVersion(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if from > currentSchemaVersion {
+		return nil, false, fmt.Errorf("schema version %d is newer than %d", from, currentSchemaVersion)
+	}
+	for version := from; version < currentSchemaVersion; version++ {
+		if data, err = migrations[version](data); err != nil {
+			return nil, false, fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+	}
+	return data, from < currentSchemaVersion, nil
+}
+
+// migrateStore upgrades every game in store to currentSchemaVersion and
+// then records that version in the store, so later starts skip the scan.
+// With dryRun set it only logs the games it would change. It returns how
+// many games needed migrating. A game that cannot be migrated is left as
+// it was and keeps the store's version from being moved on.
+func migrateStore(store Store, dryRun bool) (int, error) {
+	version, err := store.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version > currentSchemaVersion {
+		return 0, fmt.Errorf("store schema version %d is newer than %d", version, currentSchemaVersion)
+	}
+	if version == currentSchemaVersion {
+		return 0, nil
+	}
+	ids, err := store.ListGames()
+	if err != nil {
+		return 0, err
+	}
+
+	migrated, failed := 0, 0
+	for _, id := range ids {
+		data, err := store.LoadGameData(id)
+		var changed bool
+		if err == nil {
+			data, changed, err = migrateGame(data)
+		}
+		if err == nil && changed && !dryRun {
+			err = store.SaveGame(id, data)
+		}
+		if err != nil {
+			failed++
+			logger.Error("Error migrating game", slog.String("gameID", id), slog.Any("error", err))
+			continue
+		}
+		if changed {
+			migrated++
+			if dryRun {
+				logger.Info("Game would be migrated", slog.String("gameID", id))
+			}
+		}
+	}
+	if failed > 0 {
+		return migrated, fmt.Errorf("%d of %d games could not be migrated", failed, len(ids))
+	}
+	if !dryRun {
+		if err := store.SetSchemaVersion(currentSchemaVersion); err != nil {
+			return migrated, err
+		}
+	}
+	logger.Info("Store migrated", slog.Int("games", migrated), slog.Int("schemaVersion", currentSchemaVersion), slog.Bool("dryRun", dryRun))
+	return migrated, nil
+}
+
+// runMigrate runs the migrate command, "backend migrate [-dry-run]",
+// which migrates the store without starting the server, and returns the
+// exit status. The server also migrates the store whenever it starts.
+func runMigrate(store Store, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the games that would be migrated without writing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if _, err := migrateStore(store, *dryRun); err != nil {
+		logger.Error("Error migrating store", slog.Any("error", err))
+		return 1
+	}
+	return 0
+}
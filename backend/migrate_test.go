@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// v1Record returns storedTestGame as the server stored it before records
+// had a schema version, with a field from some other writer added.
+func v1Record(t *testing.T) []byte {
+	t.Helper()
+	data, err := encodeGame(storedTestGame(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err)
+	}
+	delete(record, "schemaVersion")
+	record["note"] = json.RawMessage(`"kept"`)
+	if data, err = json.Marshal(record); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMigrateV1toV2(t *testing.T) {
+	data, err := migrateV1toV2(v1Record(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["schemaVersion"] != float64(2) || record["note"] != "kept" {
+		t.Errorf("migrated record has version %v and note %v, want 2 and kept", record["schemaVersion"], record["note"])
+	}
+	game, err := decodeGame(data)
+	if err != nil {
+		t.Fatalf("decoding the migrated record: %v", err)
+	}
+	if want := storedTestGame(t); game.Game.Position().String() != want.Game.Position().String() || len(game.Players) != 2 {
+		t.Errorf("migrated game is at %s with %d players", game.Game.Position(), len(game.Players))
+	}
+}
+
+func TestMigrateStore(t *testing.T) {
+	useConfig(t, nil)
+	for name, store := range map[string]Store{
+		"memory": newMemoryStore(),
+		"redis":  newRedisStore(newFakeRedis(t)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			old := v1Record(t)
+			current, err := encodeGame(storedTestGame(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, data := range map[string][]byte{"old": old, "current": current} {
+				if err := store.SaveGame(id, data); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if n, err := migrateStore(store, true); err != nil || n != 1 {
+				t.Errorf("dry run = %d, %v; want 1 game to migrate", n, err)
+			}
+			if data, _ := store.LoadGameData("old"); !bytes.Equal(data, old) {
+				t.Errorf("the dry run rewrote the game: %s", data)
+			}
+			if version, _ := store.SchemaVersion(); version != 0 {
+				t.Errorf("schema version after the dry run = %d, want 0", version)
+			}
+
+			if n, err := migrateStore(store, false); err != nil || n != 1 {
+				t.Errorf("migrating = %d, %v; want 1 game migrated", n, err)
+			}
+			for _, id := range []string{"old", "current"} {
+				data, _ := store.LoadGameData(id)
+				if version, err := recordVersion(data); err != nil || version != currentSchemaVersion {
+					t.Errorf("game %s is at version %d, %v after migrating", id, version, err)
+				}
+			}
+			if version, _ := store.SchemaVersion(); version != currentSchemaVersion {
+				t.Errorf("schema version = %d, want %d", version, currentSchemaVersion)
+			}
+
+			// A store already at the current version is not scanned again.
+			if err := store.SaveGame("old", old); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := migrateStore(store, false); err != nil || n != 0 {
+				t.Errorf("migrating again = %d, %v; want nothing done", n, err)
+			}
+		})
+	}
+}
+
+func TestMigrateNewerStore(t *testing.T) {
+	store := newMemoryStore()
+	store.SetSchemaVersion(currentSchemaVersion + 1)
+	if _, err := migrateStore(store, false); err == nil {
+		t.Error("migrated a store from a newer server")
+	}
+
+	newer := []byte(`{"schemaVersion":3,"startingFen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"}`)
+	if _, err := decodeGame(newer); err == nil {
+		t.Error("decoded a game from a newer server")
+	}
+}
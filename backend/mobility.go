@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// mobilityHistoryLength is how many moves of mobility totals a game keeps.
+const mobilityHistoryLength = 10
+
+type PieceMobility struct {
+	Knights int `json:"knights"`
+	Bishops int `json:"bishops"`
+	Rooks   int `json:"rooks"`
+	Queen   int `json:"queen"`
+	King    int `json:"king"`
+	Pawns   int `json:"pawns"`
+	Total   int `json:"total"`
+}
+
+type MobilitySnapshot struct {
+	White int `json:"white"`
+	Black int `json:"black"`
+}
+
+// withTurn returns pos with color to move, clearing any en passant square
+// that only made sense for the original side.
+func withTurn(pos *chess.Position, color chess.Color) *chess.Position {
+	if pos.Turn() == color {
+		return pos
+	}
+	fields := strings.Fields(pos.String())
+	fields[1] = color.String()
+	fields[3] = "-"
+	option, err := chess.FEN(strings.Join(fields, " "))
+	if err != nil {
+		return pos
+	}
+	return chess.NewGame(option).Position()
+}
+
+func pieceMobility(pos *chess.Position, color chess.Color) PieceMobility {
+	pos = withTurn(pos, color)
+	board := pos.Board()
+	var mobility PieceMobility
+	for _, m := range pos.ValidMoves() {
+		switch board.Piece(m.S1()).Type() {
+		case chess.Knight:
+			mobility.Knights++
+		case chess.Bishop:
+			mobility.Bishops++
+		case chess.Rook:
+			mobility.Rooks++
+		case chess.Queen:
+			mobility.Queen++
+		case chess.King:
+			mobility.King++
+		case chess.Pawn:
+			mobility.Pawns++
+		}
+		mobility.Total++
+	}
+	return mobility
+}
+
+// recordMobility appends the current mobility totals to the game's history.
+// The caller must hold gamesMutex.
+func recordMobility(game *Game) {
+	pos := game.Game.Position()
+	game.MobilityHistory = append(game.MobilityHistory, MobilitySnapshot{
+		White: pieceMobility(pos, chess.White).Total,
+		Black: pieceMobility(pos, chess.Black).Total,
+	})
+	if len(game.MobilityHistory) > mobilityHistoryLength {
+		game.MobilityHistory = game.MobilityHistory[len(game.MobilityHistory)-mobilityHistoryLength:]
+	}
+}
+
+func getPieceMobility(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to query non-existent game", slog.String("gameID", gameID))
+		return
+	}
+	pos := game.Game.Position()
+	history := append([]MobilitySnapshot{}, game.MobilityHistory...)
+	gamesMutex.Unlock()
+
+	white := pieceMobility(pos, chess.White)
+	black := pieceMobility(pos, chess.Black)
+	advantage := map[string]interface{}{"side": "equal", "total": 0}
+	if white.Total > black.Total {
+		advantage = map[string]interface{}{"side": "white", "total": white.Total - black.Total}
+	} else if black.Total > white.Total {
+		advantage = map[string]interface{}{"side": "black", "total": black.Total - white.Total}
+	}
+
+	sendJSON(ws, map[string]interface{}{
+		"white":             white,
+		"black":             black,
+		"mobilityAdvantage": advantage,
+		"history":           history,
+	})
+	logger.Debug("Piece mobility sent", slog.String("gameID", gameID))
+}
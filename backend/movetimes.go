@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// recordMoveTime adds the time taken over the move just made to
+// game.MoveTimes and starts timing the next one. The caller must hold
+// gamesMutex.
+func recordMoveTime(game *Game, now time.Time) {
+	game.MoveTimes = append(game.MoveTimes, now.Sub(game.MoveStartedAt))
+	game.MoveStartedAt = now
+}
+
+// averageMoveTimes returns each side's average time per move, where first
+// is the side that made the first move in times.
+func averageMoveTimes(times []time.Duration, first chess.Color) (white, black time.Duration) {
+	var totals [2]time.Duration
+	var counts [2]int
+	for i, t := range times {
+		side := clockIndex(first)
+		if i%2 == 1 {
+			side = clockIndex(first.Other())
+		}
+		totals[side] += t
+		counts[side]++
+	}
+	for side := range totals {
+		if counts[side] > 0 {
+			totals[side] /= time.Duration(counts[side])
+		}
+	}
+	return totals[0], totals[1]
+}
+
+// formatMoveTime gives a move time to the millisecond, such as "1.234s".
+func formatMoveTime(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// pgnElapsed formats d for a PGN %emt annotation, as H:MM:SS.s.
+func pgnElapsed(d time.Duration) string {
+	d = d.Round(100 * time.Millisecond)
+	hours := int(d / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := (d % time.Minute).Seconds()
+	return fmt.Sprintf("%d:%02d:%04.1f", hours, minutes, seconds)
+}
+
+// pgnMovetext writes game's moves for a PGN export, annotating each with
+// the time it took as a {[%emt ...]} comment. notnil/chess cannot add
+// comments to its own export, so the moves are numbered here.
+func pgnMovetext(game *Game) string {
+	var sb strings.Builder
+	positions := game.Game.Positions()
+	moveNumber := 1
+	if fields := strings.Fields(game.StartingFEN); len(fields) == 6 {
+		fmt.Sscan(fields[5], &moveNumber)
+	}
+	for i, move := range game.Game.Moves() {
+		turn := positions[i].Turn()
+		switch {
+		case turn == chess.White:
+			fmt.Fprintf(&sb, "%d. ", moveNumber)
+		case i == 0:
+			fmt.Fprintf(&sb, "%d... ", moveNumber)
+		}
+		sb.WriteString(chess.AlgebraicNotation{}.Encode(positions[i], move))
+		if i < len(game.MoveTimes) {
+			fmt.Fprintf(&sb, " {[%%emt %s]}", pgnElapsed(game.MoveTimes[i]))
+		}
+		sb.WriteString(" ")
+		if turn == chess.Black {
+			moveNumber++
+		}
+	}
+	sb.WriteString(game.Game.Outcome().String())
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestAverageMoveTimes(t *testing.T) {
+	s := time.Second
+	for _, c := range []struct {
+		name         string
+		times        []time.Duration
+		first        chess.Color
+		white, black time.Duration
+	}{
+		{"odd number of moves", []time.Duration{1 * s, 4 * s, 3 * s}, chess.White, 2 * s, 4 * s},
+		{"odd number from black", []time.Duration{1 * s, 4 * s, 3 * s}, chess.Black, 4 * s, 2 * s},
+		{"one move", []time.Duration{5 * s}, chess.White, 5 * s, 0},
+		{"no moves", nil, chess.White, 0, 0},
+		{"rounds down", []time.Duration{1500 * time.Millisecond, s, 1 * time.Millisecond}, chess.White, 750500 * time.Microsecond, s},
+	} {
+		white, black := averageMoveTimes(c.times, c.first)
+		if white != c.white || black != c.black {
+			t.Errorf("%s: averages = %v and %v, want %v and %v", c.name, white, black, c.white, c.black)
+		}
+	}
+}
+
+func TestPGNMovetext(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 12"
+	game := &Game{
+		Game:        gameFromFEN(t, fen),
+		StartingFEN: fen,
+		MoveTimes:   []time.Duration{1234 * time.Millisecond, 61 * time.Second, time.Hour + 5*time.Second},
+	}
+	for _, move := range []string{"e5", "Nf3", "Nc6"} {
+		if err := game.Game.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "12... e5 {[%emt 0:00:01.2]} 13. Nf3 {[%emt 0:01:01.0]} Nc6 {[%emt 1:00:05.0]} *"
+	if got := pgnMovetext(game); got != want {
+		t.Errorf("movetext = %q, want %q", got, want)
+	}
+}
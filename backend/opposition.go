@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type OppositionAnalysis struct {
+	OppositionType string   `json:"oppositionType"`
+	HasOpposition  string   `json:"hasOpposition,omitempty"`
+	KeySquares     []string `json:"keySquares"`
+	Recommendation string   `json:"recommendation"`
+}
+
+// oppositionType names the relation between the two kings: they stand in
+// opposition when an odd number of squares separates them on a file, rank
+// or diagonal.
+func oppositionType(white, black chess.Square) string {
+	df := int(white.File()) - int(black.File())
+	dr := int(white.Rank()) - int(black.Rank())
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	switch {
+	case df == 0 || dr == 0:
+		gap := df + dr
+		if gap == 2 {
+			return "direct"
+		}
+		if gap%2 == 0 {
+			return "distant"
+		}
+	case df == dr && df%2 == 0:
+		return "diagonal"
+	}
+	return "none"
+}
+
+// keySquares returns the squares which, once reached by color's king,
+// guarantee the pawn on sq promotes whoever is to move. Squares on the
+// pawn's file come first.
+func keySquares(sq chess.Square, color chess.Color) []chess.Square {
+	forward := 1
+	if color == chess.Black {
+		forward = -1
+	}
+	file := int(sq.File())
+	if file == 0 || file == 7 {
+		// A rook pawn only wins if the king reaches the adjacent file
+		// in front of it.
+		adjacent := 1
+		if file == 7 {
+			adjacent = 6
+		}
+		return []chess.Square{
+			chess.NewSquare(chess.File(adjacent), promotionRank(color)-chess.Rank(forward)),
+			chess.NewSquare(chess.File(adjacent), promotionRank(color)),
+		}
+	}
+
+	ranks := []int{int(sq.Rank()) + 2*forward}
+	if analysis.RelativeRank(sq, color) >= 4 {
+		ranks = []int{int(sq.Rank()) + forward, int(sq.Rank()) + 2*forward}
+	}
+	var squares []chess.Square
+	for _, rank := range ranks {
+		if rank < 0 || rank > 7 {
+			continue
+		}
+		for _, f := range []int{file, file - 1, file + 1} {
+			squares = append(squares, chess.NewSquare(chess.File(f), chess.Rank(rank)))
+		}
+	}
+	return squares
+}
+
+func promotionRank(color chess.Color) chess.Rank {
+	if color == chess.Black {
+		return chess.Rank1
+	}
+	return chess.Rank8
+}
+
+// leadingPawn picks the side trying to promote and its most advanced pawn,
+// preferring the side with more pawns.
+func leadingPawn(board *chess.Board) (chess.Color, chess.Square) {
+	white := analysis.PawnSquares(board, chess.White)
+	black := analysis.PawnSquares(board, chess.Black)
+	color, pawns := chess.White, white
+	if len(black) > len(white) {
+		color, pawns = chess.Black, black
+	}
+	best := chess.NoSquare
+	for _, sq := range pawns {
+		if best == chess.NoSquare || analysis.RelativeRank(sq, color) > analysis.RelativeRank(best, color) {
+			best = sq
+		}
+	}
+	return color, best
+}
+
+func detectOpposition(pos *chess.Position) OppositionAnalysis {
+	result := OppositionAnalysis{OppositionType: "none", KeySquares: []string{}}
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	if nonPawnMaterial(white)+nonPawnMaterial(black) != 0 {
+		result.Recommendation = "Opposition analysis only applies to king and pawn endgames"
+		return result
+	}
+
+	board := pos.Board()
+	whiteKing := analysis.KingSquare(board, chess.White)
+	blackKing := analysis.KingSquare(board, chess.Black)
+	if whiteKing == chess.NoSquare || blackKing == chess.NoSquare {
+		return result
+	}
+	result.OppositionType = oppositionType(whiteKing, blackKing)
+	if result.OppositionType != "none" {
+		// The side that just moved into opposition holds it.
+		result.HasOpposition = colorName(pos.Turn().Other())
+	}
+
+	color, pawn := leadingPawn(board)
+	if pawn == chess.NoSquare {
+		result.Recommendation = "No pawns are left to promote"
+		return result
+	}
+	king := analysis.KingSquare(board, color)
+	target := chess.NoSquare
+	for _, sq := range keySquares(pawn, color) {
+		result.KeySquares = append(result.KeySquares, sq.String())
+		if target == chess.NoSquare || squareDistance(king, sq) < squareDistance(king, target) {
+			target = sq
+		}
+	}
+
+	side, defender := "White", "Black"
+	if color == chess.Black {
+		side, defender = defender, side
+	}
+	switch {
+	case squareDistance(king, target) == 0:
+		result.Recommendation = fmt.Sprintf("%s king stands on the key square %s; the pawn should promote", side, target)
+	case result.HasOpposition == colorName(color.Other()):
+		result.Recommendation = fmt.Sprintf("%s holds the opposition; %s king should outflank to reach %s", defender, side, target)
+	default:
+		result.Recommendation = fmt.Sprintf("%s king should aim for %s to shepherd the pawn", side, target)
+	}
+	return result
+}
+
+func getOppositionAnalysis(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, detectOpposition(pos))
+	logger.Debug("Opposition analysis sent", slog.String("gameID", gameID))
+}
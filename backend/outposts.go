@@ -0,0 +1,83 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// bishopOutpostReach is how many squares a bishop must sweep from an
+// outpost for the square to suit it.
+const bishopOutpostReach = 7
+
+type OutpostSquares struct {
+	KnightOutposts []string `json:"knightOutposts"`
+	BishopOutposts []string `json:"bishopOutposts"`
+}
+
+// isOutpost reports whether sq, on color's 4th to 6th rank, is guarded by
+// one of color's pawns and can never be attacked by an enemy pawn.
+func isOutpost(board *chess.Board, sq chess.Square, color chess.Color) bool {
+	rank := analysis.RelativeRank(sq, color)
+	if rank < 3 || rank > 5 || board.Piece(sq).Type() == chess.Pawn {
+		return false
+	}
+	if !analysis.PawnAttacks(board, sq, color) {
+		return false
+	}
+	for _, enemy := range analysis.PawnSquares(board, color.Other()) {
+		df := int(enemy.File()) - int(sq.File())
+		if (df == -1 || df == 1) && analysis.RelativeRank(enemy, color) > rank {
+			return false
+		}
+	}
+	return true
+}
+
+// findOutposts lists color's outposts in square order.
+func findOutposts(pos *chess.Position, color chess.Color) []string {
+	board := pos.Board()
+	outposts := []string{}
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if isOutpost(board, sq, color) {
+			outposts = append(outposts, sq.String())
+		}
+	}
+	return outposts
+}
+
+// classifyOutposts splits color's outposts between the pieces they suit: a
+// knight wants one in the enemy half, a bishop one with open diagonals.
+// A square can suit both.
+func classifyOutposts(pos *chess.Position, color chess.Color) OutpostSquares {
+	board := pos.Board()
+	bishop := chess.WhiteBishop
+	if color == chess.Black {
+		bishop = chess.BlackBishop
+	}
+	result := OutpostSquares{KnightOutposts: []string{}, BishopOutposts: []string{}}
+	for _, name := range findOutposts(pos, color) {
+		sq, _ := parseSquare(name)
+		if analysis.RelativeRank(sq, color) >= 4 {
+			result.KnightOutposts = append(result.KnightOutposts, sq.String())
+		}
+		if len(analysis.AttackedSquares(board, sq, bishop)) >= bishopOutpostReach {
+			result.BishopOutposts = append(result.BishopOutposts, sq.String())
+		}
+	}
+	return result
+}
+
+func getOutpostSquares(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, map[string]interface{}{
+		"white": classifyOutposts(pos, chess.White),
+		"black": classifyOutposts(pos, chess.Black),
+	})
+	logger.Debug("Outpost squares sent", slog.String("gameID", gameID))
+}
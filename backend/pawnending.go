@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// pawnEndingDepth is how many half-moves the pawn ending search looks ahead.
+const pawnEndingDepth = 4
+
+// zugzwangMargin is how much better, in pawns, passing must be before the
+// side to move counts as being in zugzwang.
+const zugzwangMargin = 1.0
+
+type ZugzwangCheck struct {
+	InZugzwang         bool   `json:"inZugzwang"`
+	EvaluationWithMove string `json:"evaluation_with_move"`
+	EvaluationNullMove string `json:"evaluation_null_move"`
+	KeySquare          string `json:"key_square,omitempty"`
+	Advice             string `json:"advice"`
+}
+
+// evaluatePawnEnding scores a king and pawn position in pawns for the side
+// to move. Beyond material it rewards passed pawns by how far they have
+// come, passers the enemy king cannot catch and a king standing on one of
+// its pawn's key squares, which wins whoever is to move.
+func evaluatePawnEnding(pos *chess.Position) float64 {
+	board := pos.Board()
+	score := 0.0
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		side := 0.0
+		for _, piece := range board.SquareMap() {
+			if piece.Color() == color && piece.Type() != chess.King {
+				side += float64(analysis.PieceValue(piece.Type())) / 100
+			}
+		}
+		king := analysis.KingSquare(board, color)
+		for _, sq := range analysis.PawnSquares(board, color) {
+			if analysis.IsPassedPawn(board, sq, color) {
+				side += 0.1 * float64(analysis.RelativeRank(sq, color))
+			}
+			for _, key := range keySquares(sq, color) {
+				if key == king {
+					side += 3
+					break
+				}
+			}
+		}
+		if _, ok := unstoppablePasser(pos, color); ok {
+			side += 4
+		}
+		if color == pos.Turn() {
+			score += side
+		} else {
+			score -= side
+		}
+	}
+	return score
+}
+
+// searchPawnEnding is a plain alpha-beta search over evaluatePawnEnding.
+func searchPawnEnding(pos *chess.Position, depth int, alpha, beta float64) float64 {
+	switch pos.Status() {
+	case chess.Checkmate:
+		return -100
+	case chess.Stalemate:
+		return 0
+	}
+	if depth == 0 {
+		return evaluatePawnEnding(pos)
+	}
+	for _, m := range pos.ValidMoves() {
+		score := -searchPawnEnding(pos.Update(m), depth-1, -beta, -alpha)
+		if score >= beta {
+			return beta
+		}
+		alpha = math.Max(alpha, score)
+	}
+	return alpha
+}
+
+// detectZugzwang compares the search result for the side to move with the
+// result after a null move. If passing would be clearly better, the
+// obligation to move is what hurts.
+func detectZugzwang(pos *chess.Position) ZugzwangCheck {
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	if nonPawnMaterial(white)+nonPawnMaterial(black) != 0 {
+		return ZugzwangCheck{Advice: "Zugzwang detection only covers pawn endgames"}
+	}
+	mover := pos.Turn()
+	if pos.Status() != chess.NoMethod || analysis.KingSquare(pos.Board(), mover) == chess.NoSquare {
+		return ZugzwangCheck{Advice: "The game is over"}
+	}
+
+	withMove := searchPawnEnding(pos, pawnEndingDepth, -1000, 1000)
+	result := ZugzwangCheck{EvaluationWithMove: fmt.Sprintf("%+.1f", withMove)}
+	if analysis.AttackCounts(pos.Board(), mover.Other())[analysis.KingSquare(pos.Board(), mover)] > 0 {
+		// A side in check cannot pass.
+		result.EvaluationNullMove = result.EvaluationWithMove
+		result.Advice = "The side to move is in check, so passing is not an option"
+		return result
+	}
+	// After the null move the opponent is to move, so its score is negated
+	// back to the original mover's point of view.
+	nullMove := -searchPawnEnding(withTurn(pos, mover.Other()), pawnEndingDepth, -1000, 1000)
+	result.EvaluationNullMove = fmt.Sprintf("%+.1f", nullMove)
+
+	if nullMove-withMove < zugzwangMargin {
+		result.Advice = fmt.Sprintf("%s is not in zugzwang; having the move costs nothing here", sideName(mover))
+		return result
+	}
+	result.InZugzwang = true
+	key := analysis.KingSquare(pos.Board(), mover.Other())
+	result.KeySquare = key.String()
+	result.Advice = fmt.Sprintf("Keep your king on %s and wait for %s to deteriorate their position", key, sideName(mover))
+	return result
+}
+
+func getZugzwangPositions(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, detectZugzwang(pos))
+	logger.Debug("Zugzwang check sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type PawnStructureSide struct {
+	Isolated int `json:"isolated"`
+	Doubled  int `json:"doubled"`
+	Backward int `json:"backward"`
+	Passed   int `json:"passed"`
+}
+
+type PawnStructure struct {
+	White     PawnStructureSide `json:"white"`
+	Black     PawnStructureSide `json:"black"`
+	Advantage string            `json:"advantage"`
+}
+
+func analyzePawnSide(board *chess.Board, color chess.Color) PawnStructureSide {
+	var side PawnStructureSide
+	files := analysis.PawnFiles(board, color)
+	for _, n := range files {
+		if n > 1 {
+			side.Doubled += n - 1
+		}
+	}
+
+	pawns := analysis.PawnSquares(board, color)
+	for _, sq := range pawns {
+		f := int(sq.File())
+		left := f > 0 && files[f-1] > 0
+		right := f < 7 && files[f+1] > 0
+		if !left && !right {
+			side.Isolated++
+		} else {
+			// A backward pawn has fallen behind its neighbours and cannot
+			// safely advance because an enemy pawn guards the stop square.
+			behind := true
+			for _, other := range pawns {
+				df := int(other.File()) - f
+				if (df == -1 || df == 1) && analysis.RelativeRank(other, color) <= analysis.RelativeRank(sq, color) {
+					behind = false
+					break
+				}
+			}
+			if behind {
+				stop := int(sq.Rank()) + 1
+				if color == chess.Black {
+					stop = int(sq.Rank()) - 1
+				}
+				if stop >= 0 && stop <= 7 && analysis.PawnAttacks(board, chess.NewSquare(sq.File(), chess.Rank(stop)), color.Other()) {
+					side.Backward++
+				}
+			}
+		}
+		if analysis.IsPassedPawn(board, sq, color) {
+			side.Passed++
+		}
+	}
+	return side
+}
+
+func (s PawnStructureSide) score() int {
+	return 2*s.Passed - s.Isolated - s.Doubled - s.Backward
+}
+
+func analyzePawnStructure(pos *chess.Position) PawnStructure {
+	board := pos.Board()
+	structure := PawnStructure{
+		White:     analyzePawnSide(board, chess.White),
+		Black:     analyzePawnSide(board, chess.Black),
+		Advantage: "equal",
+	}
+	if w, b := structure.White.score(), structure.Black.score(); w > b {
+		structure.Advantage = "white"
+	} else if b > w {
+		structure.Advantage = "black"
+	}
+	return structure
+}
+
+func getPawnStructure(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, analyzePawnStructure(pos))
+	logger.Debug("Pawn structure sent", slog.String("gameID", gameID))
+}
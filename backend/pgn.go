@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// pgnName is "?" for an empty seat, as the PGN standard asks.
+func pgnName(game *Game, color chess.Color) string {
+	if name := playerName(game, color); name != "" {
+		return name
+	}
+	return "?"
+}
+
+// gamePGN exports game as PGN with its header tags. The caller must hold
+// gamesMutex, as the tags are set on game.Game.
+func gamePGN(game *Game) string {
+	game.Game.AddTagPair("Event", "Casual game")
+	game.Game.AddTagPair("Date", game.CreatedAt.Format("2006.01.02"))
+	game.Game.AddTagPair("White", pgnName(game, chess.White))
+	game.Game.AddTagPair("Black", pgnName(game, chess.Black))
+	game.Game.AddTagPair("Result", game.Game.Outcome().String())
+	if game.Variant == "chess960" {
+		game.Game.AddTagPair("Variant", "Chess960")
+	}
+	if game.StartingFEN != chess.StartingPosition().String() {
+		game.Game.AddTagPair("SetUp", "1")
+		game.Game.AddTagPair("FEN", game.StartingFEN)
+	}
+	// Keep the library's tag pairs but write the moves with their times.
+	header, _, _ := strings.Cut(game.Game.String(), "\n\n")
+	return header + "\n\n" + pgnMovetext(game)
+}
+
+func getPGN(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	pgn := gamePGN(game)
+	gamesMutex.Unlock()
+
+	sendJSON(ws, map[string]string{"pgn": pgn})
+	logger.Debug("PGN sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/rohit746/chess/backend/plans"
+)
+
+func getPlanSuggestion(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, plans.Suggest(pos))
+	logger.Debug("Plan suggestion sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,162 @@
+package plans
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+func tradeWhenAheadRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.material(color)-b.material(color.Other()) < clearAdvantage {
+		return Suggestion{}, false
+	}
+	return Suggestion{Plan: "You are ahead in material; trade pieces, not pawns, to head for a winning endgame."}, true
+}
+
+func avoidTradesWhenBehindRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.material(color.Other())-b.material(color) < clearAdvantage {
+		return Suggestion{}, false
+	}
+	return Suggestion{Plan: "You are behind in material; avoid trades and keep pieces on to create complications."}, true
+}
+
+func queenlessRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || len(b.find(chess.Queen, chess.White))+len(b.find(chess.Queen, chess.Black)) > 0 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:       "With the queens off your king is safe enough to help; bring it towards the centre earlier than usual.",
+		KeySquares: []string{b.king(color).String()},
+	}, true
+}
+
+func kingActivityRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.isEndgame() {
+		return Suggestion{}, false
+	}
+	kings := b.find(chess.King, color)
+	if len(kings) != 1 {
+		return Suggestion{}, false
+	}
+	f, r := int(kings[0].File()), int(kings[0].Rank())
+	if f >= 2 && f <= 5 && r >= 2 && r <= 5 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:       "In the endgame the king is a strong piece; march it towards the centre.",
+		KeySquares: []string{chess.D4.String(), chess.E4.String(), chess.D5.String(), chess.E5.String()},
+	}, true
+}
+
+func oppositeBishopsDrawRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.isEndgame() || !b.oppositeBishops() || !b.onlyHas(color, chess.Bishop) || !b.onlyHas(color.Other(), chess.Bishop) {
+		return Suggestion{}, false
+	}
+	if b.material(color) >= b.material(color.Other()) {
+		return Suggestion{}, false
+	}
+	bishop := b.find(chess.Bishop, color)[0]
+	return Suggestion{
+		Plan:         fmt.Sprintf("Opposite-coloured bishop endings are drawish even a pawn or two down; blockade the enemy pawns on the colour of your bishop on %s.", bishop),
+		TargetPieces: []string{pieceLabel(b.pieces[bishop], bishop)},
+	}, true
+}
+
+func outsidePassedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.isEndgame() {
+		return Suggestion{}, false
+	}
+	for _, sq := range b.passedPawns(color) {
+		if sq.File() > chess.FileB && sq.File() < chess.FileG {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("Your outside passed pawn on %s will drag the enemy king away; advance it, then take the pawns it leaves behind.", sq),
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func squareOfThePawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.onlyHas(color) {
+		return Suggestion{}, false
+	}
+	king := b.king(color)
+	for _, sq := range b.passedPawns(color.Other()) {
+		queening, _ := square(int(sq.File()), 7, color.Other())
+		steps := 7 - rank(sq, color.Other())
+		if rank(sq, color.Other()) == 1 {
+			steps--
+		}
+		// It is color's move, so the king may be one step further away.
+		if distance(king, queening) <= steps+1 {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your king is inside the square of the pawn on %s; head for %s to catch it.", sq, queening),
+				KeySquares:   []string{queening.String()},
+				TargetPieces: []string{sq.String()},
+			}, true
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("Your king cannot catch the pawn on %s; you need counterplay of your own at once.", sq),
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func pawnEndingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.onlyHas(color) || !b.onlyHas(color.Other()) || len(b.find(chess.Pawn, color)) == 0 {
+		return Suggestion{}, false
+	}
+	king, enemy := b.king(color), b.king(color.Other())
+	return Suggestion{
+		Plan:       fmt.Sprintf("In king and pawn endings every tempo counts: take the opposition against the king on %s and head for the key squares in front of your pawns.", enemy),
+		KeySquares: []string{king.String(), enemy.String()},
+	}, true
+}
+
+func activeRookEndingRule(b *board, color chess.Color) (Suggestion, bool) {
+	rooks := b.find(chess.Rook, color)
+	if len(rooks) != 1 || len(b.find(chess.Rook, color.Other())) != 1 || !b.onlyHas(color, chess.Rook) || !b.onlyHas(color.Other(), chess.Rook) {
+		return Suggestion{}, false
+	}
+	if rank(rooks[0], color) > 1 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("Activate your rook on %s; in rook endings a passive rook loses, so attack pawns or cut off the king.", rooks[0]),
+		TargetPieces: []string{pieceLabel(b.pieces[rooks[0]], rooks[0])},
+	}, true
+}
+
+func cutOffKingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.isEndgame() || len(b.find(chess.Rook, color)) == 0 || len(b.find(chess.Queen, color.Other())) > 0 {
+		return Suggestion{}, false
+	}
+	enemy := b.king(color.Other())
+	for _, sq := range b.passedPawns(color) {
+		df := int(enemy.File()) - int(sq.File())
+		if df > -2 && df < 2 {
+			continue
+		}
+		f := int(sq.File()) + sign(df)
+		cut, _ := square(f, 0, color)
+		return Suggestion{
+			Plan:       fmt.Sprintf("Cut the enemy king off along the %s-file with your rook so it cannot reach your pawn on %s.", fileName(f), sq),
+			KeySquares: []string{cut.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func tradePawnsWhenBehindRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.isEndgame() || b.material(color.Other())-b.material(color) < analysis.PieceValues[chess.Pawn] {
+		return Suggestion{}, false
+	}
+	if len(b.find(chess.Pawn, color)) == 0 || len(b.find(chess.Pawn, color.Other())) == 0 {
+		return Suggestion{}, false
+	}
+	return Suggestion{Plan: "When behind in an endgame, trade pawns: the fewer that remain, the better your chances of a draw."}, true
+}
@@ -0,0 +1,285 @@
+package plans
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// castled reports whether color's king sits on its back rank away from the
+// centre files.
+func (b *board) castled(color chess.Color) bool {
+	king := b.king(color)
+	if king == chess.NoSquare || rank(king, color) != 0 {
+		return false
+	}
+	return king.File() <= chess.FileC || king.File() >= chess.FileF
+}
+
+// shieldGaps returns the files beside and in front of color's castled king
+// with no pawn of its own on the two ranks in front of it.
+func (b *board) shieldGaps(color chess.Color) []int {
+	if !b.castled(color) {
+		return nil
+	}
+	king := b.king(color)
+	var gaps []int
+	for f := max(int(king.File())-1, 0); f <= min(int(king.File())+1, 7); f++ {
+		covered := false
+		for r := 1; r <= 2; r++ {
+			sq, _ := square(f, r, color)
+			covered = covered || b.own(sq, chess.Pawn, color)
+		}
+		if !covered {
+			gaps = append(gaps, f)
+		}
+	}
+	return gaps
+}
+
+// backRankWeak reports whether color's king is on an otherwise open back
+// rank, guarded by rooks at most, with every square in front of it blocked
+// by its own pieces or covered by the enemy.
+func (b *board) backRankWeak(color chess.Color) bool {
+	king := b.king(color)
+	if king == chess.NoSquare || rank(king, color) != 0 {
+		return false
+	}
+	for f := 0; f < 8; f++ {
+		sq, _ := square(f, 0, color)
+		if p, ok := b.pieces[sq]; ok && p.Color() == color && p.Type() != chess.King && p.Type() != chess.Rook {
+			return false
+		}
+	}
+	for f := max(int(king.File())-1, 0); f <= min(int(king.File())+1, 7); f++ {
+		sq, _ := square(f, 1, color)
+		if p, ok := b.pieces[sq]; ok && p.Color() == color {
+			continue
+		}
+		if b.attacks[color.Other()][sq] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *board) hasHeavyPiece(color chess.Color) bool {
+	return len(b.find(chess.Rook, color)) > 0 || len(b.find(chess.Queen, color)) > 0
+}
+
+func castleRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	rights := b.pos.CastleRights()
+	switch {
+	case rights.CanCastle(color, chess.KingSide):
+		sq, _ := square(6, 0, color)
+		return Suggestion{Plan: "Castle kingside to get your king to safety and connect your rooks.", KeySquares: []string{sq.String()}}, true
+	case rights.CanCastle(color, chess.QueenSide):
+		sq, _ := square(2, 0, color)
+		return Suggestion{Plan: "Castle queenside to get your king to safety and connect your rooks.", KeySquares: []string{sq.String()}}, true
+	}
+	return Suggestion{}, false
+}
+
+func centralKingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	kings := b.find(chess.King, color.Other())
+	if len(kings) != 1 || rank(kings[0], color.Other()) > 1 {
+		return Suggestion{}, false
+	}
+	if f := kings[0].File(); f < chess.FileD || f > chess.FileE {
+		return Suggestion{}, false
+	}
+	if b.pos.CastleRights().CanCastle(color.Other(), chess.KingSide) || b.pos.CastleRights().CanCastle(color.Other(), chess.QueenSide) {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         "The enemy king is stuck in the centre; open central files with pawn breaks before it escapes.",
+		KeySquares:   []string{chess.D4.String(), chess.E4.String(), chess.D5.String(), chess.E5.String()},
+		TargetPieces: []string{pieceLabel(b.pieces[kings[0]], kings[0])},
+	}, true
+}
+
+func ownBackRankRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.hasHeavyPiece(color.Other()) || !b.backRankWeak(color) {
+		return Suggestion{}, false
+	}
+	king := b.king(color)
+	luft, _ := square(int(king.File()), 2, color)
+	return Suggestion{
+		Plan:       fmt.Sprintf("Your king on %s has no escape square; make luft before a back-rank check decides the game.", king),
+		KeySquares: []string{luft.String()},
+	}, true
+}
+
+func backRankRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.hasHeavyPiece(color) || !b.backRankWeak(color.Other()) {
+		return Suggestion{}, false
+	}
+	king := b.king(color.Other())
+	return Suggestion{
+		Plan:         fmt.Sprintf("The enemy king on %s is boxed in on its back rank; aim your rooks and queen at that rank.", king),
+		KeySquares:   []string{king.String()},
+		TargetPieces: []string{pieceLabel(b.pieces[king], king)},
+	}, true
+}
+
+func ownKingShieldRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || len(b.find(chess.Queen, color.Other())) == 0 {
+		return Suggestion{}, false
+	}
+	gaps := b.shieldGaps(color)
+	if len(gaps) == 0 {
+		return Suggestion{}, false
+	}
+	var squares []string
+	for _, f := range gaps {
+		sq, _ := square(f, 1, color)
+		squares = append(squares, sq.String())
+	}
+	return Suggestion{
+		Plan:       fmt.Sprintf("Your king's pawn cover has a gap on %s; keep a defender nearby and avoid further pawn moves there.", strings.Join(squares, ", ")),
+		KeySquares: squares,
+	}, true
+}
+
+func weakenedKingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || len(b.find(chess.Queen, color)) == 0 {
+		return Suggestion{}, false
+	}
+	gaps := b.shieldGaps(color.Other())
+	if len(gaps) == 0 {
+		return Suggestion{}, false
+	}
+	var squares []string
+	for _, f := range gaps {
+		sq, _ := square(f, 1, color.Other())
+		squares = append(squares, sq.String())
+	}
+	king := b.king(color.Other())
+	return Suggestion{
+		Plan:         fmt.Sprintf("The pawns in front of the enemy king are broken at %s; bring your queen and rooks against it.", strings.Join(squares, ", ")),
+		KeySquares:   squares,
+		TargetPieces: []string{pieceLabel(b.pieces[king], king)},
+	}, true
+}
+
+func undefendedKingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || len(b.find(chess.Queen, color)) == 0 || !b.castled(color.Other()) {
+		return Suggestion{}, false
+	}
+	king := b.king(color.Other())
+	for sq, p := range b.pieces {
+		if p.Color() == color.Other() && p.Type() != chess.Pawn && p.Type() != chess.King && distance(sq, king) <= 2 {
+			return Suggestion{}, false
+		}
+	}
+	front, _ := square(int(king.File()), 2, color.Other())
+	return Suggestion{
+		Plan:         fmt.Sprintf("No enemy piece guards the king on %s; transfer your pieces towards %s.", king, front),
+		KeySquares:   []string{front.String()},
+		TargetPieces: []string{pieceLabel(b.pieces[king], king)},
+	}, true
+}
+
+func oppositeCastlingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || !b.castled(color) || !b.castled(color.Other()) {
+		return Suggestion{}, false
+	}
+	own, enemy := b.king(color), b.king(color.Other())
+	if (own.File() <= chess.FileC) == (enemy.File() <= chess.FileC) {
+		return Suggestion{}, false
+	}
+	var storm []string
+	for f := max(int(enemy.File())-1, 0); f <= min(int(enemy.File())+1, 7); f++ {
+		for _, sq := range b.find(chess.Pawn, color) {
+			if int(sq.File()) == f {
+				storm = append(storm, sq.String())
+			}
+		}
+	}
+	if len(storm) == 0 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("The kings are on opposite wings, so it is a race: storm the enemy king with your pawns on %s.", strings.Join(storm, ", ")),
+		TargetPieces: storm,
+	}, true
+}
+
+// lineToKing returns how many pieces stand between from and color's enemy
+// king on their shared file, or diagonal if diagonal is set, or -1 if they
+// do not share one.
+func (b *board) lineToKing(from chess.Square, color chess.Color, diagonal bool) int {
+	king := b.king(color.Other())
+	if king == chess.NoSquare {
+		return -1
+	}
+	df, dr := int(king.File())-int(from.File()), int(king.Rank())-int(from.Rank())
+	switch {
+	case diagonal && (df == dr || df == -dr) && df != 0:
+	case !diagonal && df == 0 && dr != 0:
+	default:
+		return -1
+	}
+	stepF, stepR := sign(df), sign(dr)
+	between := 0
+	for sq, _ := analysis.OffsetSquare(from, stepF, stepR); sq != king; sq, _ = analysis.OffsetSquare(sq, stepF, stepR) {
+		if _, ok := b.pieces[sq]; ok {
+			between++
+		}
+	}
+	return between
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+func fileOnKingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	for _, t := range []chess.PieceType{chess.Rook, chess.Queen} {
+		for _, sq := range b.find(t, color) {
+			if n := b.lineToKing(sq, color, false); n > 0 && n <= 2 {
+				king := b.king(color.Other())
+				return Suggestion{
+					Plan:         fmt.Sprintf("Your %s on %s lines up with the enemy king on the %s-file; open the file.", pieceNames[t], sq, fileName(int(sq.File()))),
+					TargetPieces: []string{pieceLabel(b.pieces[king], king)},
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
+
+func diagonalOnKingRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	for _, t := range []chess.PieceType{chess.Bishop, chess.Queen} {
+		for _, sq := range b.find(t, color) {
+			if n := b.lineToKing(sq, color, true); n > 0 && n <= 2 {
+				king := b.king(color.Other())
+				return Suggestion{
+					Plan:         fmt.Sprintf("Your %s on %s points at the enemy king; remove what stands in between.", pieceNames[t], sq),
+					TargetPieces: []string{pieceLabel(b.pieces[king], king)},
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
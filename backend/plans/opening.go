@@ -0,0 +1,133 @@
+package plans
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// openingMoves is how many moves into the game the opening rules apply.
+const openingMoves = 12
+
+func (b *board) inOpening() bool {
+	return moveNumber(b.pos) <= openingMoves && !b.isEndgame()
+}
+
+// moveNumber reads the full move number from pos's FEN.
+func moveNumber(pos *chess.Position) int {
+	fields := strings.Fields(pos.String())
+	n := 1
+	if len(fields) == 6 {
+		fmt.Sscan(fields[5], &n)
+	}
+	return n
+}
+
+// undeveloped returns color's knights and bishops still on their starting
+// squares.
+func (b *board) undeveloped(color chess.Color) []string {
+	var labels []string
+	for _, f := range []int{1, 6, 2, 5} {
+		sq, _ := square(f, 0, color)
+		p := b.pieces[sq]
+		if (p.Type() == chess.Knight || p.Type() == chess.Bishop) && p.Color() == color {
+			labels = append(labels, pieceLabel(p, sq))
+		}
+	}
+	return labels
+}
+
+func developmentRule(b *board, color chess.Color) (Suggestion, bool) {
+	undeveloped := b.undeveloped(color)
+	if len(undeveloped) < 2 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("Finish your development: %s still sit on their starting squares.", strings.Join(undeveloped, ", ")),
+		TargetPieces: undeveloped,
+	}, true
+}
+
+func earlyQueenRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.inOpening() || len(b.undeveloped(color)) < 3 {
+		return Suggestion{}, false
+	}
+	home, _ := square(3, 0, color)
+	for _, sq := range b.find(chess.Queen, color) {
+		if sq != home && rank(sq, color) >= 2 {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your queen on %s came out before your minor pieces; develop them first so she is not chased with tempo.", sq),
+				TargetPieces: []string{pieceLabel(b.pieces[sq], sq)},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func centreControlRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.inOpening() {
+		return Suggestion{}, false
+	}
+	var targets []string
+	for _, f := range []int{3, 4} {
+		for r := 3; r <= 4; r++ {
+			sq, _ := square(f, r, color)
+			if b.own(sq, chess.Pawn, color) {
+				return Suggestion{}, false
+			}
+		}
+		sq, _ := square(f, 3, color)
+		if _, occupied := b.pieces[sq]; !occupied {
+			targets = append(targets, sq.String())
+		}
+	}
+	if len(targets) == 0 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:       fmt.Sprintf("You have no pawn in the centre yet; claim it with a pawn on %s.", strings.Join(targets, " or ")),
+		KeySquares: targets,
+	}, true
+}
+
+func connectRooksRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || !b.castled(color) {
+		return Suggestion{}, false
+	}
+	rooks := b.find(chess.Rook, color)
+	if len(rooks) != 2 || rank(rooks[0], color) != 0 || rank(rooks[1], color) != 0 {
+		return Suggestion{}, false
+	}
+	var between []string
+	for f := int(rooks[0].File()) + 1; f < int(rooks[1].File()); f++ {
+		sq, _ := square(f, 0, color)
+		if p, ok := b.pieces[sq]; ok && p.Color() == color && p.Type() != chess.King {
+			between = append(between, pieceLabel(p, sq))
+		}
+	}
+	if len(between) == 0 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("Connect your rooks by moving %s off the back rank.", strings.Join(between, ", ")),
+		TargetPieces: between,
+	}, true
+}
+
+func fianchettoRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, side := range []struct{ file, step int }{{6, -1}, {1, 1}} {
+		bishop, _ := square(side.file, 1, color)
+		pawn, _ := square(side.file, 2, color)
+		if !b.own(bishop, chess.Bishop, color) || !b.own(pawn, chess.Pawn, color) {
+			continue
+		}
+		centre, _ := square(side.file+2*side.step, 3, color)
+		return Suggestion{
+			Plan:         fmt.Sprintf("Your fianchettoed bishop on %s rakes the long diagonal; keep it and support a break on %s.", bishop, centre),
+			KeySquares:   []string{centre.String()},
+			TargetPieces: []string{pieceLabel(b.pieces[bishop], bishop)},
+		}, true
+	}
+	return Suggestion{}, false
+}
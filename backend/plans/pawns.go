@@ -0,0 +1,359 @@
+package plans
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// passedPawns returns color's passed pawns, most advanced first.
+func (b *board) passedPawns(color chess.Color) []chess.Square {
+	var passed []chess.Square
+	for r := 6; r >= 1; r-- {
+		for f := 0; f < 8; f++ {
+			sq, _ := square(f, r, color)
+			if b.own(sq, chess.Pawn, color) && b.isPassed(sq, color) {
+				passed = append(passed, sq)
+			}
+		}
+	}
+	return passed
+}
+
+// centralPawnsAhead counts color's pawns on the c- to f-files that have
+// crossed into the other half of the board.
+func (b *board) centralPawnsAhead(color chess.Color) int {
+	n := 0
+	for _, sq := range b.find(chess.Pawn, color) {
+		if sq.File() >= chess.FileC && sq.File() <= chess.FileF && rank(sq, color) >= 4 {
+			n++
+		}
+	}
+	return n
+}
+
+func passedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	passed := b.passedPawns(color)
+	if len(passed) == 0 {
+		return Suggestion{}, false
+	}
+	best := passed[0]
+	next, ok := square(int(best.File()), rank(best, color)+1, color)
+	if !ok {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:       fmt.Sprintf("Your pawn on %s is passed; support it and push it towards %s.", best, next),
+		KeySquares: []string{next.String()},
+	}, true
+}
+
+func dangerousPasserRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.passedPawns(color.Other()) {
+		if rank(sq, color.Other()) < 5 {
+			break
+		}
+		queening, _ := square(int(sq.File()), 7, color.Other())
+		return Suggestion{
+			Plan:         fmt.Sprintf("The enemy pawn on %s is close to queening; take control of %s before anything else.", sq, queening),
+			KeySquares:   []string{queening.String()},
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func protectedPassedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.passedPawns(color) {
+		if b.pawnDefends(sq, color) {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your protected passed pawn on %s is a lasting trump; trade pieces so it decides the endgame.", sq),
+				TargetPieces: []string{sq.String()},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func connectedPassersRule(b *board, color chess.Color) (Suggestion, bool) {
+	passed := b.passedPawns(color)
+	for i, a := range passed {
+		for _, c := range passed[i+1:] {
+			if df := int(a.File()) - int(c.File()); df == 1 || df == -1 {
+				return Suggestion{
+					Plan:         fmt.Sprintf("Your connected passed pawns on %s and %s are strongest side by side; advance them together.", a, c),
+					TargetPieces: []string{a.String(), c.String()},
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
+
+func blockadePassedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.passedPawns(color.Other()) {
+		stop, ok := square(int(sq.File()), rank(sq, color.Other())+1, color.Other())
+		if !ok {
+			continue
+		}
+		if p, occupied := b.pieces[stop]; occupied && p.Color() == color {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("Blockade the enemy passed pawn on %s by putting a piece, ideally a knight, on %s.", sq, stop),
+			KeySquares:   []string{stop.String()},
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func isolatedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.find(chess.Pawn, color.Other()) {
+		if !b.isIsolated(sq, color.Other()) {
+			continue
+		}
+		stop, ok := square(int(sq.File()), rank(sq, color.Other())+1, color.Other())
+		if !ok {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("Blockade the isolated pawn on %s from %s and pile up on it.", sq, stop),
+			KeySquares:   []string{stop.String()},
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func ownIsolatedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.find(chess.Pawn, color) {
+		if !b.isIsolated(sq, color) || b.isPassed(sq, color) {
+			continue
+		}
+		next, ok := square(int(sq.File()), rank(sq, color)+1, color)
+		if !ok {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("Your isolated pawn on %s gives your pieces open files but will be weak in an endgame; keep pieces on and prepare %s.", sq, next),
+			KeySquares:   []string{next.String()},
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func backwardPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	them := color.Other()
+	pawns := b.find(chess.Pawn, them)
+	for _, sq := range pawns {
+		behind := true
+		hasNeighbour := false
+		for _, other := range pawns {
+			df := int(other.File()) - int(sq.File())
+			if df == -1 || df == 1 {
+				hasNeighbour = true
+				if rank(other, them) <= rank(sq, them) {
+					behind = false
+				}
+			}
+		}
+		if !hasNeighbour || !behind {
+			continue
+		}
+		stop, ok := square(int(sq.File()), rank(sq, them)+1, them)
+		if ok && b.pawnDefends(stop, color) {
+			return Suggestion{
+				Plan:         fmt.Sprintf("The pawn on %s is backward; occupy %s in front of it and attack it down the file.", sq, stop),
+				KeySquares:   []string{stop.String()},
+				TargetPieces: []string{sq.String()},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+// doubled returns the file of color's first doubled pawns and the most
+// advanced of them.
+func (b *board) doubled(color chess.Color) (int, chess.Square, bool) {
+	files := b.pawnFiles(color)
+	for f, n := range files {
+		if n < 2 {
+			continue
+		}
+		for r := 6; r >= 1; r-- {
+			if sq, _ := square(f, r, color); b.own(sq, chess.Pawn, color) {
+				return f, sq, true
+			}
+		}
+	}
+	return 0, chess.NoSquare, false
+}
+
+func doubledPawnsRule(b *board, color chess.Color) (Suggestion, bool) {
+	f, front, ok := b.doubled(color.Other())
+	if !ok {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("The doubled pawns on the %s-file cannot protect each other; attack the front one on %s.", fileName(f), front),
+		TargetPieces: []string{front.String()},
+	}, true
+}
+
+func ownDoubledPawnsRule(b *board, color chess.Color) (Suggestion, bool) {
+	f, front, ok := b.doubled(color)
+	if !ok {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("Your doubled pawns on the %s-file are a liability; look for a pawn exchange that undoubles them.", fileName(f)),
+		TargetPieces: []string{front.String()},
+	}, true
+}
+
+func chainBaseRule(b *board, color chess.Color) (Suggestion, bool) {
+	them := color.Other()
+	for _, base := range b.find(chess.Pawn, them) {
+		if b.pawnDefends(base, them) {
+			continue
+		}
+		for _, df := range []int{-1, 1} {
+			link, ok := square(int(base.File())+df, rank(base, them)+1, them)
+			if ok && b.own(link, chess.Pawn, them) {
+				return Suggestion{
+					Plan:         fmt.Sprintf("The enemy pawn chain rests on %s; undermine the base of the chain with a pawn break.", base),
+					KeySquares:   []string{base.String()},
+					TargetPieces: []string{base.String(), link.String()},
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
+
+func hangingPawnsRule(b *board, color chess.Color) (Suggestion, bool) {
+	files := b.pawnFiles(color)
+	if files[1] > 0 || files[4] > 0 {
+		return Suggestion{}, false
+	}
+	for r := 2; r <= 4; r++ {
+		c, _ := square(2, r, color)
+		d, _ := square(3, r, color)
+		if b.own(c, chess.Pawn, color) && b.own(d, chess.Pawn, color) {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your hanging pawns on %s and %s are strong side by side; keep them level and advance one only to open lines.", c, d),
+				TargetPieces: []string{c.String(), d.String()},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func pawnLeverRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	for _, sq := range b.find(chess.Pawn, color.Other()) {
+		if sq.File() < chess.FileC || sq.File() > chess.FileF || !b.pawnDefends(sq, color) {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("Your pawns are in contact on %s; decide whether opening the position there suits you before your opponent does.", sq),
+			KeySquares:   []string{sq.String()},
+			TargetPieces: []string{sq.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func openCentreRule(b *board, color chess.Color) (Suggestion, bool) {
+	own, enemy := b.pawnFiles(color), b.pawnFiles(color.Other())
+	if b.isEndgame() || own[3]+own[4]+enemy[3]+enemy[4] > 0 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:       "The centre is open; piece activity counts for more than structure, so centralise your pieces and seize the d- and e-files.",
+		KeySquares: []string{chess.D4.String(), chess.E4.String(), chess.D5.String(), chess.E5.String()},
+	}, true
+}
+
+func closedCentreRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	blocked := map[int]int{}
+	for _, sq := range b.find(chess.Pawn, color) {
+		if f := int(sq.File()); f == 3 || f == 4 {
+			if front, ok := square(f, rank(sq, color)+1, color); ok && b.own(front, chess.Pawn, color.Other()) {
+				blocked[f] = rank(sq, color)
+			}
+		}
+	}
+	d, dOK := blocked[3]
+	e, eOK := blocked[4]
+	if !dOK || !eOK || d == e {
+		return Suggestion{}, false
+	}
+	wing, breakFile := "kingside", 5
+	if d > e {
+		wing, breakFile = "queenside", 2
+	}
+	sq, _ := square(breakFile, 4, color)
+	return Suggestion{
+		Plan:       fmt.Sprintf("The centre is locked and your pawn chain points to the %s; attack there with a pawn break on %s.", wing, sq),
+		KeySquares: []string{sq.String()},
+	}, true
+}
+
+func spaceAdvantageRule(b *board, color chess.Color) (Suggestion, bool) {
+	own, enemy := b.centralPawnsAhead(color), b.centralPawnsAhead(color.Other())
+	if own < 2 || own <= enemy || b.isEndgame() {
+		return Suggestion{}, false
+	}
+	return Suggestion{Plan: "You have more space; avoid exchanges so your opponent stays cramped, and use the room to shift pieces between wings."}, true
+}
+
+func crampedRule(b *board, color chess.Color) (Suggestion, bool) {
+	own, enemy := b.centralPawnsAhead(color), b.centralPawnsAhead(color.Other())
+	if enemy < 2 || enemy <= own || b.isEndgame() {
+		return Suggestion{}, false
+	}
+	return Suggestion{Plan: "You are short of space; trade pieces to free your position and strike at the advanced pawns with pawn breaks."}, true
+}
+
+func minorityAttackRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || len(b.find(chess.Rook, color)) == 0 {
+		return Suggestion{}, false
+	}
+	own, enemy := b.pawnFiles(color), b.pawnFiles(color.Other())
+	o, e := own[0]+own[1]+own[2], enemy[0]+enemy[1]+enemy[2]
+	if own[1] == 0 || o >= e {
+		return Suggestion{}, false
+	}
+	target, _ := square(1, 4, color)
+	return Suggestion{
+		Plan:       fmt.Sprintf("Launch a minority attack: advance your b-pawn to %s to leave the enemy queenside pawns weak.", target),
+		KeySquares: []string{target.String()},
+	}, true
+}
+
+func majorityRule(b *board, color chess.Color) (Suggestion, bool) {
+	own, enemy := b.pawnFiles(color), b.pawnFiles(color.Other())
+	sides := []struct {
+		name  string
+		files []int
+	}{{"queenside", []int{0, 1, 2}}, {"kingside", []int{5, 6, 7}}}
+	for _, side := range sides {
+		o, e := 0, 0
+		for _, f := range side.files {
+			o += own[f]
+			e += enemy[f]
+		}
+		if o > e && e > 0 {
+			return Suggestion{Plan: fmt.Sprintf("Use your %s pawn majority to create a passed pawn.", side.name)}, true
+		}
+	}
+	return Suggestion{}, false
+}
@@ -0,0 +1,296 @@
+package plans
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+func doubledRooksRule(b *board, color chess.Color) (Suggestion, bool) {
+	rooks := b.find(chess.Rook, color)
+	if len(rooks) < 2 || rooks[0].File() != rooks[1].File() {
+		return Suggestion{}, false
+	}
+	f := int(rooks[0].File())
+	if b.pawnFiles(color)[f] > 0 {
+		return Suggestion{}, false
+	}
+	target, _ := square(f, 6, color)
+	return Suggestion{
+		Plan:       fmt.Sprintf("Your rooks are doubled on the %s-file; use them to break through to %s.", fileName(f), target),
+		KeySquares: []string{target.String()},
+	}, true
+}
+
+func openFileRule(b *board, color chess.Color) (Suggestion, bool) {
+	own, enemy := b.pawnFiles(color), b.pawnFiles(color.Other())
+	rooks := b.find(chess.Rook, color)
+	if len(rooks) == 0 {
+		return Suggestion{}, false
+	}
+	for _, r := range rooks {
+		if own[r.File()] == 0 {
+			return Suggestion{}, false
+		}
+	}
+	for _, f := range []int{3, 4, 2, 5, 1, 6, 0, 7} {
+		if own[f] == 0 && enemy[f] == 0 {
+			sq, _ := square(f, 0, color)
+			return Suggestion{
+				Plan:         fmt.Sprintf("Seize the open %s-file with a rook.", fileName(f)),
+				KeySquares:   []string{sq.String()},
+				TargetPieces: []string{pieceLabel(b.pieces[rooks[0]], rooks[0])},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func halfOpenFileRule(b *board, color chess.Color) (Suggestion, bool) {
+	own, enemy := b.pawnFiles(color), b.pawnFiles(color.Other())
+	rooks := b.find(chess.Rook, color)
+	if len(rooks) == 0 {
+		return Suggestion{}, false
+	}
+	for _, r := range rooks {
+		if own[r.File()] == 0 {
+			return Suggestion{}, false
+		}
+	}
+	for _, f := range []int{3, 4, 2, 5, 1, 6, 0, 7} {
+		if own[f] > 0 || enemy[f] == 0 {
+			continue
+		}
+		for r := 1; r <= 6; r++ {
+			if target, _ := square(f, r, color); b.own(target, chess.Pawn, color.Other()) {
+				return Suggestion{
+					Plan:         fmt.Sprintf("Put a rook on the half-open %s-file to press against the pawn on %s.", fileName(f), target),
+					KeySquares:   []string{target.String()},
+					TargetPieces: []string{target.String()},
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
+
+func seventhRankRule(b *board, color chess.Color) (Suggestion, bool) {
+	own := b.pawnFiles(color)
+	for _, r := range b.find(chess.Rook, color) {
+		if own[r.File()] > 0 || rank(r, color) == 6 {
+			continue
+		}
+		target, _ := square(int(r.File()), 6, color)
+		if p, occupied := b.pieces[target]; occupied && p.Color() == color {
+			continue
+		}
+		return Suggestion{
+			Plan:       fmt.Sprintf("Penetrate with your rook to %s on the seventh rank.", target),
+			KeySquares: []string{target.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func rookBehindPassedPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	if !b.isEndgame() || len(b.find(chess.Rook, color)) == 0 {
+		return Suggestion{}, false
+	}
+	for _, sq := range b.passedPawns(color) {
+		for _, r := range b.find(chess.Rook, color) {
+			if r.File() == sq.File() && rank(r, color) < rank(sq, color) {
+				return Suggestion{}, false
+			}
+		}
+		behind, _ := square(int(sq.File()), 0, color)
+		return Suggestion{
+			Plan:       fmt.Sprintf("Rooks belong behind passed pawns: bring a rook to the %s-file behind your pawn on %s.", fileName(int(sq.File())), sq),
+			KeySquares: []string{behind.String()},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func idleRookRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || moveNumber(b.pos) < openingMoves || !b.castled(color) {
+		return Suggestion{}, false
+	}
+	for _, f := range []int{0, 7} {
+		corner, _ := square(f, 0, color)
+		if b.own(corner, chess.Rook, color) {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your rook on %s has not joined the game; bring it to a central or open file.", corner),
+				TargetPieces: []string{pieceLabel(b.pieces[corner], corner)},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func knightOutpostRule(b *board, color chess.Color) (Suggestion, bool) {
+	if len(b.find(chess.Knight, color)) == 0 {
+		return Suggestion{}, false
+	}
+	for relRank := 5; relRank >= 3; relRank-- {
+		for _, f := range []int{3, 4, 2, 5} {
+			sq, _ := square(f, relRank, color)
+			if _, occupied := b.pieces[sq]; occupied {
+				continue
+			}
+			if b.pawnDefends(sq, color) && !b.pawnCanAttack(sq, color.Other()) {
+				return Suggestion{
+					Plan:       fmt.Sprintf("Plant a knight on the outpost at %s, where no enemy pawn can challenge it.", sq),
+					KeySquares: []string{sq.String()},
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
+
+func holeRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	for _, f := range []int{3, 4, 2, 5} {
+		sq, _ := square(f, 5, color)
+		if _, occupied := b.pieces[sq]; occupied {
+			continue
+		}
+		if !b.pawnDefends(sq, color.Other()) && !b.pawnCanAttack(sq, color.Other()) {
+			return Suggestion{
+				Plan:       fmt.Sprintf("%s is a hole in the enemy camp that no pawn can ever cover; aim your pieces at it.", sq),
+				KeySquares: []string{sq.String()},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func knightOnRimRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.find(chess.Knight, color) {
+		if sq.File() != chess.FileA && sq.File() != chess.FileH {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("A knight on the rim is dim: bring the knight on %s back towards the centre.", sq),
+			TargetPieces: []string{pieceLabel(b.pieces[sq], sq)},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+// safeKnightSquares counts the squares the knight on sq could move to
+// without landing on its own piece or a square guarded by an enemy pawn.
+func (b *board) safeKnightSquares(sq chess.Square, color chess.Color) int {
+	n := 0
+	for _, to := range analysis.AttackedSquares(b.pos.Board(), sq, b.pieces[sq]) {
+		if p, ok := b.pieces[to]; ok && p.Color() == color {
+			continue
+		}
+		if !b.pawnDefends(to, color.Other()) {
+			n++
+		}
+	}
+	return n
+}
+
+func passiveKnightRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.find(chess.Knight, color) {
+		if rank(sq, color) == 0 || sq.File() == chess.FileA || sq.File() == chess.FileH {
+			continue
+		}
+		if n := b.safeKnightSquares(sq, color); n <= 2 {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your knight on %s has only %d safe squares; reroute it to a better post.", sq, n),
+				TargetPieces: []string{pieceLabel(b.pieces[sq], sq)},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func bishopPairRule(b *board, color chess.Color) (Suggestion, bool) {
+	if len(b.find(chess.Bishop, color)) < 2 || len(b.find(chess.Bishop, color.Other())) >= 2 {
+		return Suggestion{}, false
+	}
+	var labels []string
+	for _, sq := range b.find(chess.Bishop, color) {
+		labels = append(labels, pieceLabel(b.pieces[sq], sq))
+	}
+	return Suggestion{
+		Plan:         "You have the bishop pair; open the position with pawn exchanges so both bishops gain scope.",
+		TargetPieces: labels,
+	}, true
+}
+
+// pawnsOnColour counts color's pawns on squares of the same colour as sq,
+// and all of its pawns.
+func (b *board) pawnsOnColour(sq chess.Square, color chess.Color) (same, total int) {
+	for _, p := range b.find(chess.Pawn, color) {
+		total++
+		if isLight(p) == isLight(sq) {
+			same++
+		}
+	}
+	return same, total
+}
+
+func badBishopRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.find(chess.Bishop, color) {
+		same, total := b.pawnsOnColour(sq, color)
+		if total >= 4 && same*3 >= total*2 {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your bishop on %s is hemmed in by pawns on its own colour; trade it or move the pawns to the other colour.", sq),
+				TargetPieces: []string{pieceLabel(b.pieces[sq], sq)},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func goodKnightRule(b *board, color chess.Color) (Suggestion, bool) {
+	bishops := b.find(chess.Bishop, color.Other())
+	if len(b.find(chess.Knight, color)) == 0 || len(bishops) != 1 || len(b.find(chess.Knight, color.Other())) > 0 {
+		return Suggestion{}, false
+	}
+	same, total := b.pawnsOnColour(bishops[0], color.Other())
+	if total < 3 || same*3 < total*2 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("Your knight faces a bad bishop on %s; keep the enemy pawns fixed on its colour and find the knight a square the bishop cannot reach.", bishops[0]),
+		TargetPieces: []string{pieceLabel(b.pieces[bishops[0]], bishops[0])},
+	}, true
+}
+
+func bishopInOpenPositionRule(b *board, color chess.Color) (Suggestion, bool) {
+	ownBishops, ownKnights := len(b.find(chess.Bishop, color)), len(b.find(chess.Knight, color))
+	theirBishops, theirKnights := len(b.find(chess.Bishop, color.Other())), len(b.find(chess.Knight, color.Other()))
+	if ownBishops <= theirBishops || theirKnights <= ownKnights {
+		return Suggestion{}, false
+	}
+	if len(b.find(chess.Pawn, color))+len(b.find(chess.Pawn, color.Other())) > 10 {
+		return Suggestion{}, false
+	}
+	return Suggestion{Plan: "Your bishop outranges the enemy knight in this open position; create play on both wings so the knight cannot cover everything."}, true
+}
+
+// oppositeBishops reports whether each side has exactly one bishop and
+// they run on different colours.
+func (b *board) oppositeBishops() bool {
+	white, black := b.find(chess.Bishop, chess.White), b.find(chess.Bishop, chess.Black)
+	return len(white) == 1 && len(black) == 1 && isLight(white[0]) != isLight(black[0])
+}
+
+func oppositeBishopsAttackRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() || !b.oppositeBishops() || len(b.find(chess.Queen, color)) == 0 {
+		return Suggestion{}, false
+	}
+	bishop := b.find(chess.Bishop, color)[0]
+	return Suggestion{
+		Plan:         fmt.Sprintf("With opposite-coloured bishops the attacker is effectively a piece up; attack the king on the squares your bishop on %s controls.", bishop),
+		TargetPieces: []string{pieceLabel(b.pieces[bishop], bishop)},
+	}, true
+}
@@ -0,0 +1,293 @@
+// Package plans suggests strategic plans for a chess position using a set
+// of hand-written rules. It is meant for coaching hints, not evaluation.
+package plans
+
+import (
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// Suggestion is a plan for the side to move together with the squares and
+// pieces it revolves around.
+type Suggestion struct {
+	Plan         string   `json:"plan"`
+	KeySquares   []string `json:"keySquares"`
+	TargetPieces []string `json:"targetPieces"`
+}
+
+// A rule inspects the position from the point of view of color and returns
+// a suggestion when its pattern applies.
+type rule func(b *board, color chess.Color) (Suggestion, bool)
+
+// rules are tried in priority order: loose material first, then king
+// safety and development, then long-term plans, with the endgame rules
+// last as they only apply once most pieces are gone.
+var rules = []rule{
+	// Tactics
+	hangingPieceRule,
+	ownHangingPieceRule,
+	attackedByPawnRule,
+	knightForkRule,
+	pinRule,
+	loosePiecesRule,
+
+	// King safety and development
+	castleRule,
+	centralKingRule,
+	ownBackRankRule,
+	backRankRule,
+	ownKingShieldRule,
+	weakenedKingRule,
+	undefendedKingRule,
+	oppositeCastlingRule,
+	fileOnKingRule,
+	diagonalOnKingRule,
+	developmentRule,
+	earlyQueenRule,
+	centreControlRule,
+	connectRooksRule,
+	fianchettoRule,
+
+	// Pawn structure
+	passedPawnRule,
+	dangerousPasserRule,
+	protectedPassedPawnRule,
+	connectedPassersRule,
+	blockadePassedPawnRule,
+	isolatedPawnRule,
+	ownIsolatedPawnRule,
+	backwardPawnRule,
+	doubledPawnsRule,
+	ownDoubledPawnsRule,
+	chainBaseRule,
+	hangingPawnsRule,
+	pawnLeverRule,
+	openCentreRule,
+	closedCentreRule,
+	spaceAdvantageRule,
+	crampedRule,
+	minorityAttackRule,
+	majorityRule,
+
+	// Pieces
+	doubledRooksRule,
+	openFileRule,
+	halfOpenFileRule,
+	seventhRankRule,
+	rookBehindPassedPawnRule,
+	idleRookRule,
+	knightOutpostRule,
+	holeRule,
+	knightOnRimRule,
+	passiveKnightRule,
+	bishopPairRule,
+	badBishopRule,
+	goodKnightRule,
+	bishopInOpenPositionRule,
+	oppositeBishopsAttackRule,
+
+	// Material and the endgame
+	tradeWhenAheadRule,
+	avoidTradesWhenBehindRule,
+	queenlessRule,
+	kingActivityRule,
+	oppositeBishopsDrawRule,
+	outsidePassedPawnRule,
+	squareOfThePawnRule,
+	pawnEndingRule,
+	activeRookEndingRule,
+	cutOffKingRule,
+	tradePawnsWhenBehindRule,
+}
+
+// maxPlans is how many matching rules are combined into one suggestion.
+const maxPlans = 2
+
+// clearAdvantage is the material lead, in centipawns, at which the side
+// ahead should simplify and the side behind should avoid it.
+const clearAdvantage = 200
+
+// Suggest returns a plan for the side to move in pos.
+func Suggest(pos *chess.Position) Suggestion {
+	b := newBoard(pos)
+	color := pos.Turn()
+
+	result := Suggestion{KeySquares: []string{}, TargetPieces: []string{}}
+	var sentences []string
+	for _, r := range rules {
+		s, ok := r(b, color)
+		if !ok {
+			continue
+		}
+		sentences = append(sentences, s.Plan)
+		result.KeySquares = append(result.KeySquares, s.KeySquares...)
+		result.TargetPieces = append(result.TargetPieces, s.TargetPieces...)
+		if len(sentences) == maxPlans {
+			break
+		}
+	}
+	if len(sentences) == 0 {
+		sentences = append(sentences, "Improve your worst-placed piece and keep your pawn structure healthy.")
+	}
+	result.Plan = strings.Join(sentences, " ")
+	return result
+}
+
+type board struct {
+	pos     *chess.Position
+	pieces  map[chess.Square]chess.Piece
+	attacks map[chess.Color][64]int
+}
+
+func newBoard(pos *chess.Position) *board {
+	return &board{
+		pos:    pos,
+		pieces: pos.Board().SquareMap(),
+		attacks: map[chess.Color][64]int{
+			chess.White: analysis.AttackCounts(pos.Board(), chess.White),
+			chess.Black: analysis.AttackCounts(pos.Board(), chess.Black),
+		},
+	}
+}
+
+func (b *board) find(t chess.PieceType, color chess.Color) []chess.Square {
+	var squares []chess.Square
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if p, ok := b.pieces[sq]; ok && p.Type() == t && p.Color() == color {
+			squares = append(squares, sq)
+		}
+	}
+	return squares
+}
+
+// own reports whether a piece of color and type t stands on sq.
+func (b *board) own(sq chess.Square, t chess.PieceType, color chess.Color) bool {
+	p, ok := b.pieces[sq]
+	return ok && p.Type() == t && p.Color() == color
+}
+
+func (b *board) king(color chess.Color) chess.Square {
+	return analysis.KingSquare(b.pos.Board(), color)
+}
+
+func (b *board) pawnFiles(color chess.Color) [8]int {
+	return analysis.PawnFiles(b.pos.Board(), color)
+}
+
+func (b *board) isPassed(sq chess.Square, color chess.Color) bool {
+	return analysis.IsPassedPawn(b.pos.Board(), sq, color)
+}
+
+// pawnDefends reports whether a pawn of color guards sq.
+func (b *board) pawnDefends(sq chess.Square, color chess.Color) bool {
+	return analysis.PawnAttacks(b.pos.Board(), sq, color)
+}
+
+func (b *board) pawnCanAttack(sq chess.Square, attacker chess.Color) bool {
+	// Any attacker pawn on an adjacent file that has not yet passed sq could
+	// eventually advance to attack it.
+	for _, p := range b.find(chess.Pawn, attacker) {
+		df := int(p.File()) - int(sq.File())
+		if (df == -1 || df == 1) && rank(p, attacker) < rank(sq, attacker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *board) isIsolated(sq chess.Square, color chess.Color) bool {
+	files := b.pawnFiles(color)
+	f := int(sq.File())
+	return (f == 0 || files[f-1] == 0) && (f == 7 || files[f+1] == 0)
+}
+
+func (b *board) material(color chess.Color) int {
+	total := 0
+	for _, p := range b.pieces {
+		if p.Color() == color {
+			total += analysis.PieceValues[p.Type()]
+		}
+	}
+	return total
+}
+
+func (b *board) nonPawnMaterial() int {
+	total := 0
+	for _, p := range b.pieces {
+		if p.Type() != chess.Pawn {
+			total += analysis.PieceValues[p.Type()]
+		}
+	}
+	return total
+}
+
+func (b *board) isEndgame() bool {
+	return b.nonPawnMaterial() <= analysis.EndgameMaterial
+}
+
+// onlyHas reports whether color has no pieces other than its king, pawns
+// and pieces of the given types.
+func (b *board) onlyHas(color chess.Color, types ...chess.PieceType) bool {
+	for _, p := range b.pieces {
+		if p.Color() != color || p.Type() == chess.King || p.Type() == chess.Pawn {
+			continue
+		}
+		allowed := false
+		for _, t := range types {
+			allowed = allowed || p.Type() == t
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// rank returns sq's rank counted from color's back rank (0-7).
+func rank(sq chess.Square, color chess.Color) int {
+	return analysis.RelativeRank(sq, color)
+}
+
+// square returns the square on file and on relRank counted from color's
+// back rank.
+func square(file, relRank int, color chess.Color) (chess.Square, bool) {
+	if file < 0 || file > 7 || relRank < 0 || relRank > 7 {
+		return chess.NoSquare, false
+	}
+	r := relRank
+	if color == chess.Black {
+		r = 7 - relRank
+	}
+	return chess.NewSquare(chess.File(file), chess.Rank(r)), true
+}
+
+func isLight(sq chess.Square) bool {
+	return (int(sq.File())+int(sq.Rank()))%2 == 1
+}
+
+func distance(a, b chess.Square) int {
+	df, dr := int(a.File())-int(b.File()), int(a.Rank())-int(b.Rank())
+	return max(df, -df, dr, -dr)
+}
+
+func pieceLabel(p chess.Piece, sq chess.Square) string {
+	if p.Type() == chess.Pawn {
+		return sq.String()
+	}
+	return strings.ToUpper(p.Type().String()) + sq.String()
+}
+
+func fileName(f int) string {
+	return chess.File(f).String()
+}
+
+var pieceNames = map[chess.PieceType]string{
+	chess.King:   "king",
+	chess.Queen:  "queen",
+	chess.Rook:   "rook",
+	chess.Bishop: "bishop",
+	chess.Knight: "knight",
+	chess.Pawn:   "pawn",
+}
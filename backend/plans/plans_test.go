@@ -0,0 +1,166 @@
+package plans
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func position(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", fen, err)
+	}
+	return chess.NewGame(opt).Position()
+}
+
+// ruleName names r for test output.
+func ruleName(r rule) string {
+	name := reflect.ValueOf(r).Pointer()
+	for _, c := range ruleCases {
+		if reflect.ValueOf(c.rule).Pointer() == name {
+			return c.name
+		}
+	}
+	return "unknown rule"
+}
+
+// ruleCases gives a position, with the side to move, in which each rule
+// applies.
+var ruleCases = []struct {
+	name string
+	rule rule
+	fen  string
+}{
+	{"hangingPiece", hangingPieceRule, "6k1/8/8/3r4/8/8/3R4/7K w - - 0 1"},
+	{"ownHangingPiece", ownHangingPieceRule, "6k1/8/8/3r4/8/8/3N4/7K w - - 0 1"},
+	{"attackedByPawn", attackedByPawnRule, "4k3/8/8/8/3p4/4N3/8/4K3 w - - 0 1"},
+	{"knightFork", knightForkRule, "r3k3/8/8/1N6/8/8/8/4K3 w - - 0 1"},
+	{"pin", pinRule, "4k3/8/2n5/1B6/8/8/8/4K3 w - - 0 1"},
+	{"loosePieces", loosePiecesRule, "r1bqkbnr/pppppppp/8/8/2N1N3/8/PPPPPPPP/R1BQKB1R w KQkq - 0 1"},
+
+	{"castle", castleRule, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+	{"centralKing", centralKingRule, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1"},
+	{"ownBackRank", ownBackRankRule, "r5k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1"},
+	{"backRank", backRankRule, "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1"},
+	{"ownKingShield", ownKingShieldRule, "r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2NP1N2/PPP2P1P/R1BQ1RK1 w - - 0 1"},
+	{"weakenedKing", weakenedKingRule, "r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2NP1N2/PPP2P1P/R1BQ1RK1 b - - 0 1"},
+	{"undefendedKing", undefendedKingRule, "rr4k1/q4ppp/b1n5/8/8/2N5/PPPQ1PPP/R1B2RK1 w - - 0 1"},
+	{"oppositeCastling", oppositeCastlingRule, "r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2NP1N2/PPPQ1PPP/2KR3R w - - 0 1"},
+	{"fileOnKing", fileOnKingRule, "rnbqkbnr/pppppppp/8/8/8/8/PPPP1PPP/RNBQRBK1 w kq - 0 1"},
+	{"diagonalOnKing", diagonalOnKingRule, "rnbq1rk1/pppp1ppp/5n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 w - - 0 1"},
+	{"development", developmentRule, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+	{"earlyQueen", earlyQueenRule, "rnbqkbnr/pppp1ppp/8/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQkq - 0 2"},
+	{"centreControl", centreControlRule, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+	{"connectRooks", connectRooksRule, "r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 w kq - 4 5"},
+	{"fianchetto", fianchettoRule, "rnbqkbnr/pppppppp/8/8/8/6P1/PPPPPPBP/RNBQK1NR w KQkq - 0 1"},
+
+	{"passedPawn", passedPawnRule, "4k3/8/8/3P4/8/8/8/4K3 w - - 0 1"},
+	{"dangerousPasser", dangerousPasserRule, "4k3/8/8/8/8/3p4/8/4K3 w - - 0 1"},
+	{"protectedPassedPawn", protectedPassedPawnRule, "4k3/8/8/3P4/2P5/8/8/4K3 w - - 0 1"},
+	{"connectedPassers", connectedPassersRule, "4k3/8/8/2PP4/8/8/8/4K3 w - - 0 1"},
+	{"blockadePassedPawn", blockadePassedPawnRule, "4k3/8/8/8/3p4/8/8/4K3 w - - 0 1"},
+	{"isolatedPawn", isolatedPawnRule, "4k3/pp4pp/8/3p4/8/8/PPP2PPP/4K3 w - - 0 1"},
+	{"ownIsolatedPawn", ownIsolatedPawnRule, "4k3/pp3ppp/4p3/8/3P4/8/PP3PPP/4K3 w - - 0 1"},
+	{"backwardPawn", backwardPawnRule, "4k3/8/3p4/2p1p3/2P1P3/8/8/4K3 w - - 0 1"},
+	{"doubledPawns", doubledPawnsRule, "4k3/3p4/3p4/8/8/8/8/4K3 w - - 0 1"},
+	{"ownDoubledPawns", ownDoubledPawnsRule, "4k3/8/8/8/8/3P4/3P4/4K3 w - - 0 1"},
+	{"chainBase", chainBaseRule, "4k3/8/3p4/4p3/8/8/8/4K3 w - - 0 1"},
+	{"hangingPawns", hangingPawnsRule, "4k3/pp3ppp/8/8/2PP4/8/P4PPP/4K3 w - - 0 1"},
+	{"pawnLever", pawnLeverRule, "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2"},
+	{"openCentre", openCentreRule, "rnbqkbnr/ppp2ppp/8/8/8/8/PPP2PPP/RNBQKBNR w KQkq - 0 1"},
+	{"closedCentre", closedCentreRule, "rnbqkbnr/ppp2ppp/4p3/3pP3/3P4/8/PPP2PPP/RNBQKBNR w KQkq - 0 1"},
+	{"spaceAdvantage", spaceAdvantageRule, "rnbqkbnr/ppp3pp/3p1p2/3PP3/8/8/PPP2PPP/RNBQKBNR w KQkq - 0 1"},
+	{"cramped", crampedRule, "rnbqkbnr/ppp3pp/3p1p2/3PP3/8/8/PPP2PPP/RNBQKBNR b KQkq - 0 1"},
+	{"minorityAttack", minorityAttackRule, "r1bqkbnr/ppp2ppp/8/8/8/8/PP3PPP/RNBQKBNR w KQkq - 0 1"},
+	{"majority", majorityRule, "4k3/pp6/8/8/8/8/PPP5/4K3 w - - 0 1"},
+
+	{"doubledRooks", doubledRooksRule, "3k4/8/8/8/8/8/3R4/3R2K1 w - - 0 1"},
+	{"openFile", openFileRule, "rnbqkbnr/ppp2ppp/8/8/8/8/PPP2PPP/RNBQKBNR w KQkq - 0 1"},
+	{"halfOpenFile", halfOpenFileRule, "rnbqkbnr/pppppppp/8/8/8/8/PPP1PPPP/RNBQKBNR w KQkq - 0 1"},
+	{"seventhRank", seventhRankRule, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1"},
+	{"rookBehindPassedPawn", rookBehindPassedPawnRule, "4k3/8/8/3P4/8/8/8/R3K3 w - - 0 1"},
+	{"idleRook", idleRookRule, "r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/3P1N2/PPP2PPP/RNBQ1RK1 w - - 0 12"},
+	{"knightOutpost", knightOutpostRule, "rnbqkbnr/pp1p1ppp/8/8/4P3/2N5/PPPP1PPP/R1BQKBNR w KQkq - 0 1"},
+	{"hole", holeRule, "rnbqkbnr/pp1p1ppp/8/8/4P3/2N5/PPPP1PPP/R1BQKBNR w KQkq - 0 1"},
+	{"knightOnRim", knightOnRimRule, "4k3/8/8/8/8/N7/8/4K3 w - - 0 1"},
+	{"passiveKnight", passiveKnightRule, "4k3/8/8/8/P1P5/3P4/1N6/3QK3 w - - 0 1"},
+	{"bishopPair", bishopPairRule, "4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1"},
+	{"badBishop", badBishopRule, "4k3/8/8/8/8/2P1P3/1P1P4/2B1K3 w - - 0 1"},
+	{"goodKnight", goodKnightRule, "2b1k3/1p1p4/4p3/8/8/8/8/1N2K3 w - - 0 1"},
+	{"bishopInOpenPosition", bishopInOpenPositionRule, "4k3/pp3n2/8/8/8/8/PP6/2B1K3 w - - 0 1"},
+	{"oppositeBishopsAttack", oppositeBishopsAttackRule, "r2qk2r/ppp2ppp/2nb4/8/8/2NB4/PPP2PPP/R2QK2R w KQkq - 0 1"},
+
+	{"tradeWhenAhead", tradeWhenAheadRule, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1"},
+	{"avoidTradesWhenBehind", avoidTradesWhenBehindRule, "r3k3/8/8/8/8/8/8/4K3 w - - 0 1"},
+	{"queenless", queenlessRule, "rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNB1KBNR w KQkq - 0 1"},
+	{"kingActivity", kingActivityRule, "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+	{"oppositeBishopsDraw", oppositeBishopsDrawRule, "4k3/3b4/8/pp6/8/8/8/2B1K3 w - - 0 1"},
+	{"outsidePassedPawn", outsidePassedPawnRule, "4k3/8/8/8/P7/8/8/4K3 w - - 0 1"},
+	{"squareOfThePawn", squareOfThePawnRule, "4k3/8/8/8/8/8/p7/4K3 w - - 0 1"},
+	{"pawnEnding", pawnEndingRule, "4k3/4p3/8/8/8/8/4P3/4K3 w - - 0 1"},
+	{"activeRookEnding", activeRookEndingRule, "r3k3/8/8/8/8/8/8/R3K3 w - - 0 1"},
+	{"cutOffKing", cutOffKingRule, "4k3/8/8/P7/8/8/8/1R2K3 w - - 0 1"},
+	{"tradePawnsWhenBehind", tradePawnsWhenBehindRule, "4k3/pp6/8/8/8/8/P7/4K3 w - - 0 1"},
+}
+
+func TestRuleCount(t *testing.T) {
+	if n := len(rules); n < 50 || n > 100 {
+		t.Errorf("there are %d rules, want between 50 and 100", n)
+	}
+}
+
+func TestEveryRuleApplies(t *testing.T) {
+	tested := map[uintptr]bool{}
+	for _, c := range ruleCases {
+		tested[reflect.ValueOf(c.rule).Pointer()] = true
+		pos := position(t, c.fen)
+		s, ok := c.rule(newBoard(pos), pos.Turn())
+		if !ok {
+			t.Errorf("%s does not apply to %s", c.name, c.fen)
+			continue
+		}
+		if s.Plan == "" {
+			t.Errorf("%s gave no plan for %s", c.name, c.fen)
+		}
+	}
+	for i, r := range rules {
+		if !tested[reflect.ValueOf(r).Pointer()] {
+			t.Errorf("rule %d has no test position", i)
+		}
+	}
+}
+
+func TestQuietRulesAtStart(t *testing.T) {
+	pos := chess.StartingPosition()
+	b := newBoard(pos)
+	for _, r := range []rule{
+		hangingPieceRule, ownHangingPieceRule, loosePiecesRule, pinRule,
+		ownBackRankRule, backRankRule, undefendedKingRule, fileOnKingRule,
+		passedPawnRule, isolatedPawnRule, doubledPawnsRule, openCentreRule,
+		holeRule, knightOutpostRule, tradeWhenAheadRule, kingActivityRule,
+	} {
+		if s, ok := r(b, chess.White); ok {
+			t.Errorf("%s applies to the starting position: %q", ruleName(r), s.Plan)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	s := Suggest(chess.StartingPosition())
+	if !strings.HasPrefix(s.Plan, "Castle kingside") {
+		t.Errorf("plan for the starting position is %q", s.Plan)
+	}
+	if s.KeySquares == nil || s.TargetPieces == nil {
+		t.Errorf("suggestion has nil lists: %+v", s)
+	}
+
+	for _, c := range ruleCases {
+		if s := Suggest(position(t, c.fen)); s.Plan == "" {
+			t.Errorf("no plan for %s", c.fen)
+		}
+	}
+}
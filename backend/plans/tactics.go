@@ -0,0 +1,176 @@
+package plans
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// hanging returns color's pieces, other than pawns and the king, that the
+// other side attacks and color does not defend.
+func (b *board) hanging(color chess.Color) []chess.Square {
+	var squares []chess.Square
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		p, ok := b.pieces[sq]
+		if !ok || p.Color() != color || p.Type() == chess.Pawn || p.Type() == chess.King {
+			continue
+		}
+		if b.attacks[color.Other()][sq] > 0 && b.attacks[color][sq] == 0 {
+			squares = append(squares, sq)
+		}
+	}
+	return squares
+}
+
+func ownHangingPieceRule(b *board, color chess.Color) (Suggestion, bool) {
+	hanging := b.hanging(color)
+	if len(hanging) == 0 {
+		return Suggestion{}, false
+	}
+	sq := hanging[0]
+	return Suggestion{
+		Plan:         fmt.Sprintf("Your %s on %s is attacked and undefended; move it or protect it.", pieceNames[b.pieces[sq].Type()], sq),
+		KeySquares:   []string{sq.String()},
+		TargetPieces: []string{pieceLabel(b.pieces[sq], sq)},
+	}, true
+}
+
+func hangingPieceRule(b *board, color chess.Color) (Suggestion, bool) {
+	hanging := b.hanging(color.Other())
+	if len(hanging) == 0 {
+		return Suggestion{}, false
+	}
+	sq := hanging[0]
+	return Suggestion{
+		Plan:         fmt.Sprintf("The %s on %s is undefended and you attack it; take it if nothing worse follows.", pieceNames[b.pieces[sq].Type()], sq),
+		KeySquares:   []string{sq.String()},
+		TargetPieces: []string{pieceLabel(b.pieces[sq], sq)},
+	}, true
+}
+
+func attackedByPawnRule(b *board, color chess.Color) (Suggestion, bool) {
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		p, ok := b.pieces[sq]
+		if !ok || p.Color() != color || p.Type() == chess.Pawn || p.Type() == chess.King {
+			continue
+		}
+		if b.pawnDefends(sq, color.Other()) {
+			return Suggestion{
+				Plan:         fmt.Sprintf("Your %s on %s is attacked by a pawn; find it a safer square.", pieceNames[p.Type()], sq),
+				KeySquares:   []string{sq.String()},
+				TargetPieces: []string{pieceLabel(p, sq)},
+			}, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+// forkable reports whether p is a piece of color worth forking: its king,
+// queen or a rook.
+func forkable(p chess.Piece, color chess.Color) bool {
+	return p.Color() == color && (p.Type() == chess.King || p.Type() == chess.Queen || p.Type() == chess.Rook)
+}
+
+func knightForkRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, from := range b.find(chess.Knight, color) {
+		knight := b.pieces[from]
+		for _, to := range analysis.AttackedSquares(b.pos.Board(), from, knight) {
+			if p, ok := b.pieces[to]; ok && p.Color() == color {
+				continue
+			}
+			if b.pawnDefends(to, color.Other()) {
+				continue
+			}
+			var targets []string
+			for _, t := range analysis.AttackedSquares(b.pos.Board(), to, knight) {
+				if p, ok := b.pieces[t]; ok && forkable(p, color.Other()) {
+					targets = append(targets, pieceLabel(p, t))
+				}
+			}
+			if len(targets) >= 2 {
+				return Suggestion{
+					Plan:         fmt.Sprintf("Look at the knight jump from %s to %s, forking %s and %s.", from, to, targets[0], targets[1]),
+					KeySquares:   []string{to.String()},
+					TargetPieces: targets,
+				}, true
+			}
+		}
+	}
+	return Suggestion{}, false
+}
+
+// pinned returns the other side's pieces pinned to their king by color's
+// sliding pieces.
+func (b *board) pinned(color chess.Color) []chess.Square {
+	king := b.king(color.Other())
+	if king == chess.NoSquare {
+		return nil
+	}
+	var squares []chess.Square
+	scan := func(directions [][2]int, pinner chess.PieceType) {
+		for _, d := range directions {
+			candidate := chess.NoSquare
+			for sq, ok := analysis.OffsetSquare(king, d[0], d[1]); ok; sq, ok = analysis.OffsetSquare(sq, d[0], d[1]) {
+				p, occupied := b.pieces[sq]
+				if !occupied {
+					continue
+				}
+				if candidate == chess.NoSquare {
+					if p.Color() == color {
+						break
+					}
+					candidate = sq
+					continue
+				}
+				if p.Color() == color && (p.Type() == pinner || p.Type() == chess.Queen) {
+					squares = append(squares, candidate)
+				}
+				break
+			}
+		}
+	}
+	scan(analysis.RookDirections, chess.Rook)
+	scan(analysis.BishopDirections, chess.Bishop)
+	return squares
+}
+
+func pinRule(b *board, color chess.Color) (Suggestion, bool) {
+	for _, sq := range b.pinned(color) {
+		p := b.pieces[sq]
+		if p.Type() == chess.Pawn {
+			continue
+		}
+		return Suggestion{
+			Plan:         fmt.Sprintf("The %s on %s is pinned to its king; attack it again, with a pawn if you can.", pieceNames[p.Type()], sq),
+			KeySquares:   []string{sq.String()},
+			TargetPieces: []string{pieceLabel(p, sq)},
+		}, true
+	}
+	return Suggestion{}, false
+}
+
+func loosePiecesRule(b *board, color chess.Color) (Suggestion, bool) {
+	if b.isEndgame() {
+		return Suggestion{}, false
+	}
+	var loose []string
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		p, ok := b.pieces[sq]
+		// Pieces at home are rarely attacked, so only advanced ones count.
+		if !ok || p.Color() != color || p.Type() == chess.Pawn || p.Type() == chess.King || rank(sq, color) < 2 {
+			continue
+		}
+		if b.attacks[color][sq] == 0 {
+			loose = append(loose, pieceLabel(p, sq))
+		}
+	}
+	if len(loose) < 2 {
+		return Suggestion{}, false
+	}
+	return Suggestion{
+		Plan:         fmt.Sprintf("Loose pieces drop off: %s are not defended, so tie them together.", strings.Join(loose, ", ")),
+		TargetPieces: loose,
+	}, true
+}
@@ -1,11 +1,103 @@
 package main
 
 import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/gorilla/websocket"
 	"github.com/notnil/chess"
 )
 
 type Player struct {
-	Conn  *websocket.Conn
-	Color chess.Color
+	// Conn is nil while the player is disconnected and their seat is
+	// being held for them to reconnect with PlayerID, a random secret
+	// made for the seat and only ever sent to the player.
+	Conn     *websocket.Conn
+	Color    chess.Color
+	PlayerID string
+
+	// Subject is the account ID from an authenticated player's token,
+	// which their rating is kept under. It is public, so it never grants
+	// the seat. Guests have none.
+	Subject string
+
+	// GuestID is the guestIdentity of a guest's connection, which also
+	// reclaims the seat until GuestExpires. Authenticated players have
+	// none.
+	GuestID      string
+	GuestExpires time.Time
+
+	// Name is shown to the opponent and in PGN exports.
+	Name string
+
+	// TimeControl is the time control wanted while in the matchmaking
+	// queue, and Ranked whether only a rated game will do.
+	TimeControl string
+	Ranked      bool
+
+	// Rating is the player's rating going into a ranked game, and their
+	// new one once it ends, when RatingBefore keeps the old one.
+	Rating       int
+	RatingBefore int
+
+	// ForfeitTimer ends the game against a disconnected player once the
+	// reconnection grace period runs out.
+	ForfeitTimer *time.Timer
+
+	// ConfirmMoves holds each submitted move in PendingMove until the
+	// player confirms it, guarding against misclicks on mobile clients.
+	ConfirmMoves bool
+	PendingMove  string
+
+	// ChatTimes holds when the player's recent chat messages were sent,
+	// for rate limiting.
+	ChatTimes []time.Time
+
+	// Ready is set once the player's client has said it is ready for
+	// the game to start.
+	Ready bool
+
+	// LastSeen is when a message or pong last arrived on Conn. It is
+	// logged when the seat is held, showing how long the player had been
+	// silent. The read deadline, not LastSeen, decides when a connection
+	// is stale, as it also covers connections that hold no seat.
+	LastSeen time.Time
+
+	// log numbers and keeps the messages sent to the seat; see
+	// sendToPlayer.
+	log *messageLog
+}
+
+// maxNameLength is the longest display name accepted, in characters.
+const maxNameLength = 32
+
+// validateName rejects display names that are blank, too long or contain
+// unprintable characters. An empty name is allowed and means none was given.
+func validateName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if strings.TrimSpace(name) == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return errors.New("invalid name")
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return errors.New("invalid name")
+		}
+	}
+	return nil
+}
+
+// displayName returns name, falling back to the random tail of playerID.
+func displayName(name, id string) string {
+	if name != "" {
+		return name
+	}
+	if len(id) > 8 {
+		id = id[len(id)-8:]
+	}
+	return "Player-" + id
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// playerGames indexes the games each connection takes part in as a player,
+// coach or spectator, so that nothing needs to scan every game to find
+// them. It is guarded by gamesMutex. Games deleted since are dropped from
+// a connection's list the next time it joins one.
+var playerGames = make(map[*websocket.Conn][]string)
+
+// indexGame records that ws takes part in gameID. The caller must hold
+// gamesMutex.
+func indexGame(ws *websocket.Conn, gameID string) {
+	if ws == nil {
+		return
+	}
+	ids := playerGames[ws][:0]
+	for _, id := range playerGames[ws] {
+		if _, exists := games[id]; exists && id != gameID {
+			ids = append(ids, id)
+		}
+	}
+	playerGames[ws] = append(ids, gameID)
+}
+
+// unindexGame records that ws no longer takes part in gameID. The caller
+// must hold gamesMutex.
+func unindexGame(ws *websocket.Conn, gameID string) {
+	ids := playerGames[ws][:0]
+	for _, id := range playerGames[ws] {
+		if id != gameID {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		delete(playerGames, ws)
+		return
+	}
+	playerGames[ws] = ids
+}
+
+// inOtherGames reports whether ws takes part in any game besides gameID.
+// The caller must hold gamesMutex.
+func inOtherGames(ws *websocket.Conn, gameID string) bool {
+	for _, id := range playerGames[ws] {
+		if _, exists := games[id]; exists && id != gameID {
+			return true
+		}
+	}
+	return false
+}
+
+// activeGames counts the unfinished games ws holds a seat in. The caller
+// must hold gamesMutex.
+func activeGames(ws *websocket.Conn) int {
+	count := 0
+	for _, id := range playerGames[ws] {
+		game, exists := games[id]
+		if exists && getPlayer(ws, game) != nil && game.Game.Outcome() == chess.NoOutcome {
+			count++
+		}
+	}
+	return count
+}
+
+// allowAnotherGame answers ws with an error if it already plays in as many
+// games as a player may. The caller must hold gamesMutex.
+func allowAnotherGame(ws *websocket.Conn) bool {
+	if activeGames(ws) < config.MaxGamesPerPlayer {
+		return true
+	}
+	sendJSON(ws, map[string]string{"error": "too many active games"})
+	return false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGameLimit(t *testing.T) {
+	useConfig(t, func(c *Config) { c.MaxGamesPerPlayer = 2 })
+	srv := newTestServer(t)
+	alice := dial(t, srv)
+
+	var gameIDs []string
+	for i := 0; i < 2; i++ {
+		send(t, alice, map[string]string{"action": "create"})
+		gameIDs = append(gameIDs, readUntil(t, alice, hasStatus("created"))["gameID"].(string))
+	}
+	send(t, alice, map[string]string{"action": "create"})
+	if msg := readUntil(t, alice, hasError); msg["error"] != "too many active games" {
+		t.Errorf("creating a third game: %v", msg["error"])
+	}
+
+	bob := dial(t, srv)
+	send(t, bob, map[string]string{"action": "create"})
+	bobsGame := readUntil(t, bob, hasStatus("created"))["gameID"].(string)
+	send(t, alice, map[string]string{"action": "join", "gameID": bobsGame})
+	if msg := readUntil(t, alice, hasError); msg["error"] != "too many active games" {
+		t.Errorf("joining a third game: %v", msg["error"])
+	}
+
+	// A finished game no longer counts towards the limit.
+	carol := dial(t, srv)
+	send(t, carol, map[string]string{"action": "join", "gameID": gameIDs[0]})
+	readUntil(t, carol, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{alice, carol} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameIDs[0]})
+	}
+	readUntil(t, carol, isState)
+	send(t, carol, map[string]string{"action": "resign", "gameID": gameIDs[0]})
+	readUntil(t, alice, hasStatus("resigned"))
+	send(t, alice, map[string]string{"action": "join", "gameID": bobsGame})
+	readUntil(t, alice, hasStatus("joined"))
+}
+
+func TestPlayerGamesOnDisconnect(t *testing.T) {
+	useConfig(t, nil)
+	useGames(t)
+	srv := newTestServer(t)
+
+	alice := dial(t, srv)
+	send(t, alice, map[string]string{"action": "create"})
+	alone := readUntil(t, alice, hasStatus("created"))["gameID"].(string)
+
+	send(t, alice, map[string]string{"action": "create"})
+	playing := readUntil(t, alice, hasStatus("created"))["gameID"].(string)
+	bob := dial(t, srv)
+	send(t, bob, map[string]string{"action": "join", "gameID": playing})
+	readUntil(t, bob, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{alice, bob} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": playing})
+	}
+	readUntil(t, bob, isState)
+
+	_, _, watching := startTestGame(t, srv)
+	send(t, alice, map[string]string{"action": "join", "gameID": watching})
+	readUntil(t, alice, hasStatus("spectating"))
+
+	gamesMutex.RLock()
+	aliceConn := games[alone].Players[0].Conn
+	indexed := len(playerGames[aliceConn])
+	gamesMutex.RUnlock()
+	if indexed != 3 {
+		t.Fatalf("alice is indexed in %d games, want 3", indexed)
+	}
+
+	alice.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		gamesMutex.RLock()
+		_, stillIndexed := playerGames[aliceConn]
+		gamesMutex.RUnlock()
+		if !stillIndexed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("alice's games are still indexed after disconnecting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	gamesMutex.RLock()
+	defer gamesMutex.RUnlock()
+	if _, exists := games[alone]; exists {
+		t.Error("the game alice was alone in was kept")
+	}
+	for _, p := range games[playing].Players {
+		if p.Conn == aliceConn {
+			t.Error("alice's seat still has the old connection")
+		}
+		if p.ForfeitTimer != nil {
+			p.ForfeitTimer.Stop()
+		}
+	}
+	if n := len(games[playing].Players); n != 2 {
+		t.Errorf("the game in progress has %d seats, want 2 with alice's held", n)
+	}
+	if n := len(games[watching].Spectators); n != 0 {
+		t.Errorf("the watched game has %d spectators, want 0", n)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/opening"
+	"github.com/segmentio/ksuid"
+)
+
+var (
+	openingBook     *opening.BookECO
+	openingBookOnce sync.Once
+)
+
+// ecoBook loads the opening book on first use; parsing it takes a while.
+func ecoBook() *opening.BookECO {
+	openingBookOnce.Do(func() {
+		openingBook = opening.NewBookECO()
+	})
+	return openingBook
+}
+
+// normalizeOpeningName lets "Sicilian Defense, Najdorf Variation" match the
+// book's "Sicilian Defense: Najdorf Variation".
+func normalizeOpeningName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), ":", ","))
+}
+
+func findOpening(name string) *opening.Opening {
+	target := normalizeOpeningName(name)
+	for _, o := range ecoBook().Possible(nil) {
+		if normalizeOpeningName(o.Title()) == target {
+			return o
+		}
+	}
+	return nil
+}
+
+// generatePracticeGame starts a game in which the server plays the first
+// moves of msg["targetOpening"] and then answers the player's moves. Only
+// the "random" practice mode is available as the server has no engine.
+func generatePracticeGame(ws *websocket.Conn, msg map[string]string) {
+	if !allowGameCreation(ws) {
+		return
+	}
+	mode := msg["practiceMode"]
+	if mode == "" {
+		mode = "random"
+	}
+	if mode != "random" {
+		sendJSON(ws, map[string]string{"error": "unsupported practice mode"})
+		return
+	}
+	if err := validateName(msg["name"]); err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+	book := findOpening(msg["targetOpening"])
+	if book == nil {
+		sendJSON(ws, map[string]string{"error": "opening not found"})
+		return
+	}
+
+	// The book keeps each line as UCI moves despite the PGN name.
+	line := strings.Fields(book.PGN())
+	movesPlayed := len(line)
+	if n, err := strconv.Atoi(msg["moves"]); err == nil && n >= 0 && n < movesPlayed {
+		movesPlayed = n
+	}
+	chessGame := chess.NewGame()
+	for _, s := range line[:movesPlayed] {
+		m, err := chess.UCINotation{}.Decode(chessGame.Position(), s)
+		if err == nil {
+			err = chessGame.Move(m)
+		}
+		if err != nil {
+			sendJSON(ws, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	gameID := ksuid.New().String()
+	player := &Player{
+		Conn:  ws,
+		Color: chessGame.Position().Turn(),
+	}
+	player.PlayerID, player.Subject, player.Name = identify(ws, msg["name"])
+	seatGuest(player)
+	game := &Game{
+		Game:         chessGame,
+		Players:      []*Player{player},
+		StartingFEN:  chess.StartingPosition().String(),
+		Variant:      "standard",
+		CreatedAt:    time.Now(),
+		StartedAt:    time.Now(),
+		PracticeMode: mode,
+		// The book moves were not played here, so they took no time.
+		MoveTimes:     make([]time.Duration, movesPlayed),
+		MoveStartedAt: time.Now(),
+	}
+	gamesMutex.Lock()
+	if !allowAnotherGame(ws) {
+		gamesMutex.Unlock()
+		return
+	}
+	games[gameID] = game
+	indexGame(ws, gameID)
+	watchGuestGame(gameID, game)
+	gamesCreated.Inc()
+	gamesMutex.Unlock()
+
+	reply := map[string]interface{}{
+		"status":         "practice",
+		"gameID":         gameID,
+		"color":          player.Color.String(),
+		"playerID":       player.PlayerID,
+		"opening":        book.Title(),
+		"openingReached": movesPlayed == len(line),
+		"movesPlayed":    movesPlayed,
+		"fen":            chessGame.Position().String(),
+		"yourTurn":       true,
+	}
+	if player.GuestID != "" {
+		reply["guestID"] = player.GuestID
+	}
+	sendJSON(ws, reply)
+	logger.Info("Practice game created", slog.String("gameID", gameID))
+}
+
+// playPracticeReply makes the server's move in a practice game.
+func playPracticeReply(gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists || game.Game.Outcome() != chess.NoOutcome || len(game.Players) == 0 ||
+		game.Game.Position().Turn() == game.Players[0].Color {
+		gamesMutex.Unlock()
+		return
+	}
+	moves := game.Game.ValidMoves()
+	if err := game.Game.Move(moves[rand.Intn(len(moves))]); err != nil {
+		gamesMutex.Unlock()
+		logger.Error("Error playing practice reply", slog.String("gameID", gameID), slog.Any("error", err))
+		return
+	}
+	movesMade.Inc()
+	recordMoveTime(game, time.Now())
+	recordMobility(game)
+	gamesMutex.Unlock()
+
+	broadcastGameState(gameID)
+}
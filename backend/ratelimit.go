@@ -0,0 +1,168 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// tokenBucket allows bursts of up to burst events, refilled at rate per
+// second.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// allow takes a token if one is available.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryAfter is how long until the next token arrives, as sent to clients.
+func (b *tokenBucket) retryAfter() string {
+	return (time.Duration(float64(time.Second) / b.rate)).String()
+}
+
+// rateLimitStrikes is how many messages in a row a connection may have
+// refused for exceeding its rate before it is closed.
+const rateLimitStrikes = 20
+
+// connectionLimiter is a connection's message rate limiter. refused counts
+// the messages refused since the last one allowed; only the connection's
+// own read loop touches it.
+type connectionLimiter struct {
+	*tokenBucket
+	refused int
+}
+
+var (
+	connectionLimiters      = make(map[*websocket.Conn]*connectionLimiter)
+	connectionLimitersMutex sync.Mutex
+)
+
+// allowMessage reports whether ws is within its message rate, answering it
+// with an error if not and closing it once it has kept on regardless.
+func allowMessage(ws *websocket.Conn) bool {
+	connectionLimitersMutex.Lock()
+	limiter, ok := connectionLimiters[ws]
+	if !ok {
+		limiter = &connectionLimiter{tokenBucket: newTokenBucket(float64(config.ConnectionRateLimit), config.ConnectionRateLimit)}
+		connectionLimiters[ws] = limiter
+	}
+	connectionLimitersMutex.Unlock()
+
+	if limiter.allow() {
+		limiter.refused = 0
+		return true
+	}
+	sendJSON(ws, map[string]string{"error": "rate limit exceeded", "retryAfter": limiter.retryAfter()})
+	if limiter.refused++; limiter.refused == rateLimitStrikes {
+		logger.Warn("Closing connection that kept exceeding its rate limit", slog.String("remoteAddr", ws.RemoteAddr().String()))
+		closeConnection(ws, CloseRateLimited, "rate limit exceeded")
+	}
+	return false
+}
+
+func forgetConnectionLimiter(ws *websocket.Conn) {
+	connectionLimitersMutex.Lock()
+	delete(connectionLimiters, ws)
+	connectionLimitersMutex.Unlock()
+}
+
+var (
+	// connectionCounts holds how many WebSockets each client address has
+	// open.
+	connectionCounts      = make(map[string]int)
+	connectionCountsMutex sync.Mutex
+)
+
+// clientIP returns the address r came from: the last X-Forwarded-For
+// entry if the server is configured to trust it, or else the peer address.
+// Only the last entry is taken, as the proxy in front of the server appends
+// it; the ones before it come from the client. Addresses are normalised so
+// that they compare equal to Config.TrustedIPs.
+func clientIP(r *http.Request) string {
+	if config.TrustForwardedFor {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return host
+}
+
+func isTrustedIP(ip string) bool {
+	for _, trusted := range config.TrustedIPs {
+		if trusted == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireConnection counts a new connection from ip, reporting false
+// without counting it if ip is already at its limit.
+func acquireConnection(ip string) bool {
+	if isTrustedIP(ip) {
+		return true
+	}
+	connectionCountsMutex.Lock()
+	defer connectionCountsMutex.Unlock()
+	if connectionCounts[ip] >= config.MaxConnectionsPerIP {
+		return false
+	}
+	connectionCounts[ip]++
+	return true
+}
+
+// releaseConnection uncounts a connection acquireConnection let through.
+func releaseConnection(ip string) {
+	if isTrustedIP(ip) {
+		return
+	}
+	connectionCountsMutex.Lock()
+	defer connectionCountsMutex.Unlock()
+	if connectionCounts[ip]--; connectionCounts[ip] <= 0 {
+		delete(connectionCounts, ip)
+	}
+}
+
+// moveLimiter returns the game's move rate limiter, creating it on first
+// use. The caller must hold gamesMutex.
+func (g *Game) moveLimiter() *tokenBucket {
+	if g.moveBucket == nil {
+		g.moveBucket = newTokenBucket(float64(config.MoveRateLimit), config.MoveRateLimit)
+	}
+	return g.moveBucket
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMoveRateLimitIgnoresOtherConnections(t *testing.T) {
+	useConfig(t, func(c *Config) { c.MoveRateLimit = 2 })
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	outsider := dial(t, srv)
+	for i := 0; i < 5; i++ {
+		send(t, outsider, map[string]string{"action": "move", "gameID": gameID, "move": "e4"})
+		reply := readUntil(t, outsider, hasError)
+		if reply["error"] != "not your turn" {
+			t.Fatalf("outsider's move %d: got %q, want not your turn", i, reply["error"])
+		}
+	}
+	play(t, white, black, gameID, "e4")
+}
+
+func TestMoveRateLimit(t *testing.T) {
+	useConfig(t, func(c *Config) { c.MoveRateLimit = 5 })
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+
+	// Illegal moves still count as attempts.
+	for i := 0; i < 20; i++ {
+		send(t, white, map[string]string{"action": "move", "gameID": gameID, "move": "Ke2"})
+	}
+	limited := 0
+	for i := 0; i < 20; i++ {
+		reply := readUntil(t, white, hasError)
+		switch {
+		case reply["error"] != "rate limit exceeded":
+		case i < 5:
+			t.Errorf("move %d was rate limited within the allowance", i)
+		case reply["retryAfter"] == nil:
+			t.Errorf("move %d was rate limited without a retryAfter", i)
+		default:
+			limited++
+		}
+	}
+	// The bucket refills at five a second, so at most a move or two more
+	// gets through while the twenty are sent.
+	if limited < 13 {
+		t.Errorf("%d of 20 rapid moves were rate limited, want at least 13", limited)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		trust     bool
+		forwarded []string
+		want      string
+	}{
+		{"peer address", false, nil, "192.0.2.1"},
+		{"untrusted header", false, []string{"198.51.100.7"}, "192.0.2.1"},
+		{"single entry", true, []string{"198.51.100.7"}, "198.51.100.7"},
+		{"client-supplied entries ignored", true, []string{"10.0.0.1, 203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"last header wins", true, []string{"10.0.0.1", "198.51.100.7"}, "198.51.100.7"},
+		{"normalised", true, []string{"2001:DB8:0:0::1"}, "2001:db8::1"},
+		{"garbage falls back to peer", true, []string{"10.0.0.1, not-an-ip"}, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, func(c *Config) { c.TrustForwardedFor = tt.trust })
+			r := httptest.NewRequest("GET", "/ws", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectionLimitPerIP(t *testing.T) {
+	useConfig(t, func(c *Config) { c.MaxConnectionsPerIP = 2 })
+	srv := newTestServer(t)
+	dial(t, srv)
+	dial(t, srv)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err == nil {
+		t.Fatal("third connection was accepted")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("third connection: got %v, want 429", resp)
+	}
+}
+
+func TestConnectionLimitSkipsTrustedIPs(t *testing.T) {
+	useConfig(t, func(c *Config) {
+		c.MaxConnectionsPerIP = 1
+		c.TrustedIPs = []string{"127.0.0.1"}
+	})
+	srv := newTestServer(t)
+	for i := 0; i < 3; i++ {
+		dial(t, srv)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// awaitReady holds a game whose second seat was just taken until both
+// players say they are ready. The caller must hold gamesMutex.
+func awaitReady(gameID string, game *Game) {
+	game.AwaitingReady = true
+	for _, player := range game.Players {
+		player.Ready = false
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(config.ReadyTimeout, func() {
+		expireReadyCheck(gameID, game, &timer)
+	})
+	game.readyTimer = timer
+}
+
+// cancelReadyCheck stops waiting for the players to be ready. The caller
+// must hold gamesMutex.
+func cancelReadyCheck(game *Game) {
+	game.AwaitingReady = false
+	for _, player := range game.Players {
+		player.Ready = false
+	}
+	if game.readyTimer != nil {
+		game.readyTimer.Stop()
+		game.readyTimer = nil
+	}
+}
+
+func readyCount(game *Game) int {
+	count := 0
+	for _, player := range game.Players {
+		if player.Ready {
+			count++
+		}
+	}
+	return count
+}
+
+// markReady records that the player on ws is ready, starting the game once
+// both are.
+func markReady(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	switch {
+	case player == nil:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "only players can be ready"})
+		return
+	case !game.AwaitingReady:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game is not waiting for players"})
+		return
+	case player.Ready:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "already ready"})
+		return
+	}
+
+	player.Ready = true
+	count := readyCount(game)
+	if count < len(game.Players) {
+		for _, p := range game.Players {
+			sendToPlayer(p, map[string]interface{}{"status": "waiting", "gameID": gameID, "readyCount": count})
+		}
+		gamesMutex.Unlock()
+		logger.Info("Player ready", slog.String("gameID", gameID), slog.String("playerColor", colorName(player.Color)))
+		return
+	}
+
+	cancelReadyCheck(game)
+	startClock(gameID, game)
+	for _, p := range game.Players {
+		sendToPlayer(p, map[string]string{"status": "started", "gameID": gameID})
+	}
+	gamesMutex.Unlock()
+	logger.Info("Game started", slog.String("gameID", gameID))
+
+	broadcastGameState(gameID)
+}
+
+// expireReadyCheck gives up on a ready check that has not completed within
+// the configured timeout. The player who joined loses their seat, leaving
+// the game open for someone else. timer is only read under gamesMutex,
+// which awaitReady's caller held while setting it.
+func expireReadyCheck(gameID string, game *Game, timer **time.Timer) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	if games[gameID] != game || game.readyTimer != *timer || len(game.Players) < 2 {
+		return
+	}
+	cancelReadyCheck(game)
+	game.Lock()
+	joiner := game.Players[1]
+	game.Players = game.Players[:1]
+	game.Unlock()
+	unindexGame(joiner.Conn, gameID)
+
+	sendToPlayer(joiner, map[string]string{"status": "removed", "gameID": gameID, "reason": "not ready in time"})
+	sendJSON(game.Players[0].Conn, map[string]interface{}{"status": "waiting", "gameID": gameID, "readyCount": 0})
+	logger.Info("Ready check timed out, seat reopened", slog.String("gameID", gameID))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// seatTwoPlayers creates a game and has a second player join it, without
+// either saying they are ready.
+func seatTwoPlayers(t *testing.T, srv *httptest.Server) (creator, joiner *websocket.Conn, gameID string) {
+	t.Helper()
+	creator, joiner = dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create"})
+	gameID = readUntil(t, creator, hasStatus("created"))["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, joiner, hasStatus("joined"))
+	return creator, joiner, gameID
+}
+
+func waitingWith(readyCount int) func(map[string]interface{}) bool {
+	return func(msg map[string]interface{}) bool {
+		return msg["status"] == "waiting" && msg["readyCount"] == float64(readyCount)
+	}
+}
+
+func TestBothPlayersReady(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner, gameID := seatTwoPlayers(t, srv)
+
+	send(t, creator, map[string]string{"action": "ready", "gameID": gameID})
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		readUntil(t, ws, waitingWith(1))
+	}
+	send(t, creator, map[string]string{"action": "ready", "gameID": gameID})
+	if msg := readUntil(t, creator, hasError); msg["error"] != "already ready" {
+		t.Errorf("saying ready twice: %v", msg["error"])
+	}
+
+	send(t, joiner, map[string]string{"action": "ready", "gameID": gameID})
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		readUntil(t, ws, hasStatus("started"))
+		if state := readUntil(t, ws, isState); state["status"] != "ongoing" {
+			t.Errorf("status once started = %v, want ongoing", state["status"])
+		}
+	}
+}
+
+func TestOnlyOnePlayerReady(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	creator, joiner, gameID := seatTwoPlayers(t, srv)
+
+	send(t, joiner, map[string]string{"action": "ready", "gameID": gameID})
+	readUntil(t, creator, waitingWith(1))
+
+	// Until the creator is ready too, the game has not started.
+	send(t, joiner, map[string]string{"action": "move", "gameID": gameID, "move": "e4"})
+	send(t, creator, map[string]string{"action": "move", "gameID": gameID, "move": "e4"})
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		readUntil(t, ws, hasError)
+	}
+	gamesMutex.RLock()
+	game := games[gameID]
+	awaiting, moves := game.AwaitingReady, len(game.Game.Moves())
+	gamesMutex.RUnlock()
+	if !awaiting || moves != 0 {
+		t.Errorf("awaiting ready = %v with %d moves, want true with none", awaiting, moves)
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	useConfig(t, func(c *Config) { c.ReadyTimeout = 100 * time.Millisecond })
+	srv := newTestServer(t)
+	creator, joiner, gameID := seatTwoPlayers(t, srv)
+	gamesMutex.RLock()
+	joinerConn := games[gameID].Players[1].Conn
+	gamesMutex.RUnlock()
+
+	send(t, creator, map[string]string{"action": "ready", "gameID": gameID})
+	if removed := readUntil(t, joiner, hasStatus("removed")); removed["reason"] != "not ready in time" {
+		t.Errorf("removed with reason %v", removed["reason"])
+	}
+	gamesMutex.RLock()
+	for _, id := range playerGames[joinerConn] {
+		if id == gameID {
+			t.Error("the removed joiner is still indexed under the game")
+		}
+	}
+	gamesMutex.RUnlock()
+	readUntil(t, creator, waitingWith(1))
+	readUntil(t, creator, waitingWith(0))
+
+	// The reopened seat can be taken by someone else.
+	other := dial(t, srv)
+	send(t, other, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, other, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, other} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	for _, ws := range []*websocket.Conn{creator, other} {
+		readUntil(t, ws, hasStatus("started"))
+	}
+}
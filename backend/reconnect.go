@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// hasConnectedPlayers reports whether any player of game is still online.
+func hasConnectedPlayers(game *Game) bool {
+	for _, player := range game.Players {
+		if player.Conn != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// forfeitAbsentPlayer ends the game against player if they have not come
+// back by the end of the grace period.
+func forfeitAbsentPlayer(gameID string, player *Player) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists || player.Conn != nil {
+		gamesMutex.Unlock()
+		return
+	}
+	player.ForfeitTimer = nil
+	forfeited := game.Game.Outcome() == chess.NoOutcome
+	if forfeited {
+		game.Game.Resign(player.Color)
+		game.Abandoned = true
+		clearTakebackRequest(game)
+	}
+	gamesMutex.Unlock()
+
+	if forfeited {
+		logger.Info("Player forfeited after disconnecting", slog.String("gameID", gameID), slog.String("playerColor", colorName(player.Color)))
+		broadcastGameState(gameID)
+	}
+
+	gamesMutex.Lock()
+	var winner *websocket.Conn
+	if game, exists := games[gameID]; exists {
+		if other := opponent(game, player.Color); forfeited && other != nil && other.Conn != nil && !inOtherGames(other.Conn, gameID) {
+			winner = other.Conn
+		}
+		if !hasConnectedPlayers(game) {
+			game.Lock()
+			deleteGame(gameID, game)
+			game.Unlock()
+			logger.Info("Game deleted", slog.String("gameID", gameID))
+		}
+	}
+	gamesMutex.Unlock()
+
+	// The opponent has nothing left to do here, unless they are busy in
+	// another game, which closing the connection would end too.
+	if winner != nil {
+		closeConnection(winner, CloseOpponentAbandoned, "opponent abandoned the game")
+	}
+}
+
+// reconnectPlayer gives ws the seat of playerID, or a guest's seat by
+// their guestID while it lasts. If lastMessageID is set,
+// the messages to the seat numbered after it, sent while the player was
+// away or lost with the old connection, are replayed before anything new.
+func reconnectPlayer(ws *websocket.Conn, gameID, playerID, guestID, lastMessageID string) {
+	var lastID int64
+	if lastMessageID != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(lastMessageID, 10, 64); err != nil || lastID < 0 {
+			sendJSON(ws, map[string]string{"error": "invalid lastMessageID"})
+			return
+		}
+	}
+
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to reconnect to non-existent game", slog.String("gameID", gameID))
+		return
+	}
+
+	var player *Player
+	var asGuest bool
+	for _, p := range game.Players {
+		if playerID != "" && p.PlayerID == playerID {
+			player = p
+			break
+		}
+		if guestID != "" && p.GuestID == guestID {
+			player, asGuest = p, true
+			break
+		}
+	}
+	if asGuest && !time.Now().Before(player.GuestExpires) {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "guest ID expired"})
+		return
+	}
+	if player == nil {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "player not found"})
+		logger.Warn("Attempt to reconnect unknown player", slog.String("gameID", gameID))
+		return
+	}
+
+	// The new connection already runs its own read loop, so taking over
+	// the seat is all that is needed. The replay is queued under the locks
+	// so nothing sent to the seat meanwhile can overtake it.
+	game.Lock()
+	player.Conn = ws
+	indexGame(ws, gameID)
+	adoptGuest(ws, player)
+	if player.ForfeitTimer != nil {
+		player.ForfeitTimer.Stop()
+		player.ForfeitTimer = nil
+	}
+	color := player.Color
+	reply := map[string]interface{}{"status": "reconnected", "gameID": gameID, "color": color.String(), "playerID": player.PlayerID}
+	if player.GuestID != "" {
+		reply["guestID"] = player.GuestID
+	}
+	var missed [][]byte
+	if lastMessageID != "" {
+		// replayComplete is false if the buffer no longer holds some of
+		// the missed messages, in which case the client should syncState.
+		var complete bool
+		missed, complete = player.messages().since(lastID)
+		reply["replayComplete"] = complete
+	}
+	sendJSON(ws, reply)
+	for _, data := range missed {
+		enqueue(ws, outgoing{data: data})
+	}
+	game.Unlock()
+	gamesMutex.Unlock()
+
+	logger.Info("Player reconnected", slog.String("gameID", gameID), slog.String("playerColor", colorName(color)))
+
+	broadcastGameState(gameID)
+}
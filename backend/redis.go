@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisTimeout bounds each round trip to Redis.
+	redisTimeout = 5 * time.Second
+	// redisGameKeyPrefix prefixes the key of each stored game, and
+	// redisGameSet names the set of stored game IDs. Archived games are
+	// kept the same way under redisArchiveKeyPrefix and redisArchiveSet.
+	// redisRatingsHash maps player IDs to ratings, and
+	// redisSchemaVersionKey holds the store's schema version.
+	redisGameKeyPrefix    = "chess:game:"
+	redisGameSet          = "chess:games"
+	redisArchiveKeyPrefix = "chess:archive:"
+	redisArchiveSet       = "chess:archive"
+	redisRatingsHash      = "chess:ratings"
+	redisSchemaVersionKey = "chess:schema_version"
+)
+
+// RedisStore keeps each game as JSON under its own key, with the game IDs
+// in a set, archived games likewise, and the ratings in a hash.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(addr string) *RedisStore {
+	return &RedisStore{client: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})}
+}
+
+func (s *RedisStore) SaveGame(id string, data []byte) error {
+	ctx := context.Background()
+	if err := s.client.Set(ctx, redisGameKeyPrefix+id, data, 0).Err(); err != nil {
+		return err
+	}
+	return s.client.SAdd(ctx, redisGameSet, id).Err()
+}
+
+func (s *RedisStore) LoadGame(id string) (*Game, error) {
+	data, err := s.LoadGameData(id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeGame(data)
+}
+
+func (s *RedisStore) LoadGameData(id string) ([]byte, error) {
+	data, err := s.client.Get(context.Background(), redisGameKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errGameNotStored
+	}
+	return data, err
+}
+
+func (s *RedisStore) DeleteGame(id string) error {
+	ctx := context.Background()
+	if err := s.client.Del(ctx, redisGameKeyPrefix+id).Err(); err != nil {
+		return err
+	}
+	return s.client.SRem(ctx, redisGameSet, id).Err()
+}
+
+func (s *RedisStore) ListGames() ([]string, error) {
+	return s.client.SMembers(context.Background(), redisGameSet).Result()
+}
+
+func (s *RedisStore) ArchiveGame(id string, data []byte) error {
+	ctx := context.Background()
+	if err := s.client.Set(ctx, redisArchiveKeyPrefix+id, data, 0).Err(); err != nil {
+		return err
+	}
+	return s.client.SAdd(ctx, redisArchiveSet, id).Err()
+}
+
+func (s *RedisStore) LoadArchive() (map[string][]byte, error) {
+	ctx := context.Background()
+	ids, err := s.client.SMembers(ctx, redisArchiveSet).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisArchiveKeyPrefix + id
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	archived := make(map[string][]byte, len(ids))
+	for i, value := range values {
+		if data, ok := value.(string); ok {
+			archived[ids[i]] = []byte(data)
+		}
+	}
+	return archived, nil
+}
+
+func (s *RedisStore) SaveRatings(ratings map[string]int) error {
+	fields := make(map[string]interface{}, len(ratings))
+	for playerID, rating := range ratings {
+		fields[playerID] = rating
+	}
+	return s.client.HSet(context.Background(), redisRatingsHash, fields).Err()
+}
+
+func (s *RedisStore) LoadRatings() (map[string]int, error) {
+	fields, err := s.client.HGetAll(context.Background(), redisRatingsHash).Result()
+	if err != nil {
+		return nil, err
+	}
+	ratings := make(map[string]int, len(fields))
+	for playerID, value := range fields {
+		if rating, err := strconv.Atoi(value); err == nil {
+			ratings[playerID] = rating
+		}
+	}
+	return ratings, nil
+}
+
+// SchemaVersion returns 0 if the version was never set.
+func (s *RedisStore) SchemaVersion() (int, error) {
+	version, err := s.client.Get(context.Background(), redisSchemaVersionKey).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (s *RedisStore) SetSchemaVersion(version int) error {
+	return s.client.Set(context.Background(), redisSchemaVersionKey, version, 0).Err()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedisStoreMissingKeys(t *testing.T) {
+	store := newRedisStore(newFakeRedis(t))
+	if _, err := store.LoadGameData("missing"); !errors.Is(err, errGameNotStored) {
+		t.Errorf("loading a missing game: %v, want %v", err, errGameNotStored)
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != 0 {
+		t.Errorf("unset schema version = %d, %v, want 0", version, err)
+	}
+	if err := store.SetSchemaVersion(currentSchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := store.SchemaVersion(); err != nil || version != currentSchemaVersion {
+		t.Errorf("schema version = %d, %v, want %d", version, err, currentSchemaVersion)
+	}
+}
+
+func TestRedisStoreUnreachable(t *testing.T) {
+	store := newRedisStore("127.0.0.1:1")
+	start := time.Now()
+	if _, err := store.ListGames(); err == nil {
+		t.Error("ListGames succeeded with no server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*redisTimeout {
+		t.Errorf("ListGames with no server took %v", elapsed)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/segmentio/ksuid"
+)
+
+func offerRematch(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	switch {
+	case player == nil:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "only players can offer a rematch"})
+		return
+	case game.Game.Outcome() == chess.NoOutcome:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game still in progress"})
+		return
+	case game.RematchOffer == player.Color:
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "a rematch offer is already pending"})
+		return
+	case game.RematchOffer == player.Color.Other():
+		// Both sides want a rematch.
+		gamesMutex.Unlock()
+		acceptRematch(ws, gameID)
+		return
+	}
+
+	game.RematchOffer = player.Color
+	if other := opponent(game, player.Color); other != nil {
+		sendToPlayer(other, map[string]string{"status": "rematchOffer", "from": colorName(player.Color)})
+	}
+	gamesMutex.Unlock()
+	logger.Info("Rematch offered", slog.String("gameID", gameID))
+}
+
+// acceptRematch starts a new game between the same players with colors
+// swapped and the original game's settings.
+func acceptRematch(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	if player == nil || game.RematchOffer != player.Color.Other() {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "no rematch offer to accept"})
+		return
+	}
+	other := opponent(game, player.Color)
+	if other == nil || other.Conn == nil {
+		game.RematchOffer = chess.NoColor
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "opponent not connected"})
+		return
+	}
+	game.RematchOffer = chess.NoColor
+
+	chessGame := chess.NewGame()
+	if opt, err := chess.FEN(game.StartingFEN); err == nil {
+		chessGame = chess.NewGame(opt)
+	}
+	var clock *GameClock
+	if game.TimeControl != "" {
+		clock, _ = parseTimeControl(game.TimeControl)
+	}
+	rematch := &Game{
+		Game:              chessGame,
+		StartingFEN:       game.StartingFEN,
+		Variant:           game.Variant,
+		CreatedAt:         time.Now(),
+		Clock:             clock,
+		TimeControl:       game.TimeControl,
+		ApplyMoveDelay:    game.ApplyMoveDelay,
+		StalemateMeansWin: game.StalemateMeansWin,
+		Ranked:            game.Ranked,
+	}
+	for _, p := range []*Player{player, other} {
+		playerID, subject, _ := identify(p.Conn, p.Name)
+		seat := &Player{
+			Conn:         p.Conn,
+			Color:        p.Color.Other(),
+			PlayerID:     playerID,
+			Subject:      subject,
+			ConfirmMoves: p.ConfirmMoves,
+			Name:         p.Name,
+		}
+		seatGuest(seat)
+		if rematch.Ranked {
+			seat.Rating = ratingOf(subject)
+		}
+		rematch.Players = append(rematch.Players, seat)
+	}
+	newGameID := ksuid.New().String()
+	games[newGameID] = rematch
+	for _, p := range rematch.Players {
+		indexGame(p.Conn, newGameID)
+	}
+	watchGuestGame(newGameID, rematch)
+	gamesCreated.Inc()
+	startClock(newGameID, rematch)
+	gamesMutex.Unlock()
+
+	for _, p := range rematch.Players {
+		sendToPlayer(p, withGuestID(map[string]string{"status": "rematchStarted", "newGameID": newGameID, "color": p.Color.String(), "playerID": p.PlayerID}, p))
+	}
+	logger.Info("Rematch started", slog.String("gameID", gameID), slog.String("newGameID", newGameID))
+
+	broadcastGameState(newGameID)
+}
+
+func declineRematch(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	if player == nil || game.RematchOffer != player.Color.Other() {
+		sendJSON(ws, map[string]string{"error": "no rematch offer to decline"})
+		return
+	}
+	game.RematchOffer = chess.NoColor
+	if other := opponent(game, player.Color); other != nil {
+		sendToPlayer(other, map[string]string{"status": "rematchDeclined", "from": colorName(player.Color)})
+	}
+	logger.Info("Rematch declined", slog.String("gameID", gameID))
+}
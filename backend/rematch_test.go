@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRematch(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	send(t, white, map[string]string{"action": "offerRematch", "gameID": gameID})
+	if msg := readUntil(t, white, hasError); msg["error"] != "game still in progress" {
+		t.Errorf("offering a rematch during the game: %v", msg["error"])
+	}
+
+	play(t, white, black, gameID, "e4")
+	send(t, black, map[string]string{"action": "resign", "gameID": gameID})
+	readUntil(t, white, hasStatus("resigned"))
+
+	send(t, white, map[string]string{"action": "offerRematch", "gameID": gameID})
+	if offer := readUntil(t, black, hasStatus("rematchOffer")); offer["from"] != "white" {
+		t.Errorf("rematch offer from %v, want white", offer["from"])
+	}
+	send(t, black, map[string]string{"action": "acceptRematch", "gameID": gameID})
+
+	var newGameID string
+	for ws, color := range map[*websocket.Conn]string{white: "b", black: "w"} {
+		started := readUntil(t, ws, hasStatus("rematchStarted"))
+		if started["color"] != color {
+			t.Errorf("rematch color = %v, want %s", started["color"], color)
+		}
+		if newGameID == "" {
+			newGameID, _ = started["newGameID"].(string)
+		} else if started["newGameID"] != newGameID {
+			t.Errorf("players were sent to games %s and %v", newGameID, started["newGameID"])
+		}
+		if state := readUntil(t, ws, isState); state["moveCount"] != float64(0) {
+			t.Errorf("rematch starts with %v moves", state["moveCount"])
+		}
+	}
+	if newGameID == "" || newGameID == gameID {
+		t.Fatalf("rematch game ID = %q", newGameID)
+	}
+
+	// Colours are swapped, so the old black player opens the new game.
+	state := play(t, black, white, newGameID, "d4", "d5")
+	if state["status"] != "ongoing" {
+		t.Errorf("rematch status = %v, want ongoing", state["status"])
+	}
+
+	send(t, black, map[string]string{"action": "acceptRematch", "gameID": gameID})
+	if msg := readUntil(t, black, hasError); msg["error"] != "no rematch offer to accept" {
+		t.Errorf("accepting a used offer: %v", msg["error"])
+	}
+}
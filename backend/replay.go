@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// messageLog numbers the messages sent to a seat and keeps the latest
+// config.ReplayBufferSize of them, so that a player who reconnects can be
+// sent whatever they missed. It has its own lock, as messages are sent to
+// players from under either gamesMutex or the game's lock.
+type messageLog struct {
+	mu     sync.Mutex
+	lastID int64
+	// recent holds the kept messages, oldest first.
+	recent []loggedMessage
+}
+
+type loggedMessage struct {
+	id   int64
+	data []byte
+}
+
+// record numbers data, a JSON object, with the seat's next messageID and
+// keeps it, returning the numbered message.
+func (l *messageLog) record(data []byte) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	numbered := []byte(`{"messageID":` + strconv.FormatInt(l.lastID, 10))
+	if len(data) > 2 {
+		numbered = append(numbered, ',')
+	}
+	numbered = append(numbered, data[1:]...)
+	if config.ReplayBufferSize > 0 {
+		l.recent = append(l.recent, loggedMessage{id: l.lastID, data: numbered})
+		if len(l.recent) > config.ReplayBufferSize {
+			l.recent = l.recent[len(l.recent)-config.ReplayBufferSize:]
+		}
+	}
+	return numbered
+}
+
+// since returns the kept messages numbered after lastID, oldest first, and
+// whether they are all of them: false if some were already dropped.
+func (l *messageLog) since(lastID int64) ([][]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var missed [][]byte
+	for _, m := range l.recent {
+		if m.id > lastID {
+			missed = append(missed, m.data)
+		}
+	}
+	complete := lastID >= l.lastID || (len(l.recent) > 0 && l.recent[0].id <= lastID+1)
+	return missed, complete
+}
+
+// messageLogsMutex guards starting each player's message log.
+var messageLogsMutex sync.Mutex
+
+// messages returns p's message log, starting it if need be.
+func (p *Player) messages() *messageLog {
+	messageLogsMutex.Lock()
+	defer messageLogsMutex.Unlock()
+	if p.log == nil {
+		p.log = &messageLog{}
+	}
+	return p.log
+}
+
+// sendToPlayer is sendJSON for a message to the player in a seat rather
+// than to a connection. It is numbered with a messageID and kept for
+// replay, even while the player is disconnected, and sent if they are
+// not. The caller must hold gamesMutex or the game's lock.
+func sendToPlayer(player *Player, v interface{}) bool {
+	return queueForPlayer(player, v, isErrorReply(v))
+}
+
+// sendControlToPlayer is sendControl for a message to a seat.
+func sendControlToPlayer(player *Player, v interface{}) bool {
+	return queueForPlayer(player, v, true)
+}
+
+func queueForPlayer(player *Player, v interface{}, control bool) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Error("Error encoding response", slog.Any("error", err))
+		return false
+	}
+	data = player.messages().record(data)
+	if player.Conn == nil {
+		return false
+	}
+	return enqueue(player.Conn, outgoing{data: data, control: control})
+}
+
+// sendTo sends v to ws through player's seat if ws is a player's, and
+// straight to it otherwise.
+func sendTo(ws *websocket.Conn, player *Player, v interface{}) bool {
+	if player != nil {
+		return sendToPlayer(player, v)
+	}
+	return sendJSON(ws, v)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+func TestMessageLog(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.ReplayBufferSize = 2 })
+
+	var log messageLog
+	if got := string(log.record([]byte(`{"type":"a"}`))); got != `{"messageID":1,"type":"a"}` {
+		t.Errorf("first message = %s", got)
+	}
+	if got := string(log.record([]byte(`{}`))); got != `{"messageID":2}` {
+		t.Errorf("empty message = %s", got)
+	}
+	missed, complete := log.since(0)
+	if len(missed) != 2 || !complete {
+		t.Errorf("since 0 = %d messages, complete %v; want 2, true", len(missed), complete)
+	}
+
+	log.record([]byte(`{"type":"c"}`))
+	missed, complete = log.since(0)
+	if len(missed) != 2 || complete {
+		t.Errorf("since 0 past the limit = %d messages, complete %v; want 2, false", len(missed), complete)
+	}
+	missed, complete = log.since(1)
+	if len(missed) != 2 || !complete || string(missed[1]) != `{"messageID":3,"type":"c"}` {
+		t.Errorf("since 1 = %q, complete %v", missed, complete)
+	}
+	if missed, complete = log.since(3); len(missed) != 0 || !complete {
+		t.Errorf("since the latest = %d messages, complete %v; want none, true", len(missed), complete)
+	}
+}
+
+// TestReconnectReplaysMissedMessages has white drop out and black move
+// while white's seat is held, then checks that white gets the state they
+// missed on coming back.
+func TestReconnectReplaysMissedMessages(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+
+	creator, joiner := dial(t, srv), dial(t, srv)
+	send(t, creator, map[string]string{"action": "create"})
+	created := readUntil(t, creator, hasStatus("created"))
+	gameID := created["gameID"].(string)
+	send(t, joiner, map[string]string{"action": "join", "gameID": gameID})
+	joined := readUntil(t, joiner, hasStatus("joined"))
+	for _, ws := range []*websocket.Conn{creator, joiner} {
+		send(t, ws, map[string]string{"action": "ready", "gameID": gameID})
+	}
+	white, black, whiteID := creator, joiner, created["playerID"].(string)
+	if created["color"] != "w" {
+		white, black, whiteID = joiner, creator, joined["playerID"].(string)
+	}
+	readUntil(t, black, isState)
+	state := play(t, white, black, gameID, "e4")
+	lastID, ok := state["messageID"].(float64)
+	if !ok {
+		t.Fatalf("state %v has no messageID", state)
+	}
+
+	white.Close()
+	waitForHeldSeat(t, gameID, chess.White)
+	send(t, black, map[string]string{"action": "move", "gameID": gameID, "move": "e5"})
+	readUntil(t, black, func(msg map[string]interface{}) bool { return isState(msg) && msg["moveCount"] == float64(2) })
+
+	back := dial(t, srv)
+	send(t, back, map[string]string{"action": "reconnect", "gameID": gameID, "playerID": whiteID, "lastMessageID": strconv.FormatFloat(lastID, 'f', -1, 64)})
+	if reply := readUntil(t, back, hasStatus("reconnected")); reply["replayComplete"] != true {
+		t.Errorf("reconnect reply %v, want a complete replay", reply)
+	}
+	replayed := readUntil(t, back, isState)
+	if replayed["messageID"] != lastID+1 || replayed["moveCount"] != float64(2) {
+		t.Errorf("replayed %v, want the state after e5 numbered %v", replayed, lastID+1)
+	}
+
+	send(t, back, map[string]string{"action": "reconnect", "gameID": gameID, "playerID": whiteID, "lastMessageID": "x"})
+	if reply := readUntil(t, back, hasError); reply["error"] != "invalid lastMessageID" {
+		t.Errorf("bad lastMessageID: got %v", reply)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+func resignGame(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	color := getPlayerColor(ws, game)
+	if color == chess.NoColor {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "only players can resign"})
+		return
+	}
+	if game.Game.Outcome() != chess.NoOutcome {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	}
+	game.Game.Resign(color)
+	clearTakebackRequest(game)
+	gamesMutex.Unlock()
+
+	logger.Info("Player resigned", slog.String("gameID", gameID), slog.String("playerColor", colorName(color)))
+	broadcastGameState(gameID)
+}
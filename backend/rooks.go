@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type RookActivity struct {
+	OpenFile     bool `json:"openFile"`
+	HalfOpenFile bool `json:"halfOpenFile"`
+	SeventhRank  bool `json:"seventhRank"`
+	Connected    bool `json:"connected"`
+	Score        int  `json:"score"`
+}
+
+// rooksConnected reports whether two rooks share a rank or file with nothing
+// between them.
+func rooksConnected(board *chess.Board, a, b chess.Square) bool {
+	for _, target := range analysis.AttackedSquares(board, a, board.Piece(a)) {
+		if target == b {
+			return true
+		}
+	}
+	return false
+}
+
+func evaluateRookActivity(board *chess.Board, color chess.Color) RookActivity {
+	var activity RookActivity
+	own := analysis.PawnFiles(board, color)
+	enemy := analysis.PawnFiles(board, color.Other())
+
+	var rooks []chess.Square
+	for sq, piece := range board.SquareMap() {
+		if piece.Type() == chess.Rook && piece.Color() == color {
+			rooks = append(rooks, sq)
+		}
+	}
+	for _, sq := range rooks {
+		f := sq.File()
+		if own[f] == 0 && enemy[f] == 0 {
+			activity.OpenFile = true
+		} else if own[f] == 0 {
+			activity.HalfOpenFile = true
+		}
+		if analysis.RelativeRank(sq, color) == 6 {
+			activity.SeventhRank = true
+		}
+	}
+	if len(rooks) == 2 {
+		activity.Connected = rooksConnected(board, rooks[0], rooks[1])
+	}
+
+	if activity.OpenFile {
+		activity.Score += 4
+	} else if activity.HalfOpenFile {
+		activity.Score += 2
+	}
+	if activity.SeventhRank {
+		activity.Score += 3
+	}
+	if activity.Connected {
+		activity.Score += 4
+	}
+	return activity
+}
+
+// suggestRookMove returns a hint naming a legal rook move that reaches an
+// open file, or an empty string if the side to move already has one there.
+func suggestRookMove(pos *chess.Position) string {
+	board := pos.Board()
+	color := pos.Turn()
+	if evaluateRookActivity(board, color).OpenFile {
+		return ""
+	}
+	own := analysis.PawnFiles(board, color)
+	enemy := analysis.PawnFiles(board, color.Other())
+	for _, m := range pos.ValidMoves() {
+		if board.Piece(m.S1()).Type() != chess.Rook || m.S1().File() == m.S2().File() {
+			continue
+		}
+		if f := m.S2().File(); own[f] == 0 && enemy[f] == 0 {
+			return fmt.Sprintf("%s would give your rook an open file", chess.AlgebraicNotation{}.Encode(pos, m))
+		}
+	}
+	return ""
+}
+
+func getRookActivity(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	board := pos.Board()
+	sendJSON(ws, map[string]interface{}{
+		"white":               evaluateRookActivity(board, chess.White),
+		"black":               evaluateRookActivity(board, chess.Black),
+		"bestMove_suggestion": suggestRookMove(pos),
+	})
+	logger.Debug("Rook activity sent", slog.String("gameID", gameID))
+}
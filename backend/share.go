@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+const (
+	// sharedFENCacheSize caps how many decoded links are remembered, and
+	// sharedFENCacheTTL how long each is kept, since anyone can make up
+	// links.
+	sharedFENCacheSize = 1000
+	sharedFENCacheTTL  = time.Hour
+)
+
+// sharedFEN is a decoded link remembered until expires.
+type sharedFEN struct {
+	fen     string
+	expires time.Time
+}
+
+// sharedFENs remembers recently decoded links. sharedFENOrder holds its
+// keys oldest first; every entry lives equally long, so that is also the
+// order they expire in.
+var (
+	sharedFENs      = make(map[string]sharedFEN)
+	sharedFENOrder  []string
+	sharedFENsMutex sync.Mutex
+)
+
+func cachedSharedFEN(encoded string, now time.Time) (string, bool) {
+	sharedFENsMutex.Lock()
+	defer sharedFENsMutex.Unlock()
+	cached, ok := sharedFENs[encoded]
+	if !ok || now.After(cached.expires) {
+		return "", false
+	}
+	return cached.fen, true
+}
+
+// cacheSharedFEN remembers fen for encoded, first dropping expired entries
+// and, if the cache is still full, the oldest.
+func cacheSharedFEN(encoded, fen string, now time.Time) {
+	sharedFENsMutex.Lock()
+	defer sharedFENsMutex.Unlock()
+	if _, ok := sharedFENs[encoded]; ok {
+		return
+	}
+	for len(sharedFENOrder) > 0 {
+		oldest := sharedFENOrder[0]
+		if len(sharedFENOrder) < sharedFENCacheSize && !now.After(sharedFENs[oldest].expires) {
+			break
+		}
+		delete(sharedFENs, oldest)
+		sharedFENOrder = sharedFENOrder[1:]
+	}
+	sharedFENs[encoded] = sharedFEN{fen: fen, expires: now.Add(sharedFENCacheTTL)}
+	sharedFENOrder = append(sharedFENOrder, encoded)
+}
+
+func sharePosition(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	fen := pos.String()
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(fen))
+	sendJSON(ws, map[string]string{"url": config.ShareBaseURL + "/pos/" + encoded, "fen": fen})
+	logger.Info("Position shared", slog.String("gameID", gameID))
+}
+
+// decodeSharedFEN decodes and validates a FEN from a shared position link,
+// accepting only positions createGame would, and remembers valid ones.
+func decodeSharedFEN(encoded string) (string, bool) {
+	now := time.Now()
+	if fen, ok := cachedSharedFEN(encoded, now); ok {
+		return fen, true
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	fen := string(raw)
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return "", false
+	}
+	pos := chess.NewGame(opt).Position()
+	if checkPosition(pos.Board(), pos.Turn()) != nil {
+		return "", false
+	}
+	cacheSharedFEN(encoded, fen, now)
+	return fen, true
+}
+
+func handleSharedPosition(w http.ResponseWriter, r *http.Request) {
+	fen, ok := decodeSharedFEN(r.PathValue("encoded"))
+	if !ok {
+		http.Error(w, "invalid position", http.StatusBadRequest)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"fen": fen}); err != nil {
+			logger.Error("Error writing shared position", slog.Any("error", err))
+		}
+		return
+	}
+
+	http.Redirect(w, r, config.FrontendURL+"/?fen="+url.QueryEscape(fen), http.StatusFound)
+}
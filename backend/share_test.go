@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleSharedPosition(t *testing.T) {
+	useConfig(t, func(c *Config) { c.FrontendURL = "https://chess.example.com" })
+	fen := middlegameFEN
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(fen))
+
+	get := func(encoded, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/pos/"+encoded, nil)
+		r.SetPathValue("encoded", encoded)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		handleSharedPosition(w, r)
+		return w
+	}
+
+	w := get(encoded, "")
+	if want := "https://chess.example.com/?fen=" + url.QueryEscape(fen); w.Code != http.StatusFound || w.Header().Get("Location") != want {
+		t.Errorf("redirect: got %d to %q, want %d to %q", w.Code, w.Header().Get("Location"), http.StatusFound, want)
+	}
+	w = get(encoded, "application/json")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"fen":"`+fen+`"`) {
+		t.Errorf("JSON: got %d %s", w.Code, w.Body)
+	}
+	for _, bad := range []string{
+		"not*base64",
+		base64.RawURLEncoding.EncodeToString([]byte("not a fen")),
+		// No white king, and then black is in check with white to move.
+		base64.RawURLEncoding.EncodeToString([]byte("4k3/8/8/8/8/8/8/8 w - - 0 1")),
+		base64.RawURLEncoding.EncodeToString([]byte("4k3/8/8/8/8/8/8/4RK2 w - - 0 1")),
+	} {
+		if w := get(bad, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: got %d, want %d", bad, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestSharedFENCache(t *testing.T) {
+	sharedFENsMutex.Lock()
+	savedFENs, savedOrder := sharedFENs, sharedFENOrder
+	sharedFENs, sharedFENOrder = make(map[string]sharedFEN), nil
+	sharedFENsMutex.Unlock()
+	t.Cleanup(func() {
+		sharedFENsMutex.Lock()
+		sharedFENs, sharedFENOrder = savedFENs, savedOrder
+		sharedFENsMutex.Unlock()
+	})
+
+	now := time.Now()
+	for i := 0; i < sharedFENCacheSize+10; i++ {
+		cacheSharedFEN(strconv.Itoa(i), "fen", now)
+	}
+	if len(sharedFENs) != sharedFENCacheSize || len(sharedFENOrder) != sharedFENCacheSize {
+		t.Errorf("cache holds %d entries in order %d, want %d", len(sharedFENs), len(sharedFENOrder), sharedFENCacheSize)
+	}
+	if _, ok := cachedSharedFEN("0", now); ok {
+		t.Error("the oldest entry was kept past the size cap")
+	}
+	if fen, ok := cachedSharedFEN("10", now); !ok || fen != "fen" {
+		t.Errorf("entry 10 = %q, %v", fen, ok)
+	}
+	if _, ok := cachedSharedFEN("10", now.Add(sharedFENCacheTTL+time.Second)); ok {
+		t.Error("an entry was returned after it expired")
+	}
+
+	cacheSharedFEN("late", "fen", now.Add(sharedFENCacheTTL+time.Second))
+	if len(sharedFENs) != 1 {
+		t.Errorf("cache holds %d entries after the rest expired, want 1", len(sharedFENs))
+	}
+}
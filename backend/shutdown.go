@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// shutdownTimeout bounds how long in-flight HTTP requests get to finish.
+const shutdownTimeout = 15 * time.Second
+
+// serverStopping is closed when shutdown begins, ending streams that would
+// otherwise hold the server open.
+var serverStopping = make(chan struct{})
+
+// writeSnapshot saves every game to path as a JSON object of encoded games
+// by ID.
+func writeSnapshot(path string) error {
+	snapshot := newMemoryStore()
+	saveGames(snapshot)
+	encoded := make(map[string]json.RawMessage, len(snapshot.games))
+	for id, data := range snapshot.games {
+		encoded[id] = data
+	}
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		return err
+	}
+	// Write a temporary file first so a failed write cannot clobber an
+	// earlier snapshot.
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// restoreSnapshot restores the games saved by writeSnapshot, then removes
+// the file so a later crash cannot bring back games that have since moved
+// on. A missing file is not an error.
+func restoreSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var encoded map[string]json.RawMessage
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	snapshot := newMemoryStore()
+	for id, game := range encoded {
+		snapshot.games[id] = game
+	}
+	if err := restoreGames(snapshot); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// gameConnections returns every connection taking part in a game.
+func gameConnections() []*websocket.Conn {
+	gamesMutex.RLock()
+	defer gamesMutex.RUnlock()
+	var conns []*websocket.Conn
+	for _, game := range games {
+		game.Lock()
+		for _, player := range game.Players {
+			if player.Conn != nil {
+				conns = append(conns, player.Conn)
+			}
+		}
+		conns = append(conns, game.Coaches...)
+		conns = append(conns, game.Spectators...)
+		game.Unlock()
+	}
+	return conns
+}
+
+// shutdown tells everyone in a game that the server is restarting, saves
+// the games and ratings to the store and then the games to the snapshot
+// file, which is restored in preference to the store, then stops server.
+// Games are saved before connections close, as closing them ends or
+// deletes games.
+func shutdown(server *http.Server, store Store) {
+	logger.Info("Shutting down server")
+	close(serverStopping)
+
+	conns := gameConnections()
+	for _, ws := range conns {
+		sendJSON(ws, map[string]string{"status": "serverShutdown", "message": "Server restarting, please reconnect"})
+	}
+
+	saveGames(store)
+	saveRatings(store)
+	if err := writeSnapshot(config.SnapshotPath); err != nil {
+		logger.Error("Error writing game snapshot", slog.Any("error", err))
+	} else {
+		logger.Info("Games saved", slog.String("path", config.SnapshotPath))
+	}
+
+	// Hijacked WebSocket connections are not closed by Shutdown.
+	for _, ws := range conns {
+		closeConnection(ws, CloseServerShutdown, "server restarting")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Error shutting down server", slog.Any("error", err))
+	}
+}
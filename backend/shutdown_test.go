@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestShutdownWritesSnapshot starts a server, plays into a game, sends
+// the process SIGTERM and checks that shutdown saves the game to the
+// snapshot file, from which it can be restored.
+func TestShutdownWritesSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	useConfig(t, func(c *Config) { c.SnapshotPath = path })
+	useGames(t)
+	t.Cleanup(func() { serverStopping = make(chan struct{}) })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: testHandler(t)}
+	served := make(chan error, 1)
+	go func() { served <- serve(server, ln, config) }()
+	srv := &httptest.Server{URL: "http://" + ln.Addr().String()}
+
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "d4", "Nf6", "c4")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGTERM was not delivered")
+	}
+	shutdown(server, newMemoryStore())
+
+	for _, ws := range []*websocket.Conn{white, black} {
+		msg := readUntil(t, ws, hasStatus("serverShutdown"))
+		if msg["message"] != "Server restarting, please reconnect" {
+			t.Errorf("shutdown message = %v", msg["message"])
+		}
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("serve returned %v, want %v", err, http.ErrServerClosed)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading snapshot: %v", err)
+	}
+	var snapshot map[string]gameRecord
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatalf("decoding snapshot: %v", err)
+	}
+	record, ok := snapshot[gameID]
+	if !ok {
+		t.Fatalf("snapshot has games %v, not %s", snapshot, gameID)
+	}
+	if want := []string{"d2d4", "g8f6", "c2c4"}; len(record.Moves) != len(want) || record.Moves[0] != want[0] || record.Moves[2] != want[2] {
+		t.Errorf("saved moves = %v, want %v", record.Moves, want)
+	}
+	if len(record.Players) != 2 || record.Outcome != "*" {
+		t.Errorf("saved %d players with outcome %s, want 2 and *", len(record.Players), record.Outcome)
+	}
+
+	gamesMutex.Lock()
+	games = make(map[string]*Game)
+	gamesMutex.Unlock()
+	if err := restoreSnapshot(path); err != nil {
+		t.Fatalf("restoring snapshot: %v", err)
+	}
+	gamesMutex.Lock()
+	restored := games[gameID]
+	gamesMutex.Unlock()
+	if restored == nil {
+		t.Fatal("the game was not restored")
+	}
+	restored.Lock()
+	moves := len(restored.Game.Moves())
+	for _, p := range restored.Players {
+		p.ForfeitTimer.Stop()
+	}
+	restored.Unlock()
+	if moves != 3 {
+		t.Errorf("restored %d moves, want 3", moves)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("snapshot file left behind after restoring: %v", err)
+	}
+}
+
+// TestSnapshotWinsOverStore restores a game kept in both the snapshot and
+// the store, in the order main does, and checks that the snapshot's copy
+// is the one restored.
+func TestSnapshotWinsOverStore(t *testing.T) {
+	useConfig(t, nil)
+	useGames(t)
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	older := storedTestGame(t)
+	newer := storedTestGame(t)
+	if err := newer.Game.MoveStr("Nf3"); err != nil {
+		t.Fatal(err)
+	}
+	store := newMemoryStore()
+	data, err := encodeGame(older)
+	if err == nil {
+		err = store.SaveGame("g1", data)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = encodeGame(newer)
+	if err == nil {
+		data, err = json.Marshal(map[string]json.RawMessage{"g1": data})
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restoreSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreGames(store); err != nil {
+		t.Fatal(err)
+	}
+	gamesMutex.Lock()
+	restored := games["g1"]
+	gamesMutex.Unlock()
+	restored.Lock()
+	moves := len(restored.Game.Moves())
+	for _, p := range restored.Players {
+		p.ForfeitTimer.Stop()
+	}
+	restored.Unlock()
+	if moves != 3 {
+		t.Errorf("restored %d moves, want the snapshot's 3", moves)
+	}
+}
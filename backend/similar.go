@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// similarPositionsShown is the most positions get_similar_positions
+// returns.
+const similarPositionsShown = 5
+
+// SimilarPosition is an archived position like the one asked about, with
+// the results of the games it arose in by game ID.
+type SimilarPosition struct {
+	FEN      string            `json:"fen"`
+	Score    float64           `json:"score"`
+	Outcomes map[string]string `json:"outcomes"`
+}
+
+// materialKey counts each kind of piece but the kings in the placement
+// field of a position key, white's first.
+func materialKey(key string) [10]int {
+	var counts [10]int
+	placement, _, _ := strings.Cut(key, " ")
+	for _, c := range placement {
+		if i := strings.IndexRune("PNBRQpnbrq", c); i >= 0 {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// pawnIslands counts color's groups of pawns on adjacent files.
+func pawnIslands(board *chess.Board, color chess.Color) int {
+	islands := 0
+	files := analysis.PawnFiles(board, color)
+	for f, n := range files {
+		if n > 0 && (f == 0 || files[f-1] == 0) {
+			islands++
+		}
+	}
+	return islands
+}
+
+// similarityScore rates how alike two positions are from 0 to 1: 0.4 for
+// the same material, 0.2 for the same number of pawn islands on each side,
+// 0.2 for the share of pawns on the same squares and 0.2 for how close
+// each king stands to where it stands in the other.
+func similarityScore(a, b *chess.Position) float64 {
+	boardA, boardB := a.Board(), b.Board()
+	var score float64
+	if countMaterial(a, chess.White) == countMaterial(b, chess.White) && countMaterial(a, chess.Black) == countMaterial(b, chess.Black) {
+		score += 0.4
+	}
+
+	var shared, total int
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if pawnIslands(boardA, color) == pawnIslands(boardB, color) {
+			score += 0.1
+		}
+		pawns := make(map[chess.Square]bool)
+		for _, sq := range analysis.PawnSquares(boardA, color) {
+			pawns[sq] = true
+			total++
+		}
+		for _, sq := range analysis.PawnSquares(boardB, color) {
+			if pawns[sq] {
+				shared++
+			} else {
+				total++
+			}
+		}
+		distance := squareDistance(analysis.KingSquare(boardA, color), analysis.KingSquare(boardB, color))
+		score += 0.1 * float64(7-distance) / 7
+	}
+	if total == 0 {
+		score += 0.2
+	} else {
+		score += 0.2 * float64(shared) / float64(total)
+	}
+	return score
+}
+
+// similarPositions returns up to similarPositionsShown archived positions
+// with the same material as pos, most similar first. pos itself is left
+// out.
+func similarPositions(pos *chess.Position) []SimilarPosition {
+	archive.Lock()
+	defer archive.Unlock()
+	loadArchive()
+
+	own := positionKey(pos)
+	material := materialKey(own)
+	similar := []SimilarPosition{}
+	for key, ids := range archive.positions {
+		if key == own || materialKey(key) != material {
+			continue
+		}
+		opt, err := chess.FEN(key + " 0 1")
+		if err != nil {
+			continue
+		}
+		candidate := SimilarPosition{
+			FEN:      key + " 0 1",
+			Score:    similarityScore(pos, chess.NewGame(opt).Position()),
+			Outcomes: make(map[string]string, len(ids)),
+		}
+		for _, id := range ids {
+			candidate.Outcomes[id] = string(archive.games[id].Outcome)
+		}
+		similar = append(similar, candidate)
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].FEN < similar[j].FEN
+	})
+	return similar[:min(len(similar), similarPositionsShown)]
+}
+
+func getSimilarPositions(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, map[string]interface{}{"positions": similarPositions(pos)})
+	logger.Debug("Similar positions sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestSimilarityScore(t *testing.T) {
+	pos := func(fen string) *chess.Position { return gameFromFEN(t, fen).Position() }
+	kp := pos("4k3/8/8/3P4/8/8/8/4K3 w - - 0 1")
+	if got := similarityScore(kp, kp); got != 1 {
+		t.Errorf("a position scores %v against itself, want 1", got)
+	}
+	near := similarityScore(kp, pos("4k3/8/8/3P4/8/8/8/3K4 w - - 0 1"))
+	far := similarityScore(kp, pos("4k3/8/8/8/3P4/8/8/K7 w - - 0 1"))
+	other := similarityScore(kp, pos("4k3/8/8/3P4/8/8/8/4KR2 w - - 0 1"))
+	if !(1 > near && near > far && far > other) {
+		t.Errorf("scores %v for a king move, %v for a pawn and king move, %v for another rook, want falling", near, far, other)
+	}
+}
+
+func TestSimilarPositions(t *testing.T) {
+	useArchive(t, newMemoryStore())
+	for id, fen := range map[string]string{
+		"near":  "4k3/8/8/3P4/8/8/8/3K4 w - - 0 1",
+		"far":   "4k3/8/8/8/3P4/8/8/K7 w - - 0 1",
+		"rook":  "4k3/8/8/3P4/8/8/8/4KR2 w - - 0 1",
+		"same":  "4k3/8/8/3P4/8/8/8/4K3 w - - 7 30",
+		"again": "4k3/8/8/3P4/8/8/8/3K4 w - - 0 1",
+	} {
+		g := gameFromFEN(t, fen)
+		g.Resign(chess.Black)
+		archiveGame(id, &Game{Game: g})
+	}
+
+	got := similarPositions(gameFromFEN(t, "4k3/8/8/3P4/8/8/8/4K3 w - - 0 1").Position())
+	if len(got) != 2 {
+		t.Fatalf("got %d similar positions, want 2: %+v", len(got), got)
+	}
+	if got[0].FEN != "4k3/8/8/3P4/8/8/8/3K4 w - - 0 1" || !reflect.DeepEqual(got[0].Outcomes, map[string]string{"near": "1-0", "again": "1-0"}) {
+		t.Errorf("most similar position: %+v", got[0])
+	}
+	if got[1].FEN != "4k3/8/8/8/3P4/8/8/K7 w - - 0 1" || got[1].Score >= got[0].Score {
+		t.Errorf("second position: %+v after %v", got[1], got[0].Score)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+)
+
+func isSpectator(ws *websocket.Conn, game *Game) bool {
+	for _, spectator := range game.Spectators {
+		if spectator == ws {
+			return true
+		}
+	}
+	return false
+}
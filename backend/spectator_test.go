@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSpectatorJoinsOnce(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, _, gameID := startTestGame(t, srv)
+
+	spectator := dial(t, srv)
+	send(t, spectator, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, spectator, hasStatus("spectating"))
+
+	for name, ws := range map[string]*websocket.Conn{"spectator": spectator, "seated player": white} {
+		send(t, ws, map[string]string{"action": "join", "gameID": gameID})
+		msg := readUntil(t, ws, func(msg map[string]interface{}) bool {
+			return hasError(msg) || msg["status"] == "spectating"
+		})
+		if msg["error"] != "already in this game" {
+			t.Errorf("%s joining again: got %v", name, msg)
+		}
+	}
+
+	send(t, spectator, map[string]string{"action": "syncState", "gameID": gameID})
+	if state := readUntil(t, spectator, isState); state["spectators"] != float64(1) {
+		t.Errorf("spectators is %v, want 1", state["spectators"])
+	}
+}
+
+func TestYourColorOnlyForPlayers(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	spectator := dial(t, srv)
+	send(t, spectator, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, spectator, hasStatus("spectating"))
+
+	send(t, white, map[string]string{"action": "move", "gameID": gameID, "move": "e4"})
+	for ws, want := range map[*websocket.Conn]interface{}{white: "white", black: "black", spectator: nil} {
+		state := readUntil(t, ws, func(msg map[string]interface{}) bool {
+			return isState(msg) && msg["moveCount"] == float64(1)
+		})
+		if got, sent := state["yourColor"]; got != want || (want == nil && sent) {
+			t.Errorf("yourColor = %v, want %v", got, want)
+		}
+		if state["spectators"] != float64(1) || state["waitingForPlayer"] != false {
+			t.Errorf("spectators %v and waitingForPlayer %v, want 1 and false", state["spectators"], state["waitingForPlayer"])
+		}
+		whiteName, _ := state["whiteName"].(string)
+		blackName, _ := state["blackName"].(string)
+		if whiteName == "" || blackName == "" || whiteName == blackName {
+			t.Errorf("players are %q and %q", whiteName, blackName)
+		}
+		for _, key := range []string{"whitePlayer", "blackPlayer", "moveNumber", "spectatorCount"} {
+			if _, sent := state[key]; sent {
+				t.Errorf("state repeats another key as %s", key)
+			}
+		}
+	}
+}
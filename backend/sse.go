@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// sseReplaySize is how many of a game's latest events are kept for
+	// streams that reconnect with a Last-Event-ID.
+	sseReplaySize = 16
+	// sseBufferSize is how many events a slow stream may fall behind by
+	// before further events are dropped for it. It holds a full replay.
+	sseBufferSize = sseReplaySize
+)
+
+// sseEvent is a game state broadcast formatted for a Server-Sent Events
+// stream, with the state's seq as its event ID.
+type sseEvent struct {
+	seq  uint64
+	text string
+}
+
+// publishEvent sends state, numbered game.StateSeq, to every SSE stream
+// of game and keeps it for replay. A state that was already published,
+// broadcast again unchanged, is not sent twice. A stream that is too far
+// behind misses the event, which is harmless as every state carries the
+// whole move list. Callers hold game's lock.
+func publishEvent(game *Game, state map[string]interface{}) {
+	if game.sseEnded {
+		return
+	}
+	if n := len(game.sseEvents); n > 0 && game.sseEvents[n-1].seq == game.StateSeq {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		logger.Error("Error encoding game state event", slog.Any("error", err))
+		return
+	}
+	event := sseEvent{
+		seq:  game.StateSeq,
+		text: fmt.Sprintf("id: %d\ndata: %s\n\n", game.StateSeq, data),
+	}
+	game.sseEvents = append(game.sseEvents, event)
+	if len(game.sseEvents) > sseReplaySize {
+		game.sseEvents = game.sseEvents[len(game.sseEvents)-sseReplaySize:]
+	}
+	for _, events := range game.sseClients {
+		select {
+		case events <- event.text:
+		default:
+		}
+	}
+}
+
+// sseReplay returns the events a stream that last saw event seen has
+// missed: every kept event after it. A seen ahead of the latest event is
+// from before a restart, which numbers states afresh, so that stream gets
+// the latest event instead. Callers hold game's lock.
+func sseReplay(game *Game, seen uint64) []string {
+	n := len(game.sseEvents)
+	if n > 0 && seen > game.sseEvents[n-1].seq {
+		return []string{game.sseEvents[n-1].text}
+	}
+	var replay []string
+	for _, event := range game.sseEvents {
+		if event.seq > seen {
+			replay = append(replay, event.text)
+		}
+	}
+	return replay
+}
+
+// endStreams sends every SSE stream of game a final "end" event and ends
+// it, for a game that has been deleted. Callers hold game's lock.
+func endStreams(gameID string, game *Game) {
+	if game.sseEnded {
+		return
+	}
+	game.sseEnded = true
+	if game.sseDone != nil {
+		close(game.sseDone)
+	}
+	logger.Debug("SSE streams ended", slog.String("gameID", gameID), slog.Int("streams", len(game.sseClients)))
+}
+
+// sseEndEvent is the last event of a stream whose game was deleted.
+func sseEndEvent(gameID string) string {
+	data, _ := json.Marshal(map[string]string{"gameID": gameID, "status": "deleted"})
+	return fmt.Sprintf("event: end\ndata: %s\n\n", data)
+}
+
+// handleGameStream serves GET /games/{gameID}/stream, a read-only Server-
+// Sent Events stream of the game's state broadcasts. New streams start with
+// the latest state; reconnecting ones get the events since their
+// Last-Event-ID replayed. The stream ends when the game is deleted.
+func handleGameStream(w http.ResponseWriter, r *http.Request) {
+	gameID := r.PathValue("gameID")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	gamesMutex.RLock()
+	game, exists := games[gameID]
+	gamesMutex.RUnlock()
+	if !exists {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+
+	clientID := ksuid.New().String()
+	events := make(chan string, sseBufferSize)
+	game.Lock()
+	if game.sseEnded {
+		game.Unlock()
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	if game.sseClients == nil {
+		game.sseClients = make(map[string]chan string)
+	}
+	if game.sseDone == nil {
+		game.sseDone = make(chan struct{})
+	}
+	game.sseClients[clientID] = events
+	done := game.sseDone
+	if seen, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		for _, event := range sseReplay(game, seen) {
+			events <- event
+		}
+	} else if n := len(game.sseEvents); n > 0 {
+		events <- game.sseEvents[n-1].text
+	}
+	game.Unlock()
+	defer func() {
+		game.Lock()
+		delete(game.sseClients, clientID)
+		game.Unlock()
+	}()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	logger.Info("SSE client connected", slog.String("gameID", gameID))
+
+	write := func(event string) bool {
+		if _, err := fmt.Fprint(w, event); err != nil {
+			logger.Error("Error writing SSE event", slog.Any("error", err))
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			logger.Info("SSE client disconnected", slog.String("gameID", gameID))
+			return
+		case <-serverStopping:
+			return
+		case <-done:
+			// Send what was published before the game was deleted, then
+			// say it has gone.
+			for {
+				select {
+				case event := <-events:
+					if !write(event) {
+						return
+					}
+					continue
+				default:
+				}
+				break
+			}
+			write(sseEndEvent(gameID))
+			return
+		case event := <-events:
+			if !write(event) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// openStream opens the SSE stream of gameID, resuming after lastEventID if
+// it is not empty.
+func openStream(t *testing.T, url, gameID, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url+"/games/"+gameID+"/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream status %d, want 200", resp.StatusCode)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the next event from stream, returning its lines.
+func readEvent(t *testing.T, stream *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(lines) > 0 {
+				return lines
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+}
+
+func TestStreamReplaysMissedEvents(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	seq := uint64(play(t, white, black, gameID, "e4", "e5", "Nf3")["seq"].(float64))
+	id := func(seq uint64) string { return "id: " + strconv.FormatUint(seq, 10) }
+
+	stream := openStream(t, srv.URL, gameID, strconv.FormatUint(seq-2, 10))
+	for _, want := range []string{id(seq - 1), id(seq)} {
+		if event := readEvent(t, stream); event[0] != want {
+			t.Errorf("replayed event %q, want %q", event[0], want)
+		}
+	}
+
+	latest := openStream(t, srv.URL, gameID, "")
+	if event := readEvent(t, latest); event[0] != id(seq) {
+		t.Errorf("new stream started with %q, want %s", event[0], id(seq))
+	}
+
+	// A stream from before a restart, ahead of the game's seq, starts
+	// again from the latest event.
+	ahead := openStream(t, srv.URL, gameID, strconv.FormatUint(seq+10, 10))
+	if event := readEvent(t, ahead); event[0] != id(seq) {
+		t.Errorf("stream ahead of the game started with %q, want %s", event[0], id(seq))
+	}
+}
+
+// TestStreamSkipsUnchangedStates broadcasts a state again without it
+// changing, which must not publish a second event with the same ID.
+func TestStreamSkipsUnchangedStates(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4")
+
+	gamesMutex.RLock()
+	game := games[gameID]
+	gamesMutex.RUnlock()
+	game.Lock()
+	events := len(game.sseEvents)
+	game.Unlock()
+	broadcastGameState(gameID)
+	game.Lock()
+	defer game.Unlock()
+	if len(game.sseEvents) != events {
+		t.Errorf("an unchanged state added an event: %d events, want %d", len(game.sseEvents), events)
+	}
+	if n := len(game.sseEvents); n > 1 && game.sseEvents[n-1].seq <= game.sseEvents[n-2].seq {
+		t.Errorf("event IDs %d then %d do not go up", game.sseEvents[n-2].seq, game.sseEvents[n-1].seq)
+	}
+}
+
+func TestStreamEndsWhenGameDeleted(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	stream := openStream(t, srv.URL, gameID, "")
+	readEvent(t, stream)
+
+	for _, ws := range []*websocket.Conn{white, black} {
+		send(t, ws, map[string]string{"action": "abort", "gameID": gameID})
+	}
+	event := readEvent(t, stream)
+	if event[0] != "event: end" {
+		t.Fatalf("final event %q, want event: end", event)
+	}
+	if !strings.Contains(event[1], `"status":"deleted"`) {
+		t.Errorf("end event data %q, want deleted status", event[1])
+	}
+
+	resp, err := http.Get(srv.URL + "/games/" + gameID + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("stream of deleted game status %d, want 404", resp.StatusCode)
+	}
+}
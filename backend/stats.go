@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// openingMovesShown is how many half-moves a finished game's openingMoves
+// lists.
+const openingMovesShown = 10
+
+// completedGames totals the games that have finished since the server
+// started, for GET /stats. They outlive the games themselves, which are
+// deleted once their players leave.
+var completedGames struct {
+	sync.Mutex
+	count      int
+	duration   time.Duration
+	checkmates int
+	draws      int
+}
+
+// gameLength is how long game was played for, from when it started (or
+// was created, if it never started) to when it ended, or until now if it
+// has not.
+func gameLength(game *Game) time.Duration {
+	start := game.StartedAt
+	if start.IsZero() {
+		start = game.CreatedAt
+	}
+	end := game.EndedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(start)
+}
+
+// recordCompletedGame adds a game that has just ended with status to the
+// totals. The caller must hold game's lock.
+func recordCompletedGame(game *Game, status string) {
+	completedGames.Lock()
+	defer completedGames.Unlock()
+	completedGames.count++
+	completedGames.duration += gameLength(game)
+	if status == "checkmate" {
+		completedGames.checkmates++
+	}
+	if game.Game.Outcome() == chess.Draw && status != "stalemate_win" {
+		completedGames.draws++
+	}
+}
+
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	completedGames.Lock()
+	stats := map[string]interface{}{
+		"gamesCompleted":     completedGames.count,
+		"avgDurationSeconds": 0.0,
+		"checkmatePct":       0.0,
+		"drawPct":            0.0,
+	}
+	if n := completedGames.count; n > 0 {
+		stats["avgDurationSeconds"] = (completedGames.duration / time.Duration(n)).Seconds()
+		stats["checkmatePct"] = 100 * float64(completedGames.checkmates) / float64(n)
+		stats["drawPct"] = 100 * float64(completedGames.draws) / float64(n)
+	}
+	completedGames.Unlock()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logger.Error("Error sending stats", slog.Any("error", err))
+	}
+}
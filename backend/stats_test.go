@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestScholarsMateDuration finishes a game with Scholar's Mate and checks
+// that its length is measured from when play started, not from when the
+// game was created.
+func TestScholarsMateDuration(t *testing.T) {
+	payloads := make(chan gameEndPayload, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload gameEndPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding webhook payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer hook.Close()
+	useConfig(t, func(cfg *Config) { cfg.GameEndWebhookURL = hook.URL })
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	gamesMutex.Lock()
+	game := games[gameID]
+	game.Lock()
+	game.CreatedAt = time.Now().Add(-time.Hour)
+	game.StartedAt = time.Now().Add(-time.Minute)
+	game.Unlock()
+	gamesMutex.Unlock()
+
+	state := play(t, white, black, gameID, "e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#")
+	if state["status"] != "checkmate" {
+		t.Fatalf("status after Qxf7# = %v, want checkmate", state["status"])
+	}
+	if state["totalMoves"] != float64(7) {
+		t.Errorf("totalMoves = %v, want 7", state["totalMoves"])
+	}
+	if d, _ := state["durationSeconds"].(float64); d < 60 || d > 120 {
+		t.Errorf("durationSeconds = %v, want about a minute", state["durationSeconds"])
+	}
+	if want := "e4 e5 Bc4 Nc6 Qh5 Nf6 Qxf7#"; state["openingMoves"] != want {
+		t.Errorf("openingMoves = %v, want %q", state["openingMoves"], want)
+	}
+
+	select {
+	case payload := <-payloads:
+		if payload.DurationSeconds < 60 || payload.DurationSeconds > 120 {
+			t.Errorf("webhook durationSeconds = %d, want about a minute", payload.DurationSeconds)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the game end webhook was not called")
+	}
+
+	resp, err := http.Get(srv.URL + "/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding stats: %v", err)
+	}
+	if stats["gamesCompleted"] < 1 || stats["checkmatePct"] <= 0 || stats["avgDurationSeconds"] <= 0 {
+		t.Errorf("stats after a checkmate = %v", stats)
+	}
+}
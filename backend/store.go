@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// storeFlushInterval is how often the games map is written to the store.
+const storeFlushInterval = 30 * time.Second
+
+var errGameNotStored = errors.New("game not stored")
+
+// Store persists games and ratings so they survive a server restart.
+// SaveGame takes a game as encoded by encodeGame, so that no lock is held
+// while it is written, and LoadGameData gives it back as stored.
+// ArchiveGame keeps a finished game, encoded the same way, apart from the
+// games in play, and LoadArchive returns every archived game by ID.
+// SchemaVersion is the gameRecord version the stored games were last
+// migrated to; see migrateStore.
+type Store interface {
+	SaveGame(id string, data []byte) error
+	LoadGame(id string) (*Game, error)
+	LoadGameData(id string) ([]byte, error)
+	DeleteGame(id string) error
+	ListGames() ([]string, error)
+	ArchiveGame(id string, data []byte) error
+	LoadArchive() (map[string][]byte, error)
+	SaveRatings(ratings map[string]int) error
+	LoadRatings() (map[string]int, error)
+	SchemaVersion() (int, error)
+	SetSchemaVersion(version int) error
+}
+
+// newStore opens the store named by cfg.StoreBackend: "memory" or
+// "redis", which connects to cfg.RedisAddr.
+func newStore(cfg *Config) (Store, error) {
+	switch cfg.StoreBackend {
+	case "memory":
+		return newMemoryStore(), nil
+	case "redis":
+		return newRedisStore(cfg.RedisAddr), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", cfg.StoreBackend)
+	}
+}
+
+type playerRecord struct {
+	PlayerID     string      `json:"playerID"`
+	Subject      string      `json:"subject,omitempty"`
+	Name         string      `json:"name"`
+	Color        chess.Color `json:"color"`
+	ConfirmMoves bool        `json:"confirmMoves,omitempty"`
+	Rating       int         `json:"rating,omitempty"`
+	RatingBefore int         `json:"ratingBefore,omitempty"`
+	GuestID      string      `json:"guestID,omitempty"`
+	GuestExpires time.Time   `json:"guestExpires"`
+}
+
+type clockRecord struct {
+	Remaining [2]time.Duration   `json:"remaining"`
+	ClockMode string             `json:"clockMode"`
+	Increment time.Duration      `json:"increment"`
+	History   [][2]time.Duration `json:"history,omitempty"`
+}
+
+// gameRecord is the stored form of a Game. Moves are in UCI notation from
+// StartingFEN; Outcome and Method keep results that cannot be replayed,
+// such as resignations. SchemaVersion is currentSchemaVersion when it is
+// written; records from before it was added are version 1.
+type gameRecord struct {
+	SchemaVersion      int             `json:"schemaVersion"`
+	StartingFEN        string          `json:"startingFen"`
+	Variant            string          `json:"variant,omitempty"`
+	Moves              []string        `json:"moves"`
+	Outcome            string          `json:"outcome"`
+	Method             string          `json:"method"`
+	Players            []playerRecord  `json:"players"`
+	Clock              *clockRecord    `json:"clock,omitempty"`
+	TimeControl        string          `json:"timeControl,omitempty"`
+	CreatedAt          time.Time       `json:"createdAt"`
+	StartedAt          time.Time       `json:"startedAt"`
+	EndedAt            time.Time       `json:"endedAt"`
+	ChatHistory        []ChatMessage   `json:"chatHistory,omitempty"`
+	Adjudicated        bool            `json:"adjudicated,omitempty"`
+	AdjudicationReason string          `json:"adjudicationReason,omitempty"`
+	TimedOut           bool            `json:"timedOut,omitempty"`
+	Abandoned          bool            `json:"abandoned,omitempty"`
+	PracticeMode       string          `json:"practiceMode,omitempty"`
+	ApplyMoveDelay     bool            `json:"applyMoveDelay,omitempty"`
+	StalemateMeansWin  bool            `json:"stalemateMeansWin,omitempty"`
+	Ranked             bool            `json:"ranked,omitempty"`
+	MoveTimes          []time.Duration `json:"moveTimes,omitempty"`
+}
+
+// encodeGame serialises g, charging the side to move for its time so far.
+// Callers hold g's lock.
+func encodeGame(g *Game) ([]byte, error) {
+	record := gameRecord{
+		SchemaVersion:      currentSchemaVersion,
+		StartingFEN:        g.StartingFEN,
+		Variant:            g.Variant,
+		Outcome:            string(g.Game.Outcome()),
+		Method:             g.Game.Method().String(),
+		TimeControl:        g.TimeControl,
+		CreatedAt:          g.CreatedAt,
+		StartedAt:          g.StartedAt,
+		EndedAt:            g.EndedAt,
+		ChatHistory:        g.ChatHistory,
+		Adjudicated:        g.Adjudicated,
+		AdjudicationReason: g.AdjudicationReason,
+		TimedOut:           g.TimedOut,
+		Abandoned:          g.Abandoned,
+		PracticeMode:       g.PracticeMode,
+		ApplyMoveDelay:     g.ApplyMoveDelay,
+		StalemateMeansWin:  g.StalemateMeansWin,
+		Ranked:             g.Ranked,
+		MoveTimes:          g.MoveTimes,
+	}
+	positions := g.Game.Positions()
+	for i, m := range g.Game.Moves() {
+		record.Moves = append(record.Moves, chess.UCINotation{}.Encode(positions[i], m))
+	}
+	for _, p := range g.Players {
+		record.Players = append(record.Players, playerRecord{
+			PlayerID:     p.PlayerID,
+			Subject:      p.Subject,
+			Name:         p.Name,
+			Color:        p.Color,
+			ConfirmMoves: p.ConfirmMoves,
+			Rating:       p.Rating,
+			RatingBefore: p.RatingBefore,
+			GuestID:      p.GuestID,
+			GuestExpires: p.GuestExpires,
+		})
+	}
+	if c := g.Clock; c != nil {
+		turn, now := g.Game.Position().Turn(), time.Now()
+		record.Clock = &clockRecord{
+			Remaining: [2]time.Duration{c.remaining(chess.White, turn, now), c.remaining(chess.Black, turn, now)},
+			ClockMode: c.ClockMode,
+			Increment: c.Increment,
+			History:   c.History,
+		}
+	}
+	return json.Marshal(record)
+}
+
+// decodeGame rebuilds a game from encodeGame's output by replaying its
+// moves. Its players come back disconnected.
+func decodeGame(data []byte) (*Game, error) {
+	var record gameRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	if record.SchemaVersion > currentSchemaVersion {
+		return nil, fmt.Errorf("game stored with schema version %d, newer than %d", record.SchemaVersion, currentSchemaVersion)
+	}
+	opt, err := chess.FEN(record.StartingFEN)
+	if err != nil {
+		return nil, err
+	}
+	chessGame := chess.NewGame(opt)
+	for _, s := range record.Moves {
+		m, err := chess.UCINotation{}.Decode(chessGame.Position(), s)
+		if err == nil {
+			err = chessGame.Move(m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replaying move %s: %w", s, err)
+		}
+	}
+	if chessGame.Outcome() == chess.NoOutcome {
+		switch record.Method {
+		case chess.Resignation.String():
+			loser := chess.White
+			if chess.Outcome(record.Outcome) == chess.WhiteWon {
+				loser = chess.Black
+			}
+			chessGame.Resign(loser)
+		case chess.DrawOffer.String():
+			if err := chessGame.Draw(chess.DrawOffer); err != nil {
+				return nil, err
+			}
+		case chess.FiftyMoveRule.String():
+			if err := chessGame.Draw(chess.FiftyMoveRule); err != nil {
+				return nil, err
+			}
+		case chess.ThreefoldRepetition.String():
+			if err := chessGame.Draw(chess.ThreefoldRepetition); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	game := &Game{
+		Game:               chessGame,
+		StartingFEN:        record.StartingFEN,
+		Variant:            record.Variant,
+		CreatedAt:          record.CreatedAt,
+		StartedAt:          record.StartedAt,
+		EndedAt:            record.EndedAt,
+		ChatHistory:        record.ChatHistory,
+		TimeControl:        record.TimeControl,
+		Adjudicated:        record.Adjudicated,
+		AdjudicationReason: record.AdjudicationReason,
+		TimedOut:           record.TimedOut,
+		Abandoned:          record.Abandoned,
+		PracticeMode:       record.PracticeMode,
+		ApplyMoveDelay:     record.ApplyMoveDelay,
+		StalemateMeansWin:  record.StalemateMeansWin,
+		Ranked:             record.Ranked,
+		MoveTimes:          record.MoveTimes,
+	}
+	for _, p := range record.Players {
+		game.Players = append(game.Players, &Player{
+			PlayerID:     p.PlayerID,
+			Subject:      p.Subject,
+			Name:         p.Name,
+			Color:        p.Color,
+			ConfirmMoves: p.ConfirmMoves,
+			Rating:       p.Rating,
+			RatingBefore: p.RatingBefore,
+			GuestID:      p.GuestID,
+			GuestExpires: p.GuestExpires,
+		})
+	}
+	if c := record.Clock; c != nil {
+		game.Clock = &GameClock{
+			Remaining: c.Remaining,
+			ClockMode: c.ClockMode,
+			Increment: c.Increment,
+			History:   c.History,
+		}
+	}
+	return game, nil
+}
+
+// MemoryStore keeps encoded games and ratings in maps. It does not
+// outlive the process, so restarts still lose them.
+type MemoryStore struct {
+	mu            sync.Mutex
+	games         map[string][]byte
+	archived      map[string][]byte
+	ratings       map[string]int
+	schemaVersion int
+}
+
+func newMemoryStore() *MemoryStore {
+	return &MemoryStore{games: make(map[string][]byte), archived: make(map[string][]byte), ratings: make(map[string]int)}
+}
+
+func (s *MemoryStore) SaveGame(id string, data []byte) error {
+	s.mu.Lock()
+	s.games[id] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) LoadGame(id string) (*Game, error) {
+	data, err := s.LoadGameData(id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeGame(data)
+}
+
+func (s *MemoryStore) LoadGameData(id string) ([]byte, error) {
+	s.mu.Lock()
+	data, ok := s.games[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errGameNotStored
+	}
+	return data, nil
+}
+
+func (s *MemoryStore) DeleteGame(id string) error {
+	s.mu.Lock()
+	delete(s.games, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) ListGames() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.games))
+	for id := range s.games {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *MemoryStore) ArchiveGame(id string, data []byte) error {
+	s.mu.Lock()
+	s.archived[id] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) LoadArchive() (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archived := make(map[string][]byte, len(s.archived))
+	for id, data := range s.archived {
+		archived[id] = data
+	}
+	return archived, nil
+}
+
+func (s *MemoryStore) SaveRatings(ratings map[string]int) error {
+	s.mu.Lock()
+	for playerID, rating := range ratings {
+		s.ratings[playerID] = rating
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) LoadRatings() (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ratings := make(map[string]int, len(s.ratings))
+	for playerID, rating := range s.ratings {
+		ratings[playerID] = rating
+	}
+	return ratings, nil
+}
+
+func (s *MemoryStore) SchemaVersion() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.schemaVersion, nil
+}
+
+func (s *MemoryStore) SetSchemaVersion(version int) error {
+	s.mu.Lock()
+	s.schemaVersion = version
+	s.mu.Unlock()
+	return nil
+}
+
+// restoreGames loads the games in progress from store, holding both seats
+// for their players to reconnect as if they had just dropped out. Games
+// already in the games map are left as they are; games that could not be
+// resumed are removed from the store.
+func restoreGames(store Store) error {
+	ids, err := store.ListGames()
+	if err != nil {
+		return err
+	}
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	for _, id := range ids {
+		if _, exists := games[id]; exists {
+			continue
+		}
+		game, err := store.LoadGame(id)
+		if err != nil {
+			logger.Error("Error loading game", slog.String("gameID", id), slog.Any("error", err))
+			continue
+		}
+		if len(game.Players) != 2 || game.Game.Outcome() != chess.NoOutcome {
+			if err := store.DeleteGame(id); err != nil {
+				logger.Error("Error deleting stored game", slog.String("gameID", id), slog.Any("error", err))
+			}
+			continue
+		}
+		for _, player := range game.Players {
+			gameID, player := id, player
+			player.ForfeitTimer = time.AfterFunc(config.ReconnectGrace, func() {
+				forfeitAbsentPlayer(gameID, player)
+			})
+		}
+		games[id] = game
+		watchGuestGame(id, game)
+		startClock(id, game)
+		logger.Info("Game restored", slog.String("gameID", id))
+	}
+	return nil
+}
+
+// saveGames writes every game to store and drops stored games that have
+// since been deleted.
+func saveGames(store Store) {
+	gamesMutex.RLock()
+	snapshot := make(map[string]*Game, len(games))
+	for id, game := range games {
+		snapshot[id] = game
+	}
+	gamesMutex.RUnlock()
+
+	for id, game := range snapshot {
+		// Most changes to a game are made under gamesMutex alone, so the
+		// read lock is held while it is encoded, one game at a time. The
+		// store is only written once the locks are released, so a slow
+		// store cannot hold up play.
+		gamesMutex.RLock()
+		var data []byte
+		var err error
+		if games[id] == game {
+			game.Lock()
+			data, err = encodeGame(game)
+			game.Unlock()
+		}
+		gamesMutex.RUnlock()
+		if err == nil && data != nil {
+			err = store.SaveGame(id, data)
+		}
+		if err != nil {
+			logger.Error("Error saving game", slog.String("gameID", id), slog.Any("error", err))
+		}
+	}
+
+	stored, err := store.ListGames()
+	if err != nil {
+		logger.Error("Error listing stored games", slog.Any("error", err))
+		return
+	}
+	for _, id := range stored {
+		if _, live := snapshot[id]; live {
+			continue
+		}
+		if err := store.DeleteGame(id); err != nil {
+			logger.Error("Error deleting stored game", slog.String("gameID", id), slog.Any("error", err))
+		}
+	}
+}
+
+// flushGames saves the games map and changed ratings to store every
+// storeFlushInterval.
+func flushGames(store Store) {
+	ticker := time.NewTicker(storeFlushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		saveGames(store)
+		saveRatings(store)
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// TestSaveGamesWhilePlaying saves games while they change under gamesMutex
+// alone, which the race detector checks.
+func TestSaveGamesWhilePlaying(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	store := newMemoryStore()
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				saveGames(store)
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		send(t, white, map[string]string{"action": "chat", "gameID": gameID, "message": "hello"})
+	}
+	play(t, white, black, gameID, "e4", "e5", "Nf3")
+	send(t, black, map[string]string{"action": "resign", "gameID": gameID})
+	readUntil(t, white, hasStatus("resigned"))
+	close(stop)
+	<-done
+
+	saveGames(store)
+	game, err := store.LoadGame(gameID)
+	if err != nil {
+		t.Fatalf("loading saved game: %v", err)
+	}
+	if got := game.Game.Outcome(); got != chess.WhiteWon {
+		t.Errorf("saved outcome = %s, want %s", got, chess.WhiteWon)
+	}
+	if got := len(game.Game.Moves()); got != 3 {
+		t.Errorf("saved %d moves, want 3", got)
+	}
+}
+
+// blockingStore is a MemoryStore whose SaveGame waits for release, like a
+// store that has stopped answering.
+type blockingStore struct {
+	*MemoryStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) SaveGame(id string, data []byte) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.MemoryStore.SaveGame(id, data)
+}
+
+// TestSlowStoreDoesNotBlockPlay checks that no lock is held while a game is
+// written to the store.
+func TestSlowStoreDoesNotBlockPlay(t *testing.T) {
+	useConfig(t, nil)
+	useGames(t)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	store := &blockingStore{newMemoryStore(), make(chan struct{}), make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		saveGames(store)
+		close(done)
+	}()
+	<-store.saving
+	play(t, white, black, gameID, "e4")
+	close(store.release)
+	<-done
+
+	if game, err := store.LoadGame(gameID); err != nil || len(game.Game.Moves()) != 0 {
+		t.Errorf("saved game = %v, %v, want the position before e4", game, err)
+	}
+}
+
+// fakeRedis serves the handful of Redis commands RedisStore uses, from
+// memory. It stands in for a real server, which the tests cannot rely on.
+// Other commands, such as the HELLO go-redis opens with, get an error
+// reply, on which go-redis falls back to RESP2.
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	sets    map[string]map[string]bool
+	hashes  map[string]map[string]string
+}
+
+// newFakeRedis starts a fakeRedis and returns its address.
+func newFakeRedis(t testing.TB) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	r := &fakeRedis{
+		strings: make(map[string]string),
+		sets:    make(map[string]map[string]bool),
+		hashes:  make(map[string]map[string]string),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil || len(args) == 0 {
+			return
+		}
+		if _, err := fmt.Fprint(conn, r.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads one command, an array of bulk strings, from reader.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	readSize := func(prefix string) (int, error) {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		if !strings.HasPrefix(line, prefix) {
+			return 0, fmt.Errorf("unexpected %q", line)
+		}
+		return strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	}
+	n, err := readSize("*")
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readSize("$")
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// exec runs one command and returns its encoded reply.
+func (r *fakeRedis) exec(args []string) string {
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+	array := func(items []string) string {
+		reply := fmt.Sprintf("*%d\r\n", len(items))
+		for _, item := range items {
+			reply += bulk(item)
+		}
+		return reply
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch cmd, rest := strings.ToUpper(args[0]), args[1:]; {
+	case cmd == "SET" && len(rest) == 2:
+		r.strings[rest[0]] = rest[1]
+		return "+OK\r\n"
+	case cmd == "GET" && len(rest) == 1:
+		value, ok := r.strings[rest[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case cmd == "MGET" && len(rest) > 0:
+		reply := fmt.Sprintf("*%d\r\n", len(rest))
+		for _, key := range rest {
+			if value, ok := r.strings[key]; ok {
+				reply += bulk(value)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case cmd == "DEL" && len(rest) == 1:
+		delete(r.strings, rest[0])
+		return ":1\r\n"
+	case cmd == "SADD" && len(rest) == 2:
+		if r.sets[rest[0]] == nil {
+			r.sets[rest[0]] = make(map[string]bool)
+		}
+		r.sets[rest[0]][rest[1]] = true
+		return ":1\r\n"
+	case cmd == "SREM" && len(rest) == 2:
+		delete(r.sets[rest[0]], rest[1])
+		return ":1\r\n"
+	case cmd == "SMEMBERS" && len(rest) == 1:
+		var members []string
+		for member := range r.sets[rest[0]] {
+			members = append(members, member)
+		}
+		return array(members)
+	case cmd == "HSET" && len(rest) >= 3 && len(rest)%2 == 1:
+		if r.hashes[rest[0]] == nil {
+			r.hashes[rest[0]] = make(map[string]string)
+		}
+		for i := 1; i < len(rest); i += 2 {
+			r.hashes[rest[0]][rest[i]] = rest[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", len(rest)/2)
+	case cmd == "HGETALL" && len(rest) == 1:
+		var fields []string
+		for field, value := range r.hashes[rest[0]] {
+			fields = append(fields, field, value)
+		}
+		return array(fields)
+	}
+	return "-ERR unknown command or wrong number of arguments\r\n"
+}
+
+// storedTestGame is a game two moves in, with a clock and both players.
+func storedTestGame(t testing.TB) *Game {
+	t.Helper()
+	g := chess.NewGame()
+	for _, move := range []string{"e4", "c5"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Game{
+		Game:        g,
+		StartingFEN: chess.StartingPosition().String(),
+		CreatedAt:   time.Now().Add(-time.Minute).UTC().Truncate(time.Second),
+		Players: []*Player{
+			{PlayerID: "white-seat", Name: "Alice", Color: chess.White, Rating: 1500},
+			{PlayerID: "black-seat", Name: "Bob", Color: chess.Black, Rating: 1450},
+		},
+		Clock: &GameClock{
+			Remaining: [2]time.Duration{50 * time.Second, 55 * time.Second},
+			ClockMode: "blitz",
+			History:   [][2]time.Duration{{time.Minute, time.Minute}, {50 * time.Second, time.Minute}},
+		},
+		MoveTimes: []time.Duration{10 * time.Second, 5 * time.Second},
+	}
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": newMemoryStore(),
+		"redis":  newRedisStore(newFakeRedis(t)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.LoadGame("missing"); !errors.Is(err, errGameNotStored) {
+				t.Errorf("loading a missing game: %v, want %v", err, errGameNotStored)
+			}
+
+			saved := storedTestGame(t)
+			data, err := encodeGame(saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SaveGame("g1", data); err != nil {
+				t.Fatalf("saving: %v", err)
+			}
+			if ids, err := store.ListGames(); err != nil || !reflect.DeepEqual(ids, []string{"g1"}) {
+				t.Errorf("ListGames = %v, %v, want [g1]", ids, err)
+			}
+
+			loaded, err := store.LoadGame("g1")
+			if err != nil {
+				t.Fatalf("loading: %v", err)
+			}
+			if got, want := loaded.Game.Position().String(), saved.Game.Position().String(); got != want {
+				t.Errorf("position = %s, want %s", got, want)
+			}
+			for i, p := range loaded.Players {
+				want := saved.Players[i]
+				if p.PlayerID != want.PlayerID || p.Name != want.Name || p.Color != want.Color || p.Rating != want.Rating || p.Conn != nil {
+					t.Errorf("player %d = %+v, want %+v disconnected", i, p, want)
+				}
+			}
+			if loaded.Clock == nil || loaded.Clock.ClockMode != "blitz" || !reflect.DeepEqual(loaded.Clock.History, saved.Clock.History) {
+				t.Errorf("clock = %+v, want %+v", loaded.Clock, saved.Clock)
+			}
+			if !loaded.CreatedAt.Equal(saved.CreatedAt) || !reflect.DeepEqual(loaded.MoveTimes, saved.MoveTimes) {
+				t.Errorf("createdAt %v and move times %v, want %v and %v", loaded.CreatedAt, loaded.MoveTimes, saved.CreatedAt, saved.MoveTimes)
+			}
+
+			if err := store.DeleteGame("g1"); err != nil {
+				t.Fatalf("deleting: %v", err)
+			}
+			if ids, err := store.ListGames(); err != nil || len(ids) != 0 {
+				t.Errorf("ListGames after delete = %v, %v", ids, err)
+			}
+			if _, err := store.LoadGame("g1"); !errors.Is(err, errGameNotStored) {
+				t.Errorf("loading a deleted game: %v", err)
+			}
+
+			if archived, err := store.LoadArchive(); err != nil || len(archived) != 0 {
+				t.Errorf("LoadArchive before archiving = %v, %v", archived, err)
+			}
+			if err := store.ArchiveGame("g1", data); err != nil {
+				t.Fatalf("archiving: %v", err)
+			}
+			if archived, err := store.LoadArchive(); err != nil || !reflect.DeepEqual(archived, map[string][]byte{"g1": data}) {
+				t.Errorf("LoadArchive = %v, %v, want g1", archived, err)
+			}
+
+			if err := store.SaveRatings(map[string]int{"alice": 1510, "bob": 1490}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SaveRatings(map[string]int{"bob": 1480}); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := store.LoadRatings(); err != nil || !reflect.DeepEqual(got, map[string]int{"alice": 1510, "bob": 1480}) {
+				t.Errorf("LoadRatings = %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestDecodeResignedGame(t *testing.T) {
+	game := storedTestGame(t)
+	game.Game.Resign(chess.Black)
+	data, err := encodeGame(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeGame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Game.Outcome() != chess.WhiteWon || decoded.Game.Method() != chess.Resignation {
+		t.Errorf("decoded result = %s by %s, want 1-0 by resignation", decoded.Game.Outcome(), decoded.Game.Method())
+	}
+}
+
+func TestRestoreGames(t *testing.T) {
+	useConfig(t, nil)
+	useGames(t)
+	store := newMemoryStore()
+
+	ongoing := storedTestGame(t)
+	ongoing.Clock = nil
+	finished := storedTestGame(t)
+	finished.Game.Resign(chess.White)
+	for id, game := range map[string]*Game{"ongoing": ongoing, "finished": finished} {
+		data, err := encodeGame(game)
+		if err == nil {
+			err = store.SaveGame(id, data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := restoreGames(store); err != nil {
+		t.Fatal(err)
+	}
+	gamesMutex.Lock()
+	restored, finishedLoaded := games["ongoing"], games["finished"]
+	gamesMutex.Unlock()
+	if finishedLoaded != nil {
+		t.Error("a finished game was restored")
+	}
+	if restored == nil {
+		t.Fatal("the game in progress was not restored")
+	}
+	for _, p := range restored.Players {
+		if p.ForfeitTimer == nil {
+			t.Errorf("%s's seat is not held for them", p.Name)
+		} else {
+			p.ForfeitTimer.Stop()
+		}
+	}
+	if ids, _ := store.ListGames(); !reflect.DeepEqual(ids, []string{"ongoing"}) {
+		t.Errorf("stored games after restoring = %v, want [ongoing]", ids)
+	}
+}
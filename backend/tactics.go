@@ -0,0 +1,141 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+type TacticalMotif struct {
+	Type    string   `json:"type"`
+	Piece   string   `json:"piece"`
+	Square  string   `json:"square"`
+	Targets []string `json:"targets"`
+}
+
+// detectTacticalMotifs looks for forks, pins, skewers, discovered attacks and
+// double checks for both sides using static attack maps.
+func detectTacticalMotifs(pos *chess.Position) []TacticalMotif {
+	board := pos.Board()
+	squareMap := board.SquareMap()
+	defended := map[chess.Color][64]int{
+		chess.White: analysis.AttackCounts(board, chess.White),
+		chess.Black: analysis.AttackCounts(board, chess.Black),
+	}
+
+	// Iterate squares in order so the motifs come back in a stable order.
+	squares := make([]chess.Square, 0, len(squareMap))
+	for sq := range squareMap {
+		squares = append(squares, sq)
+	}
+	sort.Slice(squares, func(i, j int) bool { return squares[i] < squares[j] })
+
+	var motifs []TacticalMotif
+	for _, sq := range squares {
+		piece := squareMap[sq]
+		color := piece.Color()
+
+		// A fork attacks two or more pieces that are either worth more than
+		// the attacker or left undefended.
+		var targets []string
+		for _, target := range analysis.AttackedSquares(board, sq, piece) {
+			victim := board.Piece(target)
+			if victim == chess.NoPiece || victim.Color() == color {
+				continue
+			}
+			if analysis.PieceValue(victim.Type()) > analysis.PieceValue(piece.Type()) || defended[victim.Color()][target] == 0 {
+				targets = append(targets, target.String())
+			}
+		}
+		if len(targets) >= 2 {
+			motifs = append(motifs, TacticalMotif{Type: "fork", Piece: pieceNames[piece.Type()], Square: sq.String(), Targets: targets})
+		}
+
+		motifs = append(motifs, lineMotifs(board, sq, piece)...)
+	}
+
+	// Double check: the side to move is attacked by two pieces at once.
+	turn := pos.Turn()
+	king := analysis.KingSquare(board, turn)
+	var checkers []string
+	for _, sq := range squares {
+		piece := squareMap[sq]
+		if piece.Color() != turn.Other() {
+			continue
+		}
+		for _, target := range analysis.AttackedSquares(board, sq, piece) {
+			if target == king {
+				checkers = append(checkers, sq.String())
+			}
+		}
+	}
+	if len(checkers) >= 2 {
+		motifs = append(motifs, TacticalMotif{Type: "double_check", Piece: "king", Square: king.String(), Targets: checkers})
+	}
+	return motifs
+}
+
+// lineMotifs finds pins, skewers and discovered attacks along the lines of
+// the sliding piece on sq.
+func lineMotifs(board *chess.Board, sq chess.Square, piece chess.Piece) []TacticalMotif {
+	var directions [][2]int
+	switch piece.Type() {
+	case chess.Bishop:
+		directions = analysis.BishopDirections
+	case chess.Rook:
+		directions = analysis.RookDirections
+	case chess.Queen:
+		directions = append(append(directions, analysis.RookDirections...), analysis.BishopDirections...)
+	default:
+		return nil
+	}
+
+	var motifs []TacticalMotif
+	color := piece.Color()
+	for _, d := range directions {
+		var hits []chess.Square
+		for target, ok := analysis.OffsetSquare(sq, d[0], d[1]); ok && len(hits) < 2; target, ok = analysis.OffsetSquare(target, d[0], d[1]) {
+			if board.Piece(target) != chess.NoPiece {
+				hits = append(hits, target)
+			}
+		}
+		if len(hits) < 2 {
+			continue
+		}
+		front, back := board.Piece(hits[0]), board.Piece(hits[1])
+		if back.Color() == color {
+			continue
+		}
+		frontValue, backValue := analysis.PieceValue(front.Type()), analysis.PieceValue(back.Type())
+		targets := []string{hits[0].String(), hits[1].String()}
+
+		switch {
+		case front.Color() == color:
+			// Moving our own piece out of the way would uncover an attack.
+			if backValue >= analysis.PieceValues[chess.Rook] {
+				motifs = append(motifs, TacticalMotif{Type: "discovered_attack", Piece: pieceNames[front.Type()], Square: hits[0].String(), Targets: []string{hits[1].String()}})
+			}
+		case (back.Type() == chess.King || back.Type() == chess.Queen) && backValue > frontValue:
+			motifs = append(motifs, TacticalMotif{Type: "pin", Piece: pieceNames[piece.Type()], Square: sq.String(), Targets: targets})
+		case frontValue > backValue && frontValue > analysis.PieceValue(piece.Type()):
+			motifs = append(motifs, TacticalMotif{Type: "skewer", Piece: pieceNames[piece.Type()], Square: sq.String(), Targets: targets})
+		}
+	}
+	return motifs
+}
+
+func detectTactics(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	motifs := detectTacticalMotifs(pos)
+	if motifs == nil {
+		motifs = []TacticalMotif{}
+	}
+	sendJSON(ws, map[string]interface{}{"motifs": motifs})
+	logger.Debug("Tactical motifs sent", slog.String("gameID", gameID))
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// takebackPlies is how many half-moves a takeback for color undoes: just
+// its own last move, or the opponent's reply too if color is to move.
+func takebackPlies(game *Game, color chess.Color) int {
+	if game.Game.Position().Turn() == color {
+		return 2
+	}
+	return 1
+}
+
+func requestTakeback(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	switch {
+	case player == nil:
+		sendJSON(ws, map[string]string{"error": "only players can request a takeback"})
+		return
+	case game.Game.Outcome() != chess.NoOutcome:
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	case game.TakebackRequest != chess.NoColor:
+		sendJSON(ws, map[string]string{"error": "a takeback request is already pending"})
+		return
+	case len(game.Game.Moves()) < takebackPlies(game, player.Color):
+		sendJSON(ws, map[string]string{"error": "no move to take back"})
+		return
+	}
+
+	game.TakebackRequest = player.Color
+	afterFunc := time.AfterFunc
+	if game.afterFunc != nil {
+		afterFunc = game.afterFunc
+	}
+	var timer *time.Timer
+	timer = afterFunc(config.TakebackTimeout, func() {
+		expireTakebackRequest(gameID, game, &timer)
+	})
+	game.TakebackTimer = timer
+	if other := opponent(game, player.Color); other != nil {
+		sendToPlayer(other, map[string]string{"status": "takebackRequest", "from": colorName(player.Color)})
+	}
+	logger.Info("Takeback requested", slog.String("gameID", gameID))
+}
+
+// clearTakebackRequest withdraws any pending takeback request and stops
+// its timer. Every path that ends a game calls it, as a finished game
+// cannot be taken back. The caller must hold gamesMutex.
+func clearTakebackRequest(game *Game) {
+	game.TakebackRequest = chess.NoColor
+	if game.TakebackTimer != nil {
+		game.TakebackTimer.Stop()
+		game.TakebackTimer = nil
+	}
+}
+
+// expireTakebackRequest declines a takeback request the opponent has not
+// answered within the configured timeout. timer is only read under gamesMutex,
+// which requestTakeback held while setting it.
+func expireTakebackRequest(gameID string, game *Game, timer **time.Timer) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	if games[gameID] != game || game.TakebackTimer != *timer {
+		return
+	}
+	requester := game.TakebackRequest
+	clearTakebackRequest(game)
+	for _, player := range game.Players {
+		if player.Color == requester {
+			sendToPlayer(player, map[string]string{"status": "takebackDeclined", "reason": "timeout"})
+		}
+	}
+	logger.Info("Takeback request expired", slog.String("gameID", gameID))
+}
+
+// undoMoves replays game without its last plies half-moves, as notnil/chess
+// cannot undo a move, and rearms the flag timer for the side now to move.
+// The caller must hold gamesMutex.
+func undoMoves(gameID string, game *Game, plies int) error {
+	moves := sanMoves(game.Game)
+	replay := chess.NewGame()
+	if opt, err := chess.FEN(game.StartingFEN); err == nil {
+		replay = chess.NewGame(opt)
+	}
+	for _, move := range moves[:len(moves)-plies] {
+		if err := replay.MoveStr(move); err != nil {
+			return err
+		}
+	}
+	game.Game = replay
+	if len(game.MoveTimes) >= plies {
+		game.MoveTimes = game.MoveTimes[:len(game.MoveTimes)-plies]
+	}
+	game.MoveStartedAt = time.Now()
+
+	game.Lock()
+	if clock := game.Clock; clock != nil && len(clock.History) >= plies {
+		clock.Remaining = clock.History[len(clock.History)-plies]
+		clock.History = clock.History[:len(clock.History)-plies]
+		clock.TurnStarted = time.Now()
+		if clock.running() {
+			armFlag(gameID, game)
+		}
+	}
+	game.Unlock()
+	return nil
+}
+
+func acceptTakeback(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	switch {
+	case player == nil || game.TakebackRequest != player.Color.Other():
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "no takeback request to accept"})
+		return
+	case game.Game.Outcome() != chess.NoOutcome:
+		clearTakebackRequest(game)
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		return
+	}
+	requester := game.TakebackRequest
+	clearTakebackRequest(game)
+	err := undoMoves(gameID, game, takebackPlies(game, requester))
+	gamesMutex.Unlock()
+	if err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		logger.Error("Error taking back move", slog.String("gameID", gameID), slog.Any("error", err))
+		return
+	}
+
+	logger.Info("Takeback accepted", slog.String("gameID", gameID))
+	broadcastGameState(gameID)
+}
+
+func declineTakeback(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	player := getPlayer(ws, game)
+	if player == nil || game.TakebackRequest != player.Color.Other() {
+		sendJSON(ws, map[string]string{"error": "no takeback request to decline"})
+		return
+	}
+	clearTakebackRequest(game)
+	if other := opponent(game, player.Color); other != nil {
+		sendToPlayer(other, map[string]string{"status": "takebackDeclined", "from": colorName(player.Color)})
+	}
+	logger.Info("Takeback declined", slog.String("gameID", gameID))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+func TestTakebackCannotReviveFinishedGame(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5")
+
+	send(t, white, map[string]string{"action": "requestTakeback", "gameID": gameID})
+	readUntil(t, black, hasStatus("takebackRequest"))
+	send(t, black, map[string]string{"action": "resign", "gameID": gameID})
+	readUntil(t, black, hasStatus("resigned"))
+	send(t, black, map[string]string{"action": "acceptTakeback", "gameID": gameID})
+	readUntil(t, black, hasError)
+
+	gamesMutex.RLock()
+	defer gamesMutex.RUnlock()
+	game := games[gameID]
+	if got := game.Game.Outcome(); got != chess.WhiteWon {
+		t.Errorf("outcome = %s, want %s", got, chess.WhiteWon)
+	}
+	if got := len(game.Game.Moves()); got != 2 {
+		t.Errorf("%d moves left, want 2", got)
+	}
+	if game.TakebackRequest != chess.NoColor {
+		t.Errorf("takeback request still pending after the game ended")
+	}
+}
+
+// fakeTimers stands in for time.AfterFunc in a game, holding each timer's
+// function until fire runs it.
+type fakeTimers struct {
+	sync.Mutex
+	pending []func()
+}
+
+// use makes game's timers fake. The timer handed back never fires by
+// itself, but can be stopped as usual.
+func (f *fakeTimers) use(gameID string) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	games[gameID].afterFunc = func(_ time.Duration, fn func()) *time.Timer {
+		f.Lock()
+		f.pending = append(f.pending, fn)
+		f.Unlock()
+		return time.NewTimer(math.MaxInt64)
+	}
+}
+
+// fire runs the functions of every timer started so far, as if their time
+// had come.
+func (f *fakeTimers) fire(t testing.TB) {
+	t.Helper()
+	f.Lock()
+	pending := f.pending
+	f.pending = nil
+	f.Unlock()
+	if len(pending) == 0 {
+		t.Fatal("no timer to fire")
+	}
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+func TestTakebackRequestTimesOut(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5")
+	var timers fakeTimers
+	timers.use(gameID)
+
+	send(t, white, map[string]string{"action": "requestTakeback", "gameID": gameID})
+	readUntil(t, black, hasStatus("takebackRequest"))
+	timers.fire(t)
+	if declined := readUntil(t, white, hasStatus("takebackDeclined")); declined["reason"] != "timeout" {
+		t.Errorf("takeback declined with reason %v, want timeout", declined["reason"])
+	}
+
+	send(t, black, map[string]string{"action": "acceptTakeback", "gameID": gameID})
+	if msg := readUntil(t, black, hasError); msg["error"] != "no takeback request to accept" {
+		t.Errorf("accepting an expired request: %v", msg["error"])
+	}
+	gamesMutex.RLock()
+	game := games[gameID]
+	pending, timer := game.TakebackRequest, game.TakebackTimer
+	gamesMutex.RUnlock()
+	if pending != chess.NoColor || timer != nil {
+		t.Errorf("expired request left pending by %s with timer %v", pending, timer)
+	}
+}
+
+func TestDeclinedTakebackDoesNotTimeOut(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "e4", "e5")
+	var timers fakeTimers
+	timers.use(gameID)
+
+	send(t, white, map[string]string{"action": "requestTakeback", "gameID": gameID})
+	readUntil(t, black, hasStatus("takebackRequest"))
+	send(t, black, map[string]string{"action": "declineTakeback", "gameID": gameID})
+	if declined := readUntil(t, white, hasStatus("takebackDeclined")); declined["from"] != "black" {
+		t.Errorf("takeback declined by %v, want black", declined["from"])
+	}
+
+	timers.fire(t)
+	send(t, white, map[string]string{"action": "syncState", "gameID": gameID})
+	readUntil(t, white, func(msg map[string]interface{}) bool {
+		if msg["status"] == "takebackDeclined" {
+			t.Errorf("declined request timed out as well: %v", msg)
+		}
+		return isState(msg)
+	})
+}
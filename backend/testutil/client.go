@@ -0,0 +1,234 @@
+// Package testutil drives the chess server over WebSocket from tests, with
+// a typed method for each common action in place of hand-built JSON.
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultTimeout is how long the typed methods wait for a reply.
+const DefaultTimeout = 2 * time.Second
+
+// TimeControl is a game's time control, such as "5+3", or Untimed.
+type TimeControl string
+
+// Untimed creates a game without a clock.
+const Untimed TimeControl = ""
+
+// GameCreatedResponse is the reply to a create action.
+type GameCreatedResponse struct {
+	Status   string `json:"status"`
+	GameID   string `json:"gameID"`
+	Color    string `json:"color"`
+	PlayerID string `json:"playerID"`
+}
+
+// JoinedResponse is the reply to a player taking the second seat.
+type JoinedResponse struct {
+	Status   string `json:"status"`
+	GameID   string `json:"gameID"`
+	Color    string `json:"color"`
+	PlayerID string `json:"playerID"`
+}
+
+// GameStateResponse is a game state broadcast. Raw holds every key sent,
+// for those without a field of their own.
+type GameStateResponse struct {
+	Status    string   `json:"status"`
+	FEN       string   `json:"fen"`
+	Moves     []string `json:"moves"`
+	MoveCount int      `json:"moveCount"`
+	LastMove  string   `json:"lastMove"`
+	Winner    string   `json:"winner"`
+	WhiteName string   `json:"whiteName"`
+	BlackName string   `json:"blackName"`
+	YourColor string   `json:"yourColor"`
+
+	Raw map[string]interface{} `json:"-"`
+}
+
+// TestClient is a WebSocket connection to the server under test. Its
+// methods fail the test rather than return errors.
+type TestClient struct {
+	Conn *websocket.Conn
+
+	// Name is sent as the display name when creating or joining a game.
+	Name string
+
+	t testing.TB
+	// color is "w" or "b" once the client has a seat, and moveCount the
+	// most moves seen in a state, so MakeMove can tell its own move's
+	// state from ones broadcast before it.
+	color     string
+	moveCount int
+}
+
+// Dial connects to the server at url, as served by httptest, closing the
+// connection when the test ends.
+func Dial(t testing.TB, url string) *TestClient {
+	t.Helper()
+	return DialWith(t, url, nil)
+}
+
+// DialWith is Dial sending header with the handshake.
+func DialWith(t testing.TB, url string, header http.Header) *TestClient {
+	t.Helper()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return &TestClient{Conn: ws, t: t}
+}
+
+// Send sends msg as is.
+func (c *TestClient) Send(msg map[string]string) {
+	c.t.Helper()
+	if err := c.Conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("sending %v: %v", msg, err)
+	}
+}
+
+// WaitForMessage returns the next message, failing the test if none
+// arrives within timeout.
+func (c *TestClient) WaitForMessage(t testing.TB, timeout time.Duration) map[string]interface{} {
+	t.Helper()
+	c.Conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+	var msg map[string]interface{}
+	if err := c.Conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if count, ok := msg["moveCount"].(float64); ok && int(count) > c.moveCount {
+		c.moveCount = int(count)
+	}
+	return msg
+}
+
+// WaitFor skips messages until one satisfies match, failing the test if
+// none arrives within DefaultTimeout.
+func (c *TestClient) WaitFor(match func(map[string]interface{}) bool) map[string]interface{} {
+	c.t.Helper()
+	deadline := time.Now().Add(DefaultTimeout)
+	for {
+		msg := c.WaitForMessage(c.t, time.Until(deadline))
+		if match(msg) {
+			return msg
+		}
+	}
+}
+
+// reply waits for a message with the given status, failing the test on
+// an error reply, and decodes it into v.
+func (c *TestClient) reply(action, status string, v interface{}) {
+	c.t.Helper()
+	msg := c.WaitFor(func(msg map[string]interface{}) bool {
+		return msg["error"] != nil || msg["status"] == status
+	})
+	if msg["error"] != nil {
+		c.t.Fatalf("%s: %v", action, msg["error"])
+	}
+	decode(c.t, msg, v)
+}
+
+// CreateGame creates a game with time control tc and takes its first seat.
+func (c *TestClient) CreateGame(tc TimeControl) GameCreatedResponse {
+	c.t.Helper()
+	msg := map[string]string{"action": "create"}
+	if tc != Untimed {
+		msg["timeControl"] = string(tc)
+	}
+	if c.Name != "" {
+		msg["name"] = c.Name
+	}
+	c.Send(msg)
+	var created GameCreatedResponse
+	c.reply("create", "created", &created)
+	c.color = created.Color
+	return created
+}
+
+// JoinGame takes the second seat of game id.
+func (c *TestClient) JoinGame(id string) JoinedResponse {
+	c.t.Helper()
+	msg := map[string]string{"action": "join", "gameID": id}
+	if c.Name != "" {
+		msg["name"] = c.Name
+	}
+	c.Send(msg)
+	var joined JoinedResponse
+	c.reply("join", "joined", &joined)
+	c.color = joined.Color
+	return joined
+}
+
+// Ready says the client is ready for game id to start. It does not wait,
+// as the game only starts once both players are ready.
+func (c *TestClient) Ready(id string) {
+	c.t.Helper()
+	c.Send(map[string]string{"action": "ready", "gameID": id})
+}
+
+// WaitForState returns the next game state.
+func (c *TestClient) WaitForState() GameStateResponse {
+	c.t.Helper()
+	return c.state(c.WaitFor(isState))
+}
+
+// MakeMove plays move in game id and returns the state it led to, failing
+// the test if the move is refused.
+func (c *TestClient) MakeMove(id, move string) GameStateResponse {
+	c.t.Helper()
+	before := c.moveCount
+	c.Send(map[string]string{"action": "move", "gameID": id, "move": move})
+	msg := c.WaitFor(func(msg map[string]interface{}) bool {
+		if msg["error"] != nil {
+			return true
+		}
+		count, _ := msg["moveCount"].(float64)
+		// States broadcast before the move was made are skipped: they
+		// have as many moves as seen already, or the mover still to move.
+		return isState(msg) && int(count) > before && !toMove(msg, c.color)
+	})
+	if msg["error"] != nil {
+		c.t.Fatalf("playing %s: %v", move, msg["error"])
+	}
+	return c.state(msg)
+}
+
+func (c *TestClient) state(msg map[string]interface{}) GameStateResponse {
+	c.t.Helper()
+	var state GameStateResponse
+	decode(c.t, msg, &state)
+	state.Raw = msg
+	return state
+}
+
+func isState(msg map[string]interface{}) bool {
+	_, ok := msg["fen"]
+	return ok
+}
+
+// toMove reports whether color, "w" or "b", is to move in state.
+func toMove(state map[string]interface{}, color string) bool {
+	fen, _ := state["fen"].(string)
+	fields := strings.Fields(fen)
+	return color != "" && len(fields) > 1 && fields[1] == color
+}
+
+func decode(t testing.TB, msg map[string]interface{}, v interface{}) {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		t.Fatalf("decoding %v: %v", msg, err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+const (
+	// timePressure is how little time a player can have left when they
+	// move for the move to count as made under time pressure.
+	timePressure = 30 * time.Second
+
+	// recentMoveTimes is how many of each side's last move times are
+	// reported.
+	recentMoveTimes = 10
+
+	// blunderLoss is how much material, in centipawns, a move has to lose
+	// by the opponent's reply to count as a blunder. Without an engine,
+	// only material blunders are recognised.
+	blunderLoss = 200
+)
+
+type TimePressureStats struct {
+	WhiteMoveTimesMs            []int64 `json:"whiteMoveTimesMs"`
+	BlackMoveTimesMs            []int64 `json:"blackMoveTimesMs"`
+	WhiteTimePressureMoves      int     `json:"whiteTimePressureMoves"`
+	BlackTimePressureMoves      int     `json:"blackTimePressureMoves"`
+	CorrelationBlunderToLowTime float64 `json:"correlationBlunderToLowTime"`
+}
+
+// materialBalance is color's material lead in centipawns.
+func materialBalance(pos *chess.Position, color chess.Color) int {
+	return countMaterial(pos, color).centipawns() - countMaterial(pos, color.Other()).centipawns()
+}
+
+// isMaterialBlunder reports whether move i of positions lost at least
+// blunderLoss for its side once the opponent had replied.
+func isMaterialBlunder(positions []*chess.Position, i int) bool {
+	side := positions[i].Turn()
+	after := positions[min(i+2, len(positions)-1)]
+	return materialBalance(positions[i], side)-materialBalance(after, side) >= blunderLoss
+}
+
+// correlation is the Pearson correlation of xs and ys, or 0 when either
+// does not vary.
+func correlation(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// timePressureStats works out game's statistics from its move times and,
+// in timed games, the clock before each move. The correlation compares
+// material blunders with how little time was left when they were made.
+// The caller must hold the game's lock.
+func timePressureStats(game *Game) TimePressureStats {
+	stats := TimePressureStats{WhiteMoveTimesMs: []int64{}, BlackMoveTimesMs: []int64{}}
+	positions := game.Game.Positions()
+	var lowTime, blunders []float64
+	for i, used := range game.MoveTimes {
+		if i+1 >= len(positions) {
+			break
+		}
+		side := positions[i].Turn()
+		if side == chess.White {
+			stats.WhiteMoveTimesMs = append(stats.WhiteMoveTimesMs, used.Milliseconds())
+		} else {
+			stats.BlackMoveTimesMs = append(stats.BlackMoveTimesMs, used.Milliseconds())
+		}
+
+		if game.Clock == nil || i >= len(game.Clock.History) {
+			continue
+		}
+		left := max(game.Clock.History[i][clockIndex(side)]-used, 0)
+		if left < timePressure {
+			if side == chess.White {
+				stats.WhiteTimePressureMoves++
+			} else {
+				stats.BlackTimePressureMoves++
+			}
+		}
+		blunder := 0.0
+		if isMaterialBlunder(positions, i) {
+			blunder = 1
+		}
+		lowTime = append(lowTime, -left.Seconds())
+		blunders = append(blunders, blunder)
+	}
+	stats.WhiteMoveTimesMs = stats.WhiteMoveTimesMs[max(len(stats.WhiteMoveTimesMs)-recentMoveTimes, 0):]
+	stats.BlackMoveTimesMs = stats.BlackMoveTimesMs[max(len(stats.BlackMoveTimesMs)-recentMoveTimes, 0):]
+	stats.CorrelationBlunderToLowTime = correlation(blunders, lowTime)
+	return stats
+}
+
+func getTimePressureStats(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	game.Lock()
+	over := game.Game.Outcome() != chess.NoOutcome
+	var stats TimePressureStats
+	if over {
+		stats = timePressureStats(game)
+	}
+	game.Unlock()
+	gamesMutex.Unlock()
+
+	if !over {
+		sendJSON(ws, map[string]string{"error": "game still in progress"})
+		return
+	}
+	sendJSON(ws, stats)
+	logger.Debug("Time pressure stats sent", slog.String("gameID", gameID))
+}
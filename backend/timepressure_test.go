@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// queenBlunderGame has white drop its queen with 3.Qxe5+?? on 25 seconds.
+func queenBlunderGame(t *testing.T) *Game {
+	t.Helper()
+	g := chess.NewGame()
+	for _, move := range []string{"e4", "e5", "Qh5", "Nc6", "Qxe5+", "Nxe5"} {
+		if err := g.MoveStr(move); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	s := time.Second
+	return &Game{
+		Game:      g,
+		MoveTimes: []time.Duration{5 * s, 5 * s, 5 * s, 5 * s, 5 * s, 5 * s},
+		Clock: &GameClock{History: [][2]time.Duration{
+			{60 * s, 60 * s},
+			{55 * s, 60 * s},
+			{55 * s, 55 * s},
+			{50 * s, 55 * s},
+			{25 * s, 50 * s},
+			{20 * s, 50 * s},
+		}},
+	}
+}
+
+func TestTimePressureStats(t *testing.T) {
+	stats := timePressureStats(queenBlunderGame(t))
+	want := []int64{5000, 5000, 5000}
+	if !reflect.DeepEqual(stats.WhiteMoveTimesMs, want) || !reflect.DeepEqual(stats.BlackMoveTimesMs, want) {
+		t.Errorf("move times = %v and %v, want %v each", stats.WhiteMoveTimesMs, stats.BlackMoveTimesMs, want)
+	}
+	if stats.WhiteTimePressureMoves != 1 || stats.BlackTimePressureMoves != 0 {
+		t.Errorf("time pressure moves = %d and %d, want 1 and 0", stats.WhiteTimePressureMoves, stats.BlackTimePressureMoves)
+	}
+	if stats.CorrelationBlunderToLowTime < 0.9 {
+		t.Errorf("correlation = %v, want the blunder on low time to show", stats.CorrelationBlunderToLowTime)
+	}
+}
+
+func TestTimePressureStatsUntimed(t *testing.T) {
+	game := queenBlunderGame(t)
+	game.Clock = nil
+	stats := timePressureStats(game)
+	if len(stats.WhiteMoveTimesMs) != 3 || len(stats.BlackMoveTimesMs) != 3 {
+		t.Errorf("move times = %v and %v, want three each", stats.WhiteMoveTimesMs, stats.BlackMoveTimesMs)
+	}
+	if stats.WhiteTimePressureMoves != 0 || stats.BlackTimePressureMoves != 0 || stats.CorrelationBlunderToLowTime != 0 {
+		t.Errorf("untimed game has clock statistics: %+v", stats)
+	}
+}
+
+func TestTimePressureStatsKeepsLastMoveTimes(t *testing.T) {
+	game := &Game{Game: chess.NewGame()}
+	moves := []string{
+		"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7", "Re1", "b5",
+		"Bb3", "d6", "c3", "O-O", "h3", "Nb8", "d4", "Nbd7", "c4", "c6", "cxb5", "axb5",
+	}
+	for i, move := range moves {
+		if err := game.Game.MoveStr(move); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+		game.MoveTimes = append(game.MoveTimes, time.Duration(i)*time.Second)
+	}
+	stats := timePressureStats(game)
+	var want []int64
+	for i := 4; i < len(moves); i += 2 {
+		want = append(want, int64(i)*1000)
+	}
+	if !reflect.DeepEqual(stats.WhiteMoveTimesMs, want) {
+		t.Errorf("white move times = %v, want the last ten: %v", stats.WhiteMoveTimesMs, want)
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	for _, c := range []struct {
+		xs, ys []float64
+		want   float64
+	}{
+		{[]float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{[]float64{1, 2, 3}, []float64{3, 2, 1}, -1},
+		{[]float64{1, 1, 1}, []float64{1, 2, 3}, 0},
+		{[]float64{1}, []float64{1}, 0},
+	} {
+		if got := correlation(c.xs, c.ys); got < c.want-1e-9 || got > c.want+1e-9 {
+			t.Errorf("correlation(%v, %v) = %v, want %v", c.xs, c.ys, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serve runs server on ln, over HTTPS when cfg has a certificate and key
+// or an ACME domain, whose certificates server.TLSConfig then fetches.
+func serve(server *http.Server, ln net.Listener, cfg *Config) error {
+	if cfg.useTLS() {
+		return server.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return server.Serve(ln)
+}
+
+// acmeManager fetches and renews the certificate for cfg.ACMEDomain from
+// Let's Encrypt, caching it in cfg.ACMECacheDir across restarts.
+func acmeManager(cfg *Config) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.ACMEDomain),
+		Cache:      autocert.DirCache(cfg.ACMECacheDir),
+	}
+}
+
+// redirectToHTTPS sends every request to the same host and path over
+// HTTPS on tlsPort.
+func redirectToHTTPS(tlsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// checkOrigin accepts WebSocket upgrades from the allowed origins, or from
+// anywhere when none are configured. Requests without an Origin header do
+// not come from a browser and are let through.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(config.AllowedOrigins) == 0 || origin == "" {
+		return true
+	}
+	for _, allowed := range config.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
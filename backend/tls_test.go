@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// selfSignedCert writes a certificate for 127.0.0.1 and its key to files
+// in a temporary directory and returns their paths and a pool trusting it.
+func selfSignedCert(t *testing.T) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	return certFile, keyFile, pool
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile, pool := selfSignedCert(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: testHandler(t)}
+	done := make(chan error, 1)
+	go func() { done <- serve(server, ln, &Config{TLSCertFile: certFile, TLSKeyFile: keyFile}) }()
+	t.Cleanup(func() {
+		server.Close()
+		if err := <-done; err != http.ErrServerClosed {
+			t.Errorf("serve returned %v", err)
+		}
+	})
+
+	dialer := websocket.Dialer{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	ws, _, err := dialer.Dial("wss://"+ln.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dialing over TLS: %v", err)
+	}
+	defer ws.Close()
+	send(t, ws, map[string]string{"action": "create"})
+	readUntil(t, ws, hasStatus("created"))
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, c := range []struct{ tlsPort, want string }{
+		{"443", "https://chess.example/games?status=all"},
+		{"8443", "https://chess.example:8443/games?status=all"},
+	} {
+		srv := httptest.NewServer(redirectToHTTPS(c.tlsPort))
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/games?status=all", nil)
+		req.Host = "chess.example:80"
+		resp, err := client.Do(req)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != c.want {
+			t.Errorf("port %s: got %s to %q, want 301 to %q", c.tlsPort, resp.Status, resp.Header.Get("Location"), c.want)
+		}
+	}
+}
+
+func TestAllowedOrigins(t *testing.T) {
+	useConfig(t, func(cfg *Config) { cfg.AllowedOrigins = []string{"https://chess.example"} })
+	srv := httptest.NewTLSServer(testHandler(t))
+	t.Cleanup(srv.Close)
+	dialer := websocket.Dialer{TLSClientConfig: srv.Client().Transport.(*http.Transport).TLSClientConfig}
+	url := "wss" + strings.TrimPrefix(srv.URL, "https") + "/ws"
+
+	ws, _, err := dialer.Dial(url, http.Header{"Origin": {"https://chess.example"}})
+	if err != nil {
+		t.Fatalf("dialing from an allowed origin: %v", err)
+	}
+	ws.Close()
+
+	_, resp, err := dialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("dialing from another origin: got %v, want 403", err)
+	}
+}
+
+func TestACMEConfig(t *testing.T) {
+	t.Setenv("ACME_DOMAIN", "chess.example")
+	t.Setenv("ACME_CACHE_DIR", "/var/cache/chess")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ACMEDomain != "chess.example" || cfg.ACMECacheDir != "/var/cache/chess" || !cfg.useTLS() || cfg.Port != "443" {
+		t.Errorf("got domain %q, cache %q, TLS %t, port %s", cfg.ACMEDomain, cfg.ACMECacheDir, cfg.useTLS(), cfg.Port)
+	}
+
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_KEY_FILE", "key.pem")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "ACME_DOMAIN") {
+		t.Errorf("LoadConfig with ACME_DOMAIN and a certificate returned %v, want an error", err)
+	}
+}
+
+func TestACMEManager(t *testing.T) {
+	dir := t.TempDir()
+	m := acmeManager(&Config{ACMEDomain: "chess.example", ACMECacheDir: dir})
+	if m.Cache != autocert.DirCache(dir) {
+		t.Errorf("got cache %v, want %s", m.Cache, dir)
+	}
+	if err := m.HostPolicy(context.Background(), "chess.example"); err != nil {
+		t.Errorf("policy refused the domain: %v", err)
+	}
+	if err := m.HostPolicy(context.Background(), "evil.example"); err == nil {
+		t.Error("policy accepted another domain")
+	}
+	if m.TLSConfig().GetCertificate == nil {
+		t.Error("TLS config does not fetch certificates")
+	}
+
+	// Anything but a challenge still redirects to HTTPS.
+	srv := httptest.NewServer(m.HTTPHandler(redirectToHTTPS("443")))
+	t.Cleanup(srv.Close)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/games", nil)
+	req.Host = "chess.example"
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Location") != "https://chess.example/games" {
+		t.Errorf("got %s to %q, want a redirect to HTTPS", resp.Status, resp.Header.Get("Location"))
+	}
+}
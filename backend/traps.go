@@ -0,0 +1,104 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+)
+
+// trapMoveLimit is the number of half-moves, 15 moves each, during which
+// positions are checked against the trap database.
+const trapMoveLimit = 30
+
+//go:embed traps.json
+var trapsJSON []byte
+
+// OpeningTrap is a position in which the side to move can spring a trap
+// with TriggeringMove.
+type OpeningTrap struct {
+	Name           string `json:"name"`
+	FEN            string `json:"fen"`
+	TriggeringMove string `json:"triggeringMove"`
+	Consequence    string `json:"consequence"`
+}
+
+type TrapDetection struct {
+	TrapAvailable  bool   `json:"trapAvailable"`
+	Trap           string `json:"trap,omitempty"`
+	TriggeringMove string `json:"triggeringMove,omitempty"`
+	Consequence    string `json:"consequence,omitempty"`
+	Warning        string `json:"warning,omitempty"`
+}
+
+var openingTraps []OpeningTrap
+
+func init() {
+	if err := json.Unmarshal(trapsJSON, &openingTraps); err != nil {
+		panic("loading traps.json: " + err.Error())
+	}
+}
+
+// trapKey keeps the placement, side to move and castling fields of a FEN,
+// so move counters and en passant squares do not stop a match.
+func trapKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	return strings.Join(fields, " ")
+}
+
+func findTrap(pos *chess.Position) (OpeningTrap, bool) {
+	key := trapKey(pos.String())
+	for _, trap := range openingTraps {
+		if trapKey(trap.FEN) == key {
+			return trap, true
+		}
+	}
+	return OpeningTrap{}, false
+}
+
+// detectOpeningTrap reports a trap the side to move can spring, or warns it
+// off a move that would walk into one.
+func detectOpeningTrap(game *chess.Game) TrapDetection {
+	var result TrapDetection
+	if len(game.Moves()) >= trapMoveLimit {
+		return result
+	}
+	pos := game.Position()
+	if trap, ok := findTrap(pos); ok {
+		result.TrapAvailable = true
+		result.Trap = trap.Name
+		result.TriggeringMove = trap.TriggeringMove
+		result.Consequence = trap.Consequence
+		return result
+	}
+	for _, m := range pos.ValidMoves() {
+		if trap, ok := findTrap(pos.Update(m)); ok {
+			move := chess.AlgebraicNotation{}.Encode(pos, m)
+			result.Warning = fmt.Sprintf("Avoid %s: it walks into %s (%s)", move, trap.Name, trap.TriggeringMove)
+			break
+		}
+	}
+	return result
+}
+
+func getOpeningTrap(ws *websocket.Conn, gameID string) {
+	gamesMutex.Lock()
+	game, exists := games[gameID]
+	if !exists {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		return
+	}
+	result := detectOpeningTrap(game.Game)
+	gamesMutex.Unlock()
+
+	sendJSON(ws, result)
+	logger.Debug("Opening trap check sent", slog.String("gameID", gameID))
+}
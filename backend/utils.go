@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"log/slog"
 	"math/rand"
 
 	"github.com/gorilla/websocket"
@@ -21,6 +23,57 @@ func toggleColor(color chess.Color) chess.Color {
 	return chess.White
 }
 
+// colorName returns "white" or "black", the form colors take in messages.
+func colorName(color chess.Color) string {
+	switch color {
+	case chess.White:
+		return "white"
+	case chess.Black:
+		return "black"
+	}
+	return ""
+}
+
+// sideName returns "White" or "Black", for use in prose.
+func sideName(color chess.Color) string {
+	if color == chess.Black {
+		return "Black"
+	}
+	return "White"
+}
+
+// outcomeWinner returns the color name of the winner of a decisive outcome,
+// or an empty string for draws and unfinished games.
+func outcomeWinner(outcome chess.Outcome) string {
+	switch outcome {
+	case chess.WhiteWon:
+		return "white"
+	case chess.BlackWon:
+		return "black"
+	}
+	return ""
+}
+
+// parseColorName parses "white" or "black".
+func parseColorName(name string) (chess.Color, bool) {
+	switch name {
+	case "white":
+		return chess.White, true
+	case "black":
+		return chess.Black, true
+	}
+	return chess.NoColor, false
+}
+
+func getPlayer(ws *websocket.Conn, game *Game) *Player {
+	for _, player := range game.Players {
+		if player.Conn == ws {
+			return player
+		}
+	}
+	return nil
+}
+
 func getPlayerColor(ws *websocket.Conn, game *Game) chess.Color {
 	for _, player := range game.Players {
 		if player.Conn == ws {
@@ -29,3 +82,85 @@ func getPlayerColor(ws *websocket.Conn, game *Game) chess.Color {
 	}
 	return chess.NoColor
 }
+
+// playerName returns the display name of the player of color, or "" for
+// an empty seat.
+func playerName(game *Game, color chess.Color) string {
+	for _, player := range game.Players {
+		if player.Color == color {
+			return player.Name
+		}
+	}
+	return ""
+}
+
+// sendJSON queues v for ws's writer, reporting whether it was queued.
+// Nothing is sent to a nil connection, such as a player who has dropped out.
+// v is encoded straight away, so callers may change it afterwards. Error
+// replies are sent as control messages, ahead of any queued game states.
+func sendJSON(ws *websocket.Conn, v interface{}) bool {
+	return queueJSON(ws, v, isErrorReply(v))
+}
+
+// sendControl is sendJSON for a time-critical message that goes ahead of
+// anything else queued for ws.
+func sendControl(ws *websocket.Conn, v interface{}) bool {
+	return queueJSON(ws, v, true)
+}
+
+func queueJSON(ws *websocket.Conn, v interface{}, control bool) bool {
+	if ws == nil {
+		return false
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Error("Error encoding response", slog.Any("error", err))
+		return false
+	}
+	return enqueue(ws, outgoing{data: data, control: control})
+}
+
+// isErrorReply reports whether v is an {"error": ...} reply.
+func isErrorReply(v interface{}) bool {
+	var ok bool
+	switch m := v.(type) {
+	case map[string]string:
+		_, ok = m["error"]
+	case map[string]interface{}:
+		_, ok = m["error"]
+	}
+	return ok
+}
+
+// currentPosition returns the current position of the game with the given
+// ID, replying to ws with an error if there is no such game.
+func currentPosition(ws *websocket.Conn, gameID string) (*chess.Position, bool) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	game, exists := games[gameID]
+	if !exists {
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to query non-existent game", slog.String("gameID", gameID))
+		return nil, false
+	}
+	return game.Game.Position(), true
+}
+
+// parseSquare parses an algebraic square name such as "e4".
+func parseSquare(s string) (chess.Square, bool) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return chess.NoSquare, false
+	}
+	return chess.NewSquare(chess.File(s[0]-'a'), chess.Rank(s[1]-'1')), true
+}
+
+// sanMoves returns the moves of g in standard algebraic notation.
+func sanMoves(g *chess.Game) []string {
+	positions := g.Positions()
+	moves := []string{}
+	for i, m := range g.Moves() {
+		moves = append(moves, chess.AlgebraicNotation{}.Encode(positions[i], m))
+	}
+	return moves
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// webhookRetries is how many times a failed webhook call is retried,
+// waiting webhookBackoff before the first retry and twice as long before
+// each one after. webhookBackoff is a variable so tests can shorten it.
+const webhookRetries = 3
+
+var webhookBackoff = time.Second
+
+var webhookClient = &http.Client{Timeout: 5 * time.Second}
+
+// gameEndPayload is the body posted to the webhook when a game ends.
+type gameEndPayload struct {
+	GameID          string   `json:"gameID"`
+	Outcome         string   `json:"outcome"`
+	Method          string   `json:"method"`
+	Moves           []string `json:"moves"`
+	DurationSeconds int      `json:"durationSeconds"`
+	PGN             string   `json:"pgn"`
+}
+
+// newGameEndPayload describes game from the status, winner and method that
+// broadcastGameState worked out for it. The caller must hold gamesMutex and
+// the game's lock.
+func newGameEndPayload(gameID string, game *Game, status, winner, method string) *gameEndPayload {
+	outcome := winner
+	if outcome == "" {
+		outcome = outcomeWinner(game.Game.Outcome())
+	}
+	if outcome == "" {
+		outcome = "draw"
+	}
+	switch {
+	case method != "":
+	case status == "resigned":
+		method = "resignation"
+	case status == "draw":
+		method = "insufficientMaterial"
+	default:
+		method = status
+	}
+	return &gameEndPayload{
+		GameID:          gameID,
+		Outcome:         outcome,
+		Method:          method,
+		Moves:           sanMoves(game.Game),
+		DurationSeconds: int(gameLength(game).Seconds()),
+		PGN:             gamePGN(game),
+	}
+}
+
+// notifyGameEnd posts payload to url, retrying with exponential backoff
+// if the call fails or is not answered with a 2xx status.
+func notifyGameEnd(url string, payload *gameEndPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Error encoding webhook payload", slog.String("gameID", payload.GameID), slog.Any("error", err))
+		return
+	}
+
+	backoff := webhookBackoff
+	for attempt := 0; ; attempt++ {
+		err = postWebhook(url, body)
+		if err == nil {
+			logger.Info("Game end webhook sent", slog.String("gameID", payload.GameID))
+			return
+		}
+		if attempt == webhookRetries {
+			break
+		}
+		logger.Warn("Game end webhook failed, retrying", slog.String("gameID", payload.GameID), slog.Duration("backoff", backoff), slog.Any("error", err))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	logger.Error("Game end webhook failed", slog.String("gameID", payload.GameID), slog.Int("attempts", webhookRetries+1), slog.Any("error", err))
+}
+
+func postWebhook(url string, body []byte) error {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
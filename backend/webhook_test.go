@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGameEndWebhook(t *testing.T) {
+	payloads := make(chan gameEndPayload, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("webhook Content-Type = %q", ct)
+		}
+		var payload gameEndPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding webhook payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer hook.Close()
+	useConfig(t, func(cfg *Config) { cfg.GameEndWebhookURL = hook.URL })
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	play(t, white, black, gameID, "f3", "e5", "g4", "Qh4#")
+	select {
+	case payload := <-payloads:
+		if payload.GameID != gameID || payload.Outcome != "black" || payload.Method != "checkmate" {
+			t.Errorf("payload = %s won by %s in %s, want black by checkmate in %s", payload.Outcome, payload.Method, payload.GameID, gameID)
+		}
+		if want := []string{"f3", "e5", "g4", "Qh4#"}; !reflect.DeepEqual(payload.Moves, want) {
+			t.Errorf("payload moves = %v, want %v", payload.Moves, want)
+		}
+		if !strings.Contains(payload.PGN, "Qh4#") || !strings.Contains(payload.PGN, `[Result "0-1"]`) {
+			t.Errorf("payload PGN = %q", payload.PGN)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the game end webhook was not called")
+	}
+}
+
+func TestGameEndWebhookRetries(t *testing.T) {
+	saved := webhookBackoff
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() { webhookBackoff = saved })
+
+	for _, c := range []struct {
+		name         string
+		failures     int32
+		wantAttempts int32
+	}{
+		{"succeeds at once", 0, 1},
+		{"succeeds on the last retry", webhookRetries, webhookRetries + 1},
+		{"keeps failing", 10, webhookRetries + 1},
+	} {
+		var attempts atomic.Int32
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) <= c.failures {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			}
+		}))
+		notifyGameEnd(hook.URL, &gameEndPayload{GameID: "g1", Outcome: "draw", Method: "agreement"})
+		hook.Close()
+		if got := attempts.Load(); got != c.wantAttempts {
+			t.Errorf("%s: %d attempts, want %d", c.name, got, c.wantAttempts)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/notnil/chess"
@@ -13,162 +15,515 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all connections
-	},
+	CheckOrigin:     checkOrigin,
 }
 
 var (
 	games      = make(map[string]*Game)
-	gamesMutex sync.Mutex
+	gamesMutex sync.RWMutex
 )
 
 func handleConnections(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	if !acquireConnection(ip) {
+		logger.Warn("Too many connections", slog.String("ip", ip))
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+	defer releaseConnection(ip)
+
+	claims, authenticated, err := authenticate(r)
+	if err != nil {
+		logger.Warn("Authentication error", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("Upgrade error:", err)
+		logger.Error("Upgrade error", slog.Any("error", err))
 		return
 	}
 	defer ws.Close()
+	startWriter(ws)
+	defer stopWriter(ws)
+	connectionsActive.Inc()
+	defer connectionsActive.Dec()
+
+	defer forgetIdentity(ws)
+	if authenticated {
+		rememberIdentity(ws, claims)
+	}
+
+	stopHeartbeat := startHeartbeat(ws)
+	defer stopHeartbeat()
+	defer forgetLatency(ws)
+	defer forgetConnectionLimiter(ws)
+	defer removePlayer(ws)
+	defer leaveQueue(ws)
+	defer leaveLessons(ws)
 
 	// Handle WebSocket communication
 	for {
-		var msg map[string]string
+		var msg Message
 		err := ws.ReadJSON(&msg)
 		if err != nil {
-			log.Println("Read error:", err)
+			if isTimeout(err) {
+				logger.Info("Connection timed out", slog.String("remoteAddr", ws.RemoteAddr().String()))
+				closeConnection(ws, websocket.CloseGoingAway, "connection timed out")
+			} else {
+				logger.Debug("Read error", slog.Any("error", err))
+			}
 			break
 		}
+		ws.SetReadDeadline(time.Now().Add(pongWait))
 
 		// Process WebSocket messages (e.g., game actions, moves)
 		handleMessage(ws, msg)
+		touchPlayer(ws)
 	}
 }
 
 func handleMessage(ws *websocket.Conn, msg map[string]string) {
 	// Implement your WebSocket message handling logic here
-	log.Printf("Received message: %v", msg)
+	action := msg["action"]
+	reqLog := logger.With(slog.String("requestID", ksuid.New().String()), slog.String("action", action))
+	reqLog.Debug("Received message", slog.String("gameID", msg["gameID"]))
+	if !allowMessage(ws) {
+		reqLog.Warn("Message rate limit exceeded")
+		return
+	}
 
 	// Example: Handle different message types (create, join, move)
-	action := msg["action"]
 	switch action {
 	case "create":
-		createGame(ws)
+		createGame(ws, msg)
 	case "join":
-		joinGame(ws, msg["gameID"])
+		joinGame(ws, msg)
+	case "ready":
+		markReady(ws, msg["gameID"])
+	case "findGame":
+		findGame(ws, msg)
+	case "cancelFind":
+		cancelFind(ws)
 	case "move":
-		makeMove(ws, msg["gameID"], msg["move"])
+		makeMove(ws, msg["gameID"], msg["move"], false)
+	case "confirm_move":
+		confirmMove(ws, msg["gameID"], msg["move"])
+	case "cancel_move":
+		cancelMove(ws, msg["gameID"])
+	case "set_game_result":
+		setGameResult(ws, msg)
+	case "get_material_eval":
+		getMaterialEval(ws, msg["gameID"])
+	case "generate_fen_from_description":
+		generateFENFromDescription(ws, msg["description"])
+	case "share_position":
+		sharePosition(ws, msg["gameID"])
+	case "get_pawn_structure":
+		getPawnStructure(ws, msg["gameID"])
+	case "get_king_safety":
+		getKingSafety(ws, msg["gameID"])
+	case "get_zugzwang_risk":
+		getZugzwangRisk(ws, msg["gameID"])
+	case "detect_tactics":
+		detectTactics(ws, msg["gameID"])
+	case "get_plan_suggestion":
+		getPlanSuggestion(ws, msg["gameID"])
+	case "get_position_complexity":
+		getPositionComplexity(ws, msg["gameID"])
+	case "get_board_control_over_time":
+		getBoardControlOverTime(ws, msg["gameID"])
+	case "get_endgame_classification":
+		getEndgameClassification(ws, msg["gameID"])
+	case "get_piece_mobility":
+		getPieceMobility(ws, msg["gameID"])
+	case "get_space_advantage":
+		getSpaceAdvantage(ws, msg["gameID"])
+	case "get_rook_activity":
+		getRookActivity(ws, msg["gameID"])
+	case "get_checkmate_patterns":
+		getCheckmatePatterns(ws, msg["gameID"])
+	case "get_color_weakness":
+		getColorWeakness(ws, msg["gameID"])
+	case "get_piece_coordination":
+		getPieceCoordination(ws, msg["gameID"])
+	case "compute_knight_tour":
+		computeKnightTour(ws, msg["startSquare"])
+	case "get_pawn_breakthrough":
+		getPawnBreakthrough(ws, msg["gameID"])
+	case "get_opposition_analysis":
+		getOppositionAnalysis(ws, msg["gameID"])
+	case "compute_material_imbalance_evaluation":
+		computeMaterialImbalanceEvaluation(ws, msg["gameID"])
+	case "generate_practice_game":
+		generatePracticeGame(ws, msg)
+	case "get_opening_trap":
+		getOpeningTrap(ws, msg["gameID"])
+	case "get_opposite_colored_bishops":
+		getOppositeColoredBishops(ws, msg["gameID"])
+	case "get_zugzwang_positions":
+		getZugzwangPositions(ws, msg["gameID"])
+	case "get_battery_analysis":
+		getBatteryAnalysis(ws, msg["gameID"])
+	case "get_outpost_squares":
+		getOutpostSquares(ws, msg["gameID"])
+	case "get_clock_history":
+		getClockHistory(ws, msg["gameID"])
+	case "get_time_pressure_stats":
+		getTimePressureStats(ws, msg["gameID"])
+	case "get_leaderboard_nearby":
+		getLeaderboardNearby(ws, msg["playerID"], msg["radius"])
+	case "position_frequency":
+		getPositionFrequency(ws, msg["gameID"])
+	case "get_similar_positions":
+		getSimilarPositions(ws, msg["gameID"])
+	case "get_activity_heatmap":
+		getActivityHeatmap(ws, msg["playerID"])
+	case "export_csv":
+		exportCSV(ws, msg["playerID"])
+	case "requestTakeback":
+		requestTakeback(ws, msg["gameID"])
+	case "acceptTakeback":
+		acceptTakeback(ws, msg["gameID"])
+	case "declineTakeback":
+		declineTakeback(ws, msg["gameID"])
+	case "offerRematch":
+		offerRematch(ws, msg["gameID"])
+	case "acceptRematch":
+		acceptRematch(ws, msg["gameID"])
+	case "declineRematch":
+		declineRematch(ws, msg["gameID"])
+	case "chat":
+		sendChat(ws, msg["gameID"], msg["message"])
+	case "getChatHistory":
+		getChatHistory(ws, msg["gameID"])
+	case "getPGN":
+		getPGN(ws, msg["gameID"])
+	case "syncState":
+		syncState(ws, msg["gameID"], msg["sinceSeq"])
+	case "getLegalMoves":
+		getLegalMoves(ws, msg["gameID"], msg["square"])
+	case "resign":
+		resignGame(ws, msg["gameID"])
+	case "abort":
+		abortGame(ws, msg["gameID"])
+	case "offerDraw":
+		offerDraw(ws, msg["gameID"])
+	case "acceptDraw":
+		acceptDraw(ws, msg["gameID"])
+	case "claimDraw":
+		claimDraw(ws, msg["gameID"], msg["reason"])
+	case "declineDraw":
+		declineDraw(ws, msg["gameID"])
+	case "convert_to_correspondence":
+		convertToCorrespondence(ws, msg["gameID"])
+	case "reconnect":
+		reconnectPlayer(ws, msg["gameID"], msg["playerID"], msg["guestID"], msg["lastMessageID"])
+	case "coach_observe":
+		coachObserve(ws, msg)
+	case "create_lesson":
+		createLesson(ws, msg)
+	case "join_lesson":
+		joinLesson(ws, msg["lessonID"], msg["name"])
+	case "lesson_move":
+		lessonMove(ws, msg["lessonID"], msg["move"])
+	case "coach_comment":
+		coachComment(ws, msg["gameID"], msg["text"])
+	case "coach_whisper":
+		coachWhisper(ws, msg["gameID"], msg["targetColor"], msg["message"])
 	default:
-		log.Printf("Unknown action: %s", action)
+		reqLog.Warn("Unknown action")
 	}
 }
 
-func createGame(ws *websocket.Conn) {
+func createGame(ws *websocket.Conn, msg map[string]string) {
+	if !allowGameCreation(ws) {
+		return
+	}
+	if err := validateName(msg["name"]); err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var clock *GameClock
+	if tc := msg["timeControl"]; tc != "" {
+		var err error
+		clock, err = parseTimeControl(tc)
+		if err != nil {
+			sendJSON(ws, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	variant := msg["variant"]
+	if variant == "" {
+		variant = "standard"
+	}
+	fen := msg["fen"]
+	switch {
+	case variant == "chess960" && fen != "":
+		sendJSON(ws, map[string]string{"error": "a FEN cannot be given for chess960"})
+		return
+	case variant == "chess960":
+		// Played without Chess960 castling; see chess960FEN.
+		fen = chess960FEN(chess960BackRank())
+	case variant != "standard":
+		sendJSON(ws, map[string]string{"error": "unsupported variant"})
+		return
+	}
+
+	chessGame := chess.NewGame()
+	if fen != "" {
+		opt, err := chess.FEN(fen)
+		if err != nil {
+			sendJSON(ws, map[string]string{"error": "invalid FEN: " + err.Error()})
+			return
+		}
+		chessGame = chess.NewGame(opt)
+		pos := chessGame.Position()
+		if err := checkPosition(pos.Board(), pos.Turn()); err != nil {
+			sendJSON(ws, map[string]string{"error": "invalid FEN: " + err.Error()})
+			return
+		}
+	}
+
 	gameID := ksuid.New().String()
 	playerColor := randomColor()
-	player := &Player{Conn: ws, Color: playerColor}
+	player := &Player{
+		Conn:         ws,
+		Color:        playerColor,
+		ConfirmMoves: msg["confirmMoves"] == "true",
+	}
+	player.PlayerID, player.Subject, player.Name = identify(ws, msg["name"])
+	seatGuest(player)
 	game := &Game{
-		Game: chess.NewGame(),
+		Game: chessGame,
 		Players: []*Player{
 			player,
 		},
+		StartingFEN:       chessGame.Position().String(),
+		Variant:           variant,
+		CreatedAt:         time.Now(),
+		Clock:             clock,
+		TimeControl:       msg["timeControl"],
+		ApplyMoveDelay:    msg["applyMoveDelay"] == "true",
+		StalemateMeansWin: msg["stalemateMeansWin"] == "true",
 	}
 	gamesMutex.Lock()
+	if !allowAnotherGame(ws) {
+		gamesMutex.Unlock()
+		return
+	}
 	games[gameID] = game
+	indexGame(ws, gameID)
+	watchGuestGame(gameID, game)
+	gamesCreated.Inc()
 	gamesMutex.Unlock()
 
 	// Notify the player about the game creation
-	err := ws.WriteJSON(map[string]string{"status": "created", "gameID": gameID, "color": playerColor.String()})
-	if err != nil {
-		log.Println("Error sending game creation response:", err)
-		return
-	}
+	sendJSON(ws, withGuestID(map[string]string{"status": "created", "gameID": gameID, "color": playerColor.String(), "playerID": player.PlayerID}, player))
 
-	log.Printf("Game created with ID: %s", gameID)
+	logger.Info("Game created", slog.String("gameID", gameID), slog.String("playerColor", colorName(playerColor)))
 }
 
-func joinGame(ws *websocket.Conn, gameID string) {
+func joinGame(ws *websocket.Conn, msg map[string]string) {
+	if !allowSeat(ws) {
+		return
+	}
+	gameID := msg["gameID"]
+	if err := validateName(msg["name"]); err != nil {
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		return
+	}
+
 	gamesMutex.Lock()
 	game, exists := games[gameID]
 	if !exists {
 		gamesMutex.Unlock()
-		err := ws.WriteJSON(map[string]string{"error": "game not found"})
-		if err != nil {
-			log.Println("Error sending game not found response:", err)
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to join non-existent game", slog.String("gameID", gameID))
+		return
+	}
+
+	// Once both seats are taken anyone else joins as a spectator, as
+	// does anyone joining a practice game against the server.
+	if len(game.Players) >= 2 || game.PracticeMode != "" {
+		game.Lock()
+		if getPlayer(ws, game) != nil || isSpectator(ws, game) {
+			game.Unlock()
+			gamesMutex.Unlock()
+			sendJSON(ws, map[string]string{"error": "already in this game"})
+			return
+		}
+		if len(game.Spectators) >= config.SpectatorLimit {
+			game.Unlock()
+			gamesMutex.Unlock()
+			sendJSON(ws, map[string]string{"error": "game full"})
+			logger.Warn("Attempt to join full game", slog.String("gameID", gameID))
+			return
 		}
-		log.Printf("Attempt to join non-existent game with ID: %s", gameID)
+		game.Spectators = append(game.Spectators, ws)
+		game.Unlock()
+		indexGame(ws, gameID)
+		gamesMutex.Unlock()
+
+		sendJSON(ws, map[string]string{"status": "spectating", "gameID": gameID})
+		logger.Info("Spectator joined game", slog.String("gameID", gameID))
+		broadcastGameState(gameID)
 		return
 	}
 
-	if len(game.Players) >= 2 {
+	playerID, subject, name := identify(ws, msg["name"])
+	if subject != "" && subject == game.Players[0].Subject {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "already in this game"})
+		return
+	}
+	if name == game.Players[0].Name {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "name already taken"})
+		return
+	}
+	if !allowAnotherGame(ws) {
 		gamesMutex.Unlock()
-		err := ws.WriteJSON(map[string]string{"error": "game full"})
-		if err != nil {
-			log.Println("Error sending game full response:", err)
-		}
-		log.Printf("Attempt to join full game with ID: %s", gameID)
 		return
 	}
 
 	playerColor := toggleColor(game.Players[0].Color)
-	player := &Player{Conn: ws, Color: playerColor}
+	player := &Player{
+		Conn:         ws,
+		Color:        playerColor,
+		PlayerID:     playerID,
+		Subject:      subject,
+		Name:         name,
+		ConfirmMoves: msg["confirmMoves"] == "true",
+	}
+	seatGuest(player)
 	game.Players = append(game.Players, player)
-	gamesMutex.Unlock()
+	indexGame(ws, gameID)
+	watchGuestGame(gameID, game)
+	// The clock and the first game state wait until both clients say
+	// they are ready; see markReady.
+	awaitReady(gameID, game)
 
 	// Notify the player about successfully joining the game
-	err := ws.WriteJSON(map[string]string{"status": "joined", "gameID": gameID, "color": playerColor.String()})
-	if err != nil {
-		log.Println("Error sending game join response:", err)
-		return
+	sendJSON(ws, withGuestID(map[string]string{"status": "joined", "gameID": gameID, "color": playerColor.String(), "playerID": player.PlayerID}, player))
+	for _, p := range game.Players {
+		sendToPlayer(p, map[string]interface{}{"status": "waiting", "gameID": gameID, "readyCount": 0})
 	}
+	gamesMutex.Unlock()
 
-	log.Printf("Player joined game with ID: %s", gameID)
-
-	// Broadcast updated game state to all players
-	broadcastGameState(gameID)
+	logger.Info("Player joined game", slog.String("gameID", gameID), slog.String("playerColor", colorName(playerColor)))
 }
 
-func makeMove(ws *websocket.Conn, gameID, moveStr string) {
+// makeMove applies moveStr for the player on ws. Players who asked to
+// confirm their moves only get a preview unless confirmed is set.
+func makeMove(ws *websocket.Conn, gameID, moveStr string, confirmed bool) {
+	if delay := moveDelay(ws, gameID); delay > 0 {
+		time.Sleep(delay)
+	}
+
 	gamesMutex.Lock()
 	game, exists := games[gameID]
 	if !exists {
 		gamesMutex.Unlock()
-		err := ws.WriteJSON(map[string]string{"error": "game not found"})
-		if err != nil {
-			log.Println("Error sending game not found response:", err)
-		}
-		log.Printf("Attempt to move in non-existent game with ID: %s", gameID)
+		sendJSON(ws, map[string]string{"error": "game not found"})
+		logger.Warn("Attempt to move in non-existent game", slog.String("gameID", gameID))
+		return
+	}
+
+	if game.Game.Outcome() != chess.NoOutcome {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game already over"})
+		logger.Warn("Move attempt in finished game", slog.String("gameID", gameID))
+		return
+	}
+
+	if game.AwaitingReady {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "game has not started"})
 		return
 	}
 
 	if game.Game.Position().Turn() != getPlayerColor(ws, game) {
 		gamesMutex.Unlock()
-		err := ws.WriteJSON(map[string]string{"error": "not your turn"})
-		if err != nil {
-			log.Println("Error sending not your turn response:", err)
-		}
-		log.Printf("Invalid move attempt: not player's turn in game %s", gameID)
+		sendJSON(ws, map[string]string{"error": "not your turn"})
+		logger.Warn("Invalid move attempt: not player's turn", slog.String("gameID", gameID))
+		return
+	}
+
+	// Only the player to move is charged, so nobody else can use up
+	// their allowance while their clock runs.
+	if limiter := game.moveLimiter(); !limiter.allow() {
+		gamesMutex.Unlock()
+		sendJSON(ws, map[string]string{"error": "rate limit exceeded", "retryAfter": limiter.retryAfter()})
+		logger.Warn("Move rate limit exceeded", slog.String("gameID", gameID))
+		return
+	}
+
+	if player := getPlayer(ws, game); !confirmed && player != nil && player.ConfirmMoves {
+		previewMove(ws, game, player, moveStr)
+		gamesMutex.Unlock()
+		return
+	}
+
+	// The move only counts if it came in before the mover's flag fell;
+	// the flag timer may not have fired yet.
+	game.Lock()
+	now := time.Now()
+	mover := game.Game.Position().Turn()
+	if game.Clock != nil && game.Clock.running() && game.Clock.remaining(mover, mover, now) <= 0 {
+		game.Unlock()
+		gamesMutex.Unlock()
+		forfeitOnTime(gameID, mover)
 		return
 	}
 
 	err := game.Game.MoveStr(moveStr)
 	if err != nil {
+		moveValidationErrors.Inc()
+		game.Unlock()
 		gamesMutex.Unlock()
-		err := ws.WriteJSON(map[string]string{"error": err.Error()})
-		if err != nil {
-			log.Println("Error sending move error response:", err)
-		}
-		log.Printf("Invalid move in game %s: %s", gameID, moveStr)
+		sendJSON(ws, map[string]string{"error": err.Error()})
+		logger.Warn("Invalid move", slog.String("gameID", gameID), slog.String("playerColor", colorName(mover)), slog.String("move", moveStr))
 		return
 	}
 
+	movesMade.Inc()
+	recordMoveTime(game, now)
+	if game.Clock != nil && game.Clock.running() {
+		game.Clock.switchTurn(mover, now)
+		if game.Game.Outcome() == chess.NoOutcome {
+			armFlag(gameID, game)
+		} else {
+			game.Clock.stop()
+		}
+	}
+	// Playing on instead of answering lets a draw offer or takeback
+	// request lapse, and a move that ends the game withdraws any takeback
+	// request.
+	if game.DrawOffer == mover.Other() {
+		game.DrawOffer = chess.NoColor
+	}
+	if game.TakebackRequest == mover.Other() || game.Game.Outcome() != chess.NoOutcome {
+		clearTakebackRequest(game)
+	}
+	game.Unlock()
+
+	recordMobility(game)
+	practice := game.PracticeMode != ""
 	gamesMutex.Unlock()
 
-	log.Printf("Move made in game %s: %s", gameID, moveStr)
+	logger.Info("Move made", slog.String("gameID", gameID), slog.String("playerColor", colorName(mover)), slog.String("move", moveStr))
 
 	// Broadcast updated game state to all players
 	broadcastGameState(gameID)
+
+	if practice {
+		playPracticeReply(gameID)
+	}
 }
 
 func broadcastGameState(gameID string) {
@@ -176,56 +531,282 @@ func broadcastGameState(gameID string) {
 	game, exists := games[gameID]
 	if !exists {
 		gamesMutex.Unlock()
-		log.Printf("Game not found when broadcasting game state for ID: %s", gameID)
+		logger.Warn("Game not found when broadcasting game state", slog.String("gameID", gameID))
 		return
 	}
 	game.Lock()
 
 	status := "ongoing"
-	if game.Game.Outcome() != chess.NoOutcome {
+	winner := ""
+	method := ""
+	if game.Adjudicated {
+		status = "adjudicated"
+		winner = outcomeWinner(game.Game.Outcome())
+	} else if game.Abandoned {
+		status = "abandoned"
+		winner = outcomeWinner(game.Game.Outcome())
+	} else if game.TimedOut {
+		status = "timeout"
+		winner = outcomeWinner(game.Game.Outcome())
+		if winner == "" {
+			// The opponent of the side that flagged could never have mated.
+			method = "insufficientMaterial"
+		}
+	} else if game.Game.Outcome() != chess.NoOutcome {
 		if game.Game.Method() == chess.Checkmate {
 			status = "checkmate"
+		} else if game.Game.Method() == chess.Stalemate && game.StalemateMeansWin {
+			// The side left without a move loses under this training rule.
+			status = "stalemate_win"
+			winner = colorName(game.Game.Position().Turn().Other())
 		} else if game.Game.Method() == chess.Stalemate {
 			status = "stalemate"
 		} else if game.Game.Method() == chess.InsufficientMaterial {
 			status = "draw"
+		} else if game.Game.Method() == chess.Resignation {
+			status = "resigned"
+			winner = outcomeWinner(game.Game.Outcome())
+		} else if game.Game.Method() == chess.DrawOffer {
+			status = "draw"
+			method = "agreement"
+		} else if game.Game.Method() == chess.FiftyMoveRule {
+			status = "draw"
+			method = "fiftyMoveRule"
+		} else if game.Game.Method() == chess.ThreefoldRepetition {
+			status = "draw"
+			method = "threefoldRepetition"
+		} else if game.Game.Method() == chess.SeventyFiveMoveRule {
+			status = "draw"
+			method = "seventyFiveMoveRule"
+		} else if game.Game.Method() == chess.FivefoldRepetition {
+			status = "draw"
+			method = "fivefoldRepetition"
 		}
 	}
 
-	state := map[string]string{
-		"status": status,
-		"fen":    game.Game.Position().String(),
+	var ended *gameEndPayload
+	var archived []byte
+	justEnded := status != "ongoing" && !game.endRecorded
+	if justEnded {
+		game.endRecorded = true
+		game.EndedAt = time.Now()
+		if game.Clock != nil {
+			game.Clock.stopAt(game.Game.Position().Turn(), game.EndedAt)
+		}
+		gameDuration.Observe(gameLength(game).Seconds())
+		recordCompletedGame(game, status)
+		if game.Ranked {
+			updateRatings(gameID, game)
+		}
+		archived = archiveGame(gameID, game)
+		if config.GameEndWebhookURL != "" {
+			ended = newGameEndPayload(gameID, game, status, winner, method)
+		}
 	}
 
+	state := map[string]interface{}{
+		"status":      status,
+		"fen":         game.Game.Position().String(),
+		"material":    evaluateMaterial(game.Game.Position()),
+		"startingFen": game.StartingFEN,
+		"variant":     game.Variant,
+	}
+	state["whiteName"] = playerName(game, chess.White)
+	state["blackName"] = playerName(game, chess.Black)
+	state["waitingForPlayer"] = len(game.Players) < 2
+	moves := sanMoves(game.Game)
+	state["moves"] = moves
+	state["moveCount"] = len(moves)
+	state["lastMove"] = ""
+	if len(moves) > 0 {
+		state["lastMove"] = moves[len(moves)-1]
+	}
+	if winner != "" {
+		state["winner"] = winner
+	}
+	if len(game.MoveTimes) > 0 {
+		state["lastMoveTime"] = formatMoveTime(game.MoveTimes[len(game.MoveTimes)-1])
+	}
+	if status != "ongoing" {
+		white, black := averageMoveTimes(game.MoveTimes, game.Game.Positions()[0].Turn())
+		state["avgMoveTimeWhite"] = formatMoveTime(white)
+		state["avgMoveTimeBlack"] = formatMoveTime(black)
+		state["durationSeconds"] = gameLength(game).Seconds()
+		state["totalMoves"] = len(moves)
+		state["openingMoves"] = strings.Join(moves[:min(len(moves), openingMovesShown)], " ")
+	}
+	if method != "" {
+		state["method"] = method
+	}
+	if gamePhase(game.Game.Position()) == "endgame" {
+		state["zugzwangRisk"] = assessZugzwangRisk(game.Game.Position())
+		state["phase"] = "endgame"
+	}
+	criticalMoment := false
+	if len(game.Game.Moves()) < complexityMoveLimit {
+		complexity := scorePositionComplexity(game.Game.Position()).Score
+		state["complexity"] = complexity
+		change := complexity - game.LastComplexity
+		criticalMoment = len(game.Game.Moves()) > 0 && (change > criticalComplexityJump || change < -criticalComplexityJump)
+		game.LastComplexity = complexity
+	}
+	if game.Clock != nil {
+		turn := game.Game.Position().Turn()
+		now := time.Now()
+		state["whiteTime"] = game.Clock.remaining(chess.White, turn, now).Milliseconds()
+		state["blackTime"] = game.Clock.remaining(chess.Black, turn, now).Milliseconds()
+		state["clockMode"] = game.Clock.ClockMode
+	}
+	state["spectators"] = len(game.Spectators)
+	if battery, ok := strongestBattery(detectBatteries(game.Game.Position())); ok {
+		state["strongestBattery"] = battery
+	}
+	if game.Adjudicated {
+		state["adjudicated"] = true
+		state["outcome"] = game.Game.Outcome().String()
+		state["reason"] = game.AdjudicationReason
+	}
+	if game.Ranked {
+		state["ranked"] = true
+		if white, black := opponent(game, chess.Black), opponent(game, chess.White); white != nil && black != nil {
+			state["whiteRating"] = white.Rating
+			state["blackRating"] = black.Rating
+		}
+	}
+
+	// state is the same for everyone; stateFor adds what is only for one
+	// recipient. Queueing never blocks on a client, so it is done under
+	// the locks to keep each client's states in the order they were taken.
+	// A player who is away still has the state kept to replay to them.
+	delta := recordState(game, state)
 	for _, player := range game.Players {
-		err := player.Conn.WriteJSON(state)
-		if err != nil {
-			log.Println("Error broadcasting game state:", err)
+		if player.Conn == nil {
+			sendToPlayer(player, stateFor(player, state))
+		} else if !sendState(player.Conn, player, game, state, delta) {
+			broadcastErrors.Inc()
+		}
+	}
+	watchers := make([]*websocket.Conn, 0, len(game.Coaches)+len(game.Spectators))
+	watchers = append(watchers, game.Coaches...)
+	watchers = append(watchers, game.Spectators...)
+	for _, conn := range watchers {
+		if !sendState(conn, nil, game, state, delta) {
+			broadcastErrors.Inc()
+		}
+	}
+	if justEnded {
+		// Everyone hears the game is over straight away, even with states
+		// still queued ahead of the final one.
+		gameOver := map[string]interface{}{"type": "game_over", "gameID": gameID, "status": status}
+		if winner != "" {
+			gameOver["winner"] = winner
+		}
+		if game.Adjudicated {
+			gameOver["adjudicated"] = true
+		}
+		for _, player := range game.Players {
+			sendControlToPlayer(player, gameOver)
+		}
+		for _, conn := range watchers {
+			sendControl(conn, gameOver)
 		}
 	}
+	if criticalMoment {
+		for _, player := range game.Players {
+			sendToPlayer(player, map[string]interface{}{"type": "critical_moment", "complexity": state["complexity"]})
+		}
+	}
+	publishEvent(game, state)
 
 	game.Unlock()
 	gamesMutex.Unlock()
 
-	log.Printf("Game state broadcast for game ID %s: %s", gameID, status)
+	if ended != nil {
+		go notifyGameEnd(config.GameEndWebhookURL, ended)
+	}
+	if archived != nil {
+		saveArchivedGame(gameID, archived)
+	}
+
+	logger.Debug("Game state broadcast", slog.String("gameID", gameID), slog.String("status", status))
+}
+
+// stateFor returns state as sent to player, or to a coach or spectator if
+// player is nil, which tells a player their own color. state itself is
+// left alone.
+func stateFor(player *Player, state map[string]interface{}) map[string]interface{} {
+	if player == nil {
+		return state
+	}
+	own := make(map[string]interface{}, len(state)+1)
+	for key, value := range state {
+		own[key] = value
+	}
+	own["yourColor"] = colorName(player.Color)
+	return own
+}
+
+// deleteGame removes game from games, ending its SSE streams and guest
+// timer. The caller must hold gamesMutex and game's lock.
+func deleteGame(gameID string, game *Game) {
+	delete(games, gameID)
+	endStreams(gameID, game)
+	if game.guestTimer != nil {
+		game.guestTimer.Stop()
+	}
 }
 
 func removePlayer(ws *websocket.Conn) {
 	gamesMutex.Lock()
 	defer gamesMutex.Unlock()
 
-	for gameID, game := range games {
+	gameIDs := playerGames[ws]
+	delete(playerGames, ws)
+	for _, gameID := range gameIDs {
+		game, exists := games[gameID]
+		if !exists {
+			continue
+		}
 		game.Lock()
 		for i, player := range game.Players {
-			if player.Conn == ws {
+			if player.Conn != ws {
+				continue
+			}
+			// Hold the seat of a player who drops out of a game in
+			// progress so they can reconnect; anyone else just leaves,
+			// including from a game that never got past the ready check.
+			started := !game.AwaitingReady
+			cancelReadyCheck(game)
+			if started && len(game.Players) == 2 && game.Game.Outcome() == chess.NoOutcome {
+				player.Conn = nil
+				player.ForfeitTimer = time.AfterFunc(config.ReconnectGrace, func() {
+					forfeitAbsentPlayer(gameID, player)
+				})
+				logger.Info("Player disconnected, holding seat", slog.String("gameID", gameID), slog.String("playerColor", colorName(player.Color)), slog.Time("lastSeen", player.LastSeen))
+			} else {
 				game.Players = append(game.Players[:i], game.Players[i+1:]...)
-				log.Printf("Player removed from game ID %s", gameID)
+				game.RematchOffer = chess.NoColor
+				logger.Info("Player removed", slog.String("gameID", gameID))
+			}
+			break
+		}
+		for i, coach := range game.Coaches {
+			if coach == ws {
+				game.Coaches = append(game.Coaches[:i], game.Coaches[i+1:]...)
+				break
+			}
+		}
+		for i, spectator := range game.Spectators {
+			if spectator == ws {
+				game.Spectators = append(game.Spectators[:i], game.Spectators[i+1:]...)
+				delete(game.SpectatorChatTimes, ws)
 				break
 			}
 		}
-		if len(game.Players) == 0 {
-			delete(games, gameID)
-			log.Printf("Game ID %s deleted", gameID)
+		delete(game.deltaClients, ws)
+		if !hasConnectedPlayers(game) && len(game.Players) < 2 {
+			deleteGame(gameID, game)
+			logger.Info("Game deleted", slog.String("gameID", gameID))
 		}
 		game.Unlock()
 	}
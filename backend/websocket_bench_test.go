@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/segmentio/ksuid"
+)
+
+// middlegameMoves reach a closed Ruy Lopez, twenty half-moves in.
+var middlegameMoves = []string{
+	"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7",
+	"Re1", "b5", "Bb3", "d6", "c3", "O-O", "h3", "Nb8", "d4", "Nbd7",
+}
+
+// middlegameFEN is the position after middlegameMoves.
+const middlegameFEN = "r1bq1rk1/2pnbppp/p2p1n2/1p2p3/3PP3/1BP2N1P/PP3PP1/RNBQR1K1 w - - 1 11"
+
+func middlegame(b *testing.B) *chess.Game {
+	b.Helper()
+	game := chess.NewGame()
+	for _, move := range middlegameMoves {
+		if err := game.MoveStr(move); err != nil {
+			b.Fatalf("playing %s: %v", move, err)
+		}
+	}
+	return game
+}
+
+// benchGame adds a started game at the middlegame position, with both
+// players and the given number of spectators connected.
+func benchGame(b *testing.B, spectators int) (string, *Game) {
+	b.Helper()
+	useConfig(b, func(c *Config) { c.MoveRateLimit = 1 << 30 })
+	conns := serverConns(b, 2+spectators)
+	now := time.Now()
+	game := &Game{
+		Game: middlegame(b),
+		Players: []*Player{
+			{Conn: conns[0], Color: chess.White, PlayerID: ksuid.New().String(), Name: "White"},
+			{Conn: conns[1], Color: chess.Black, PlayerID: ksuid.New().String(), Name: "Black"},
+		},
+		Spectators:    conns[2:],
+		StartingFEN:   chess.StartingPosition().String(),
+		Variant:       "standard",
+		CreatedAt:     now,
+		StartedAt:     now,
+		MoveTimes:     make([]time.Duration, len(middlegameMoves)),
+		MoveStartedAt: now,
+	}
+	gameID := ksuid.New().String()
+	gamesMutex.Lock()
+	games[gameID] = game
+	gamesMutex.Unlock()
+	b.Cleanup(func() {
+		gamesMutex.Lock()
+		delete(games, gameID)
+		gamesMutex.Unlock()
+	})
+	return gameID, game
+}
+
+func BenchmarkBroadcastGameState(b *testing.B) {
+	gameID, _ := benchGame(b, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		broadcastGameState(gameID)
+	}
+}
+
+func BenchmarkBroadcastGameState100Spectators(b *testing.B) {
+	gameID, _ := benchGame(b, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		broadcastGameState(gameID)
+	}
+}
+
+// BenchmarkMakeMove plays quiet manoeuvring moves from the middlegame,
+// going back to it every few moves so the game never ends.
+func BenchmarkMakeMove(b *testing.B) {
+	gameID, game := benchGame(b, 0)
+	moves := []string{"Bc2", "Bb7", "Nbd2", "Re8", "Nf1", "Bf8", "Ng3", "g6"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ply := i % len(moves)
+		if ply == 0 && i > 0 {
+			b.StopTimer()
+			gamesMutex.Lock()
+			game.Game = middlegame(b)
+			game.MoveTimes = game.MoveTimes[:len(middlegameMoves)]
+			gamesMutex.Unlock()
+			b.StartTimer()
+		}
+		makeMove(game.Players[ply%2].Conn, gameID, moves[ply], false)
+	}
+}
+
+func BenchmarkFENParsing(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		opt, err := chess.FEN(middlegameFEN)
+		if err != nil {
+			b.Fatal(err)
+		}
+		chess.NewGame(opt)
+	}
+}
+
+// BenchmarkStatePayload encodes the state broadcast after a middlegame
+// move whole and as a delta against the state before it, reporting the
+// size of each.
+func BenchmarkStatePayload(b *testing.B) {
+	gameID, game := benchGame(b, 0)
+	broadcastGameState(gameID)
+	game.Lock()
+	prev := game.lastState
+	game.Unlock()
+	makeMove(game.Players[0].Conn, gameID, "Bc2", false)
+	game.Lock()
+	state := game.lastState
+	game.Unlock()
+
+	payloads := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"full", state},
+		{"delta", map[string]interface{}{"seq": state["seq"], "delta": stateDelta(prev, state)}},
+	}
+	for _, p := range payloads {
+		b.Run(p.name, func(b *testing.B) {
+			var size int
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				data, err := json.Marshal(p.payload)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "payload-bytes")
+		})
+	}
+}
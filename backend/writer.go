@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeBufferSize is how many messages of each priority may queue for
+	// a connection before further ones are dropped.
+	writeBufferSize = 32
+	// writeTimeout bounds each write, so a stalled client only holds up
+	// its own writer.
+	writeTimeout = 10 * time.Second
+)
+
+// outgoing is a message queued for a connection's writer: an encoded JSON
+// message, or a close frame when closeCode is set. Control messages, such
+// as error replies and game over notices, are sent ahead of anything else
+// queued, so a backlog of game states cannot hold them up.
+type outgoing struct {
+	data        []byte
+	control     bool
+	closeCode   int
+	closeReason string
+}
+
+// writeQueues holds a connection's queued messages, by priority.
+type writeQueues struct {
+	control chan outgoing
+	normal  chan outgoing
+}
+
+var (
+	writers      = make(map[*websocket.Conn]*writeQueues)
+	writersMutex sync.Mutex
+)
+
+// startWriter gives ws a goroutine that performs all of its writes in
+// turn, so that nothing else blocks on a slow client.
+func startWriter(ws *websocket.Conn) {
+	queues := &writeQueues{
+		control: make(chan outgoing, writeBufferSize),
+		normal:  make(chan outgoing, writeBufferSize),
+	}
+	writersMutex.Lock()
+	writers[ws] = queues
+	writersMutex.Unlock()
+	go runWriter(ws, queues)
+}
+
+// stopWriter ends ws's writer once it has sent what is already queued.
+func stopWriter(ws *websocket.Conn) {
+	writersMutex.Lock()
+	if queues, ok := writers[ws]; ok {
+		delete(writers, ws)
+		close(queues.control)
+		close(queues.normal)
+	}
+	writersMutex.Unlock()
+}
+
+// runWriter writes ws's queued messages, draining control messages before
+// each normal one, until both queues are closed and empty.
+func runWriter(ws *websocket.Conn, queues *writeQueues) {
+	control, normal := queues.control, queues.normal
+	for control != nil || normal != nil {
+		var msg outgoing
+		var ok bool
+		select {
+		case msg, ok = <-control:
+		default:
+			select {
+			case msg, ok = <-control:
+			case msg, ok = <-normal:
+				if !ok {
+					normal = nil
+					continue
+				}
+			}
+		}
+		if !ok {
+			control = nil
+			continue
+		}
+		write(ws, msg)
+	}
+}
+
+func write(ws *websocket.Conn, msg outgoing) {
+	if msg.closeCode != 0 {
+		frame := websocket.FormatCloseMessage(msg.closeCode, msg.closeReason)
+		if err := ws.WriteControl(websocket.CloseMessage, frame, time.Now().Add(time.Second)); err != nil {
+			logger.Error("Error sending close message", slog.Any("error", err))
+		}
+		ws.Close()
+		return
+	}
+	ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := ws.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+		logger.Error("Error sending response", slog.Any("error", err))
+	}
+}
+
+// enqueue hands msg to ws's writer without blocking, reporting whether it
+// was accepted.
+func enqueue(ws *websocket.Conn, msg outgoing) bool {
+	writersMutex.Lock()
+	defer writersMutex.Unlock()
+
+	queues, ok := writers[ws]
+	if !ok {
+		return false
+	}
+	queue := queues.normal
+	if msg.control {
+		queue = queues.control
+	}
+	select {
+	case queue <- msg:
+		return true
+	default:
+		logger.Warn("Write queue full, dropping message", slog.String("remoteAddr", ws.RemoteAddr().String()))
+		return false
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestConcurrentBroadcastsWithStalledClient broadcasts from two goroutines
+// at once to a game watched by a spectator that never reads. Neither
+// broadcast may wait on that spectator, and the players must still get
+// their states.
+func TestConcurrentBroadcastsWithStalledClient(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+
+	stalled := dial(t, srv)
+	send(t, stalled, map[string]string{"action": "join", "gameID": gameID})
+	readUntil(t, stalled, hasStatus("spectating"))
+	if tcp, ok := stalled.UnderlyingConn().(*net.TCPConn); ok {
+		tcp.SetReadBuffer(1)
+	}
+
+	const broadcasts = 500
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < broadcasts; j++ {
+				broadcastGameState(gameID)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("concurrent broadcasts did not finish with a stalled spectator")
+	}
+
+	play(t, white, black, gameID, "e4")
+	readUntil(t, black, func(msg map[string]interface{}) bool {
+		return isState(msg) && msg["moveCount"] == float64(1)
+	})
+}
+
+// rawConn returns the server end of a WebSocket connection, with no writer
+// started for it, and the client end.
+func rawConn(t *testing.T) (server, client *websocket.Conn) {
+	t.Helper()
+	accepted := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		accepted <- ws
+	}))
+	t.Cleanup(srv.Close)
+	client = dial(t, srv)
+	server = <-accepted
+	t.Cleanup(func() { server.Close() })
+	return server, client
+}
+
+// TestControlMessagesFirst queues states and then a control message, and
+// checks that the writer sends the control message before the states,
+// which still go out in order.
+func TestControlMessagesFirst(t *testing.T) {
+	server, client := rawConn(t)
+	queues := &writeQueues{
+		control: make(chan outgoing, writeBufferSize),
+		normal:  make(chan outgoing, writeBufferSize),
+	}
+	for i := 0; i < 3; i++ {
+		queues.normal <- outgoing{data: []byte(fmt.Sprintf(`{"n":%d}`, i))}
+	}
+	queues.control <- outgoing{data: []byte(`{"type":"game_over"}`), control: true}
+	close(queues.control)
+	close(queues.normal)
+	go runWriter(server, queues)
+
+	want := []string{`{"type":"game_over"}`, `{"n":0}`, `{"n":1}`, `{"n":2}`}
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, w := range want {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading: %v", err)
+		}
+		if string(data) != w {
+			t.Errorf("got %s, want %s", data, w)
+		}
+	}
+}
+
+func TestGameOverNotice(t *testing.T) {
+	useConfig(t, nil)
+	srv := newTestServer(t)
+	white, black, gameID := startTestGame(t, srv)
+	play(t, white, black, gameID, "f3", "e5", "g4")
+	send(t, black, map[string]string{"action": "move", "gameID": gameID, "move": "Qh4#"})
+
+	for _, ws := range []*websocket.Conn{white, black} {
+		notice := readUntil(t, ws, func(msg map[string]interface{}) bool { return msg["type"] == "game_over" })
+		if notice["gameID"] != gameID || notice["status"] != "checkmate" {
+			t.Errorf("game over notice = %v, want checkmate in %s", notice, gameID)
+		}
+	}
+}
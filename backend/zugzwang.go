@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+	"github.com/notnil/chess"
+	"github.com/rohit746/chess/backend/analysis"
+)
+
+// gamePhase classifies a position as "endgame" or "middlegame" from the
+// amount of non-pawn material left on the board.
+func gamePhase(pos *chess.Position) string {
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	if nonPawnMaterial(white)+nonPawnMaterial(black) <= analysis.EndgameMaterial {
+		return "endgame"
+	}
+	return "middlegame"
+}
+
+// assessZugzwangRisk returns a rough 0.0–1.0 likelihood that the side to
+// move would rather pass. It rewards pure pawn endings, active kings, level
+// material and a shortage of legal moves. It is a teaching aid only.
+func assessZugzwangRisk(pos *chess.Position) float64 {
+	if gamePhase(pos) != "endgame" {
+		return 0
+	}
+
+	white := countMaterial(pos, chess.White)
+	black := countMaterial(pos, chess.Black)
+	risk := 0.0
+
+	pieces := nonPawnMaterial(white) + nonPawnMaterial(black)
+	if pieces == 0 {
+		risk += 0.4
+	} else if white.Rooks+white.Queen+black.Rooks+black.Queen == 0 {
+		risk += 0.15
+	}
+
+	board := pos.Board()
+	active := 0
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if king := analysis.KingSquare(board, color); king != chess.NoSquare && analysis.RelativeRank(king, color) >= 2 {
+			active++
+		}
+	}
+	risk += 0.125 * float64(active)
+
+	if diff := white.centipawns() - black.centipawns(); diff >= -100 && diff <= 100 {
+		risk += 0.2
+	}
+	if len(pos.ValidMoves()) <= 5 {
+		risk += 0.15
+	}
+	return math.Min(risk, 1)
+}
+
+func getZugzwangRisk(ws *websocket.Conn, gameID string) {
+	pos, ok := currentPosition(ws, gameID)
+	if !ok {
+		return
+	}
+	sendJSON(ws, map[string]interface{}{
+		"zugzwangRisk": assessZugzwangRisk(pos),
+		"phase":        gamePhase(pos),
+	})
+	logger.Debug("Zugzwang risk sent", slog.String("gameID", gameID))
+}
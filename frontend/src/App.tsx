@@ -77,6 +77,10 @@ const App: React.FC = () => {
         if (parsedMessage.status) {
           setGameStatus(parsedMessage.status);
         }
+        // The board is up, so tell the server this client is ready to play.
+        if (parsedMessage.status === "waiting") {
+          sendMessage(JSON.stringify({ action: "ready", gameID: parsedMessage.gameID }));
+        }
         if (parsedMessage.fen) {
           const updatedGame = new ChessJS.Chess(parsedMessage.fen);
           setGame(updatedGame);
@@ -85,7 +89,7 @@ const App: React.FC = () => {
         console.error("Failed to parse message", error);
       }
     }
-  }, [lastMessage]);
+  }, [lastMessage, sendMessage]);
 
   useEffect(() => {
     if (joiningGameID !== "") {